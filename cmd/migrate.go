@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"dalian-bot/internal/conf"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/plugins"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/forward"
+	"dalian-bot/internal/services/settings"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const migrateUsage = `usage: dalian migrate [-config path] [status | -dry-run]
+
+Without arguments, create missing indexes and apply pending migrations of every plugin and service.
+  status    print the schema version of every plugin
+  -dry-run  print what would be done, without applying anything
+`
+
+// runMigrate the `migrate` subcommand. Return the exit code.
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := flags.String("config", "config/credentials.yaml", "path of the credential file")
+	dryRun := flags.Bool("dry-run", false, "print what would be done without applying")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	flags.Parse(args)
+	showStatus := false
+	switch flags.Arg(0) {
+	case "":
+	case "status":
+		showStatus = true
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	cred, err := conf.GetCred(*configPath)
+	if err != nil {
+		core.Logger.Errorf("reading credentials from %s failed: %v", *configPath, err)
+		return 1
+	}
+	reg := core.NewServiceRegistry()
+	dataService := newDataService(cred)
+	if err := dataService.Init(reg); err != nil {
+		core.Logger.Errorf("data service initialization failed: %v", err)
+		return 1
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	dataService.Start(wg)
+	defer func() {
+		wg.Add(1)
+		dataService.Stop(wg)
+	}()
+	for _, schema := range append(plugins.Schemas(), forward.Schema, settings.Schema) {
+		if err := dataService.RegisterSchema(schema); err != nil {
+			core.Logger.Errorf("invalid schema: %v", err)
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	statuses, err := dataService.SchemaStatus(ctx)
+	if err != nil {
+		core.Logger.Errorf("reading schema status failed: %v", err)
+		return 1
+	}
+	if showStatus {
+		printSchemaStatus(os.Stdout, statuses)
+		return 0
+	}
+	printSchemaPlan(os.Stdout, statuses, dataService.Backend)
+	if *dryRun {
+		fmt.Println("dry run, nothing applied.")
+		return 0
+	}
+	if err := dataService.Migrate(ctx); err != nil {
+		core.Logger.Errorf("migration failed: %v", err)
+		return 1
+	}
+	fmt.Println("done.")
+	return 0
+}
+
+func printSchemaStatus(w io.Writer, statuses []data.SchemaStatus) {
+	for _, st := range statuses {
+		state := "up to date"
+		switch {
+		case st.Version > st.LatestVersion:
+			state = "NEWER than this build"
+		case !st.UpToDate():
+			state = fmt.Sprintf("%d migration(s), %d index(es) pending", len(st.Pending), len(st.MissingIndexes))
+		}
+		fmt.Fprintf(w, "%-10s version %d/%d  %s\n", st.Namespace, st.Version, st.LatestVersion, state)
+	}
+}
+
+func printSchemaPlan(w io.Writer, statuses []data.SchemaStatus, backend string) {
+	planned := false
+	for _, st := range statuses {
+		if !st.IndexSupported && len(st.MissingIndexes) == 0 {
+			fmt.Fprintf(w, "%s: indexes skipped, not supported by the %s backend\n", st.Namespace, backend)
+		}
+		for _, index := range st.MissingIndexes {
+			var keys []string
+			for _, k := range index.Keys {
+				if k.Descending {
+					keys = append(keys, "-"+k.Field)
+				} else {
+					keys = append(keys, k.Field)
+				}
+			}
+			fmt.Fprintf(w, "%s: create index %s on %s (%s)\n", st.Namespace, index.Name, index.Collection, strings.Join(keys, ", "))
+			planned = true
+		}
+		for _, m := range st.Pending {
+			fmt.Fprintf(w, "%s: apply migration %d, %s\n", st.Namespace, m.Version, m.Description)
+			planned = true
+		}
+	}
+	if !planned {
+		fmt.Fprintln(w, "nothing to do, every schema is up to date.")
+	}
+}
@@ -1,43 +1,119 @@
 package main
 
 import (
+	"context"
 	"dalian-bot/internal/conf"
 	"dalian-bot/internal/core"
 	"dalian-bot/internal/plugins"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/data"
 	"dalian-bot/internal/services/ddtv"
 	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/external"
+	"dalian-bot/internal/services/forward"
+	"dalian-bot/internal/services/hooks"
+	"dalian-bot/internal/services/settings"
+	"dalian-bot/internal/services/telegram"
 	"dalian-bot/internal/services/web"
+	"errors"
+	"flag"
 	"go.uber.org/zap"
+	"io/fs"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
 func main() {
-
 	logger, _ := zap.NewDevelopment()
 	core.Logger = core.DalianLogger{SugaredLogger: logger.Sugar()}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", "config/credentials.yaml", "path of the credential file")
+	consoleOnly := flag.Bool("console", false, "run plugins with the local console instead of Discord")
+	consoleSocket := flag.String("console-socket", "", "read console input from the given unix socket instead of stdin")
+	flag.Parse()
 	core.Logger.Infof("Dalian core logger initialized!")
 
 	/* Read Config files */
-	cred, err := conf.GetCred("config/credentials.yaml")
-	if err != nil {
-		panic("credential test failed")
+	cred := &conf.Cred{}
+	// a credential file is not necessary for console-only runs.
+	if _, err := os.Stat(*configPath); !*consoleOnly || !errors.Is(err, fs.ErrNotExist) {
+		if cred, err = conf.GetCred(*configPath); err != nil {
+			panic("credential test failed")
+		}
+	}
+	consoleEnabled := *consoleOnly || cred.ConsoleEnabled
+	discordEnabled := !*consoleOnly && cred.DiscordToken.Value != ""
+	telegramEnabled := !*consoleOnly && cred.TelegramToken.Value != ""
+	if *consoleSocket == "" {
+		*consoleSocket = cred.ConsoleSocket
 	}
 
 	/* Generate bot template */
 	dalianBot := core.NewBot()
 
 	/* Initialize & register services */
-	webService := web.Service{ServiceConfig: web.ServiceConfig{TrustedProxies: []string{"165.232.129.202"}}}
-	webService.Init(dalianBot.ServiceRegistry)
-	ddtvService := ddtv.Service{}
-	ddtvService.Init(dalianBot.ServiceRegistry)
-	dataService := data.Service{ServiceConfig: data.ServiceConfig{URI: cred.MongoURI.Value}}
-	dataService.Init(dalianBot.ServiceRegistry)
-	discordService := discord.Service{ServiceConfig: discord.ServiceConfig{Token: cred.DiscordToken.Value, AdminChannel: cred.AdminChannel.Value}}
-	discordService.Init(dalianBot.ServiceRegistry)
+	if consoleEnabled {
+		consoleService := console.Service{ServiceConfig: console.ServiceConfig{SocketPath: *consoleSocket}}
+		consoleService.Init(dalianBot.ServiceRegistry)
+	}
+	if discordEnabled || telegramEnabled {
+		webService := newWebService(cred)
+		if err := webService.Init(dalianBot.ServiceRegistry); err != nil {
+			core.Logger.Panicf("web service initialization failed: %v", err)
+		}
+		ddtvService := newDDTVService(cred)
+		if err := ddtvService.Init(dalianBot.ServiceRegistry); err != nil {
+			core.Logger.Panicf("ddtv service initialization failed: %v", err)
+		}
+		// inbound webhooks are optional
+		if len(cred.HookEndpoints) > 0 {
+			hooksService := newHooksService(cred)
+			if err := hooksService.Init(dalianBot.ServiceRegistry); err != nil {
+				core.Logger.Panicf("hooks service initialization failed: %v", err)
+			}
+		}
+	}
+	// the console lists archived sites and notify channels too
+	if discordEnabled || telegramEnabled || consoleEnabled {
+		dataService := newDataService(cred)
+		dataService.Init(dalianBot.ServiceRegistry)
+	}
+	if discordEnabled {
+		forwardService := forward.Service{}
+		forwardService.Init(dalianBot.ServiceRegistry)
+		discordService := discord.Service{ServiceConfig: discord.ServiceConfig{
+			Token:           cred.DiscordToken.Value,
+			AdminChannel:    cred.AdminChannel.Value,
+			OwnerIDs:        cred.Owners,
+			CommandGuildIDs: cred.CommandGuilds,
+		}}
+		discordService.Init(dalianBot.ServiceRegistry)
+		// guild settings override the prefix and separator of discordService
+		settingsService := settings.Service{}
+		settingsService.Init(dalianBot.ServiceRegistry)
+	}
+	// telegram runs with or without discord
+	if telegramEnabled {
+		telegramService := telegram.Service{ServiceConfig: telegram.ServiceConfig{
+			Token:         cred.TelegramToken.Value,
+			WebhookURL:    cred.TelegramWebhookURL.Value,
+			WebhookSecret: cred.TelegramWebhookSecret.Value,
+		}}
+		if err := telegramService.Init(dalianBot.ServiceRegistry); err != nil {
+			core.Logger.Panicf("telegram service initialization failed: %v", err)
+		}
+	}
+	// external plugins are launched by their plugin, once every service is online.
+	if len(cred.ExternalPlugins) > 0 {
+		externalService := newExternalService(cred)
+		if err := externalService.Init(dalianBot.ServiceRegistry); err != nil {
+			core.Logger.Panicf("external plugin service initialization failed: %v", err)
+		}
+	}
 
 	dalianBot.ServiceRegistry.StartAll()
 
@@ -45,8 +121,41 @@ func main() {
 	dalianBot.QuickRegisterPlugin(plugins.NewPingPlugin)
 	dalianBot.QuickRegisterPlugin(plugins.NewWhatPlugin)
 	dalianBot.QuickRegisterPlugin(plugins.NewHelpPlugin)
-	dalianBot.QuickRegisterPlugin(plugins.NewDDTVPlugin)
-	dalianBot.QuickRegisterPlugin(plugins.NewArchivePlugin)
+	if discordEnabled {
+		dalianBot.QuickRegisterPlugin(plugins.NewAdminPlugin)
+		dalianBot.QuickRegisterPlugin(plugins.NewSettingsPlugin)
+	}
+	// DDTV notifications reach telegram chats without discord too
+	if discordEnabled || telegramEnabled {
+		dalianBot.QuickRegisterPlugin(plugins.NewDDTVPlugin)
+	}
+	if discordEnabled || consoleEnabled {
+		dalianBot.QuickRegisterPlugin(plugins.NewArchivePlugin)
+	}
+	if discordEnabled {
+		dalianBot.QuickRegisterPlugin(plugins.NewForwardPlugin)
+		dalianBot.QuickRegisterPlugin(plugins.NewCustomCmdPlugin)
+		dalianBot.QuickRegisterPlugin(plugins.NewDashboardPlugin)
+	}
+	if len(cred.ExternalPlugins) > 0 {
+		dalianBot.QuickRegisterPlugin(plugins.NewExternalPlugin)
+	}
+
+	/* Bring slash commands up to date */
+	var discordService *discord.Service
+	if err := dalianBot.ServiceRegistry.FetchService(&discordService); err == nil {
+		if err := discordService.SyncCommands(); err != nil {
+			core.Logger.Errorf("slash command sync failed: %v", err)
+		}
+	}
+
+	/* Bring plugin schemas up to date */
+	var dataService *data.Service
+	if err := dalianBot.ServiceRegistry.FetchService(&dataService); err == nil {
+		if err := dataService.Migrate(context.Background()); err != nil {
+			core.Logger.Panicf("database migration failed: %v", err)
+		}
+	}
 
 	/* Startup */
 	dalianBot.Run()
@@ -54,8 +163,92 @@ func main() {
 	/* Lock main thread */
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
-	<-sc
+	restart := false
+	select {
+	case <-sc:
+	case req := <-dalianBot.ShutdownRequests():
+		core.Logger.Infof("Shutdown requested: %s", req.Reason)
+		restart = req.Restart
+	}
 
 	/* Graceful Shutdown */
 	dalianBot.GracefulShutDown()
+	if restart {
+		restartProcess()
+	}
+}
+
+// restartProcess replace the process with a fresh one of the same executable and arguments.
+func restartProcess() {
+	executable, err := os.Executable()
+	if err != nil {
+		core.Logger.Panicf("restart failed, executable not found: %v", err)
+	}
+	core.Logger.Infof("Restarting %s...", executable)
+	if err := syscall.Exec(executable, os.Args, os.Environ()); err != nil {
+		core.Logger.Panicf("restart failed: %v", err)
+	}
+}
+
+func newDataService(cred *conf.Cred) *data.Service {
+	return &data.Service{ServiceConfig: data.ServiceConfig{
+		Backend: cred.DataBackend,
+		URI:     cred.MongoURI.Value,
+		Path:    cred.DataPath,
+	}}
+}
+
+func newWebService(cred *conf.Cred) *web.Service {
+	return &web.Service{ServiceConfig: web.ServiceConfig{
+		Addr:           cred.WebAddr,
+		BasePath:       cred.WebBasePath,
+		PublicURL:      cred.WebPublicURL,
+		TLSCertFile:    cred.WebTLSCert,
+		TLSKeyFile:     cred.WebTLSKey,
+		TrustedProxies: cred.WebTrustedProxies,
+		ReadTimeout:    cred.WebReadTimeout,
+		WriteTimeout:   cred.WebWriteTimeout,
+	}}
+}
+
+func newDDTVService(cred *conf.Cred) *ddtv.Service {
+	var senders []ddtv.Sender
+	for _, sender := range cred.DDTVSenders {
+		senders = append(senders, ddtv.Sender{
+			Name:             sender.Name,
+			Secret:           sender.Secret,
+			RequireSignature: sender.RequireSignature,
+			AllowedIPs:       sender.AllowedIPs,
+		})
+	}
+	return &ddtv.Service{ServiceConfig: ddtv.ServiceConfig{Senders: senders}}
+}
+
+func newHooksService(cred *conf.Cred) *hooks.Service {
+	var endpoints []hooks.Endpoint
+	for _, endpoint := range cred.HookEndpoints {
+		endpoints = append(endpoints, hooks.Endpoint{
+			Name:             endpoint.Name,
+			Decoder:          endpoint.Decoder,
+			Secret:           endpoint.Secret,
+			RequireSignature: endpoint.RequireSignature,
+			SignatureHeader:  endpoint.SignatureHeader,
+			AllowedIPs:       endpoint.AllowedIPs,
+		})
+	}
+	return &hooks.Service{ServiceConfig: hooks.ServiceConfig{Endpoints: endpoints}}
+}
+
+func newExternalService(cred *conf.Cred) *external.Service {
+	var pluginConfigs []external.PluginConfig
+	for _, p := range cred.ExternalPlugins {
+		pluginConfigs = append(pluginConfigs, external.PluginConfig{
+			Name:    p.Name,
+			Command: p.Command,
+			Args:    p.Args,
+			Dir:     p.Dir,
+			Env:     p.Env,
+		})
+	}
+	return &external.Service{ServiceConfig: external.ServiceConfig{Plugins: pluginConfigs}}
 }
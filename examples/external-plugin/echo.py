@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""Minimal Dalian external plugin.
+
+Replies to `<prefix>echo <text>` on discord and console, answers the `/echo` slash command,
+and counts the echoes with data.get/data.put. See internal/services/external for the protocol.
+"""
+import json
+import os
+import sys
+import threading
+
+COMMAND = os.environ.get("ECHO_PREFIX", "echo")
+
+lock = threading.Lock()
+next_id = 0
+pending = {}
+prefixes = {}
+
+
+def send(message):
+    message["jsonrpc"] = "2.0"
+    with lock:
+        sys.stdout.write(json.dumps(message) + "\n")
+        sys.stdout.flush()
+
+
+def call(method, params):
+    """Call a Dalian method and wait for its result."""
+    global next_id
+    with lock:
+        next_id += 1
+        request_id = next_id
+        waiter = pending[request_id] = {"event": threading.Event()}
+    send({"id": request_id, "method": method, "params": params})
+    waiter["event"].wait(10)
+    response = waiter.get("response", {})
+    if "error" in response:
+        raise RuntimeError(response["error"]["message"])
+    return response.get("result")
+
+
+def count_echo():
+    try:
+        count = call("data.get", {"key": "count"})["value"] or 0
+        call("data.put", {"key": "count", "value": count + 1})
+    except RuntimeError:
+        # no data service, e.g. in console mode
+        return "?"
+    return count + 1
+
+
+def echo_text(messenger, content):
+    prefix = prefixes.get(messenger, "$") + COMMAND
+    if not content.startswith(prefix + " "):
+        return None
+    return content[len(prefix) + 1:].strip()
+
+
+def on_trigger(kind, data):
+    if kind == "discord.message":
+        text = echo_text("discord", data["content"])
+        if text:
+            call("discord.send_message", {"channel_id": data["channel_id"], "content": f"{text} (#{count_echo()})"})
+    elif kind == "console.message":
+        text = echo_text("console", data["content"])
+        if text:
+            call("console.send_message", {"channel_id": data["channel_id"], "content": f"{text} (#{count_echo()})"})
+    elif kind == "discord.interaction":
+        options = {o["name"]: o["value"] for o in data["data"].get("options", [])}
+        call("discord.respond_interaction", {
+            "interaction_id": data["id"],
+            "content": f"{options.get('text', '')} (#{count_echo()})",
+            "ephemeral": True,
+        })
+
+
+def handle(message):
+    method = message.get("method")
+    if method is None:
+        with lock:
+            waiter = pending.pop(message.get("id"), None)
+        if waiter:
+            waiter["response"] = message
+            waiter["event"].set()
+    elif method == "initialize":
+        for messenger in ("discord", "console", "telegram"):
+            if message["params"].get(messenger):
+                prefixes[messenger] = message["params"][messenger]["prefix"]
+        send({"id": message["id"], "result": {
+            "subscriptions": ["discord.message", "discord.interaction", "console.message"],
+            "commands": [{
+                "name": COMMAND,
+                "description": "Repeat after you",
+                "options": [{"type": 3, "name": "text", "description": "What to say", "required": True}],
+            }],
+        }})
+    elif method == "trigger":
+        params = message["params"]
+        # calls block until answered, so triggers are handled off the reading thread.
+        threading.Thread(target=on_trigger, args=(params["kind"], params["data"]), daemon=True).start()
+    elif method == "shutdown":
+        sys.exit(0)
+
+
+for line in sys.stdin:
+    if line.strip():
+        handle(json.loads(line))
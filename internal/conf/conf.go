@@ -4,6 +4,7 @@ import (
 	"dalian-bot/internal/core"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 type Cred struct {
@@ -11,11 +12,27 @@ type Cred struct {
 	DiscordCred  `yaml:"discord-cred"`
 	MongoCred    `yaml:"mongo-cred"`
 	OnedriveCred `yaml:"onedrive-cred"`
+	TelegramCred `yaml:"telegram-cred,omitempty"`
+	ConsoleConf  `yaml:"console,omitempty"`
+	DataConf     `yaml:"data,omitempty"`
+	WebConf      `yaml:"web,omitempty"`
+	DDTVConf     `yaml:"ddtv,omitempty"`
+	HooksConf    `yaml:"hooks,omitempty"`
+	// ExternalPlugins out-of-process plugins, see package external.
+	ExternalPlugins []ExternalPluginConf `yaml:"external-plugins,omitempty"`
+	// ShortDiscordCred and ShortMongoCred the `discord` and `mongo` keys of credentials_format.yaml,
+	// read when the -cred keys are absent.
+	ShortDiscordCred DiscordCred `yaml:"discord,omitempty"`
+	ShortMongoCred   MongoCred   `yaml:"mongo,omitempty"`
 }
 
 type DiscordCred struct {
 	DiscordToken yaml.Node `yaml:"token"`
 	AdminChannel yaml.Node `yaml:"admin-channel,omitempty"`
+	// Owners users allowed to run admin commands outside the admin channel.
+	Owners []string `yaml:"owners,omitempty"`
+	// CommandGuilds register slash commands in these guilds instead of globally.
+	CommandGuilds []string `yaml:"command-guilds,omitempty"`
 }
 
 type MongoCred struct {
@@ -27,6 +44,71 @@ type OnedriveCred struct {
 	OnedriveSecret   yaml.Node `yaml:"secret"`
 }
 
+type TelegramCred struct {
+	TelegramToken         yaml.Node `yaml:"token"`
+	TelegramWebhookURL    yaml.Node `yaml:"webhook-url,omitempty"`
+	TelegramWebhookSecret yaml.Node `yaml:"webhook-secret,omitempty"`
+}
+
+// ConsoleConf Local console for running plugins without Discord.
+type ConsoleConf struct {
+	ConsoleEnabled bool   `yaml:"enabled"`
+	ConsoleSocket  string `yaml:"socket,omitempty"` // read stdin when empty
+}
+
+// DataConf Storage backend of plugins.
+type DataConf struct {
+	DataBackend string `yaml:"backend,omitempty"` // mongo or file, mongo when mongo credentials are set
+	DataPath    string `yaml:"path,omitempty"`    // file backend location
+}
+
+// WebConf HTTP server receiving webhooks.
+type WebConf struct {
+	WebAddr           string        `yaml:"listen,omitempty"` // defaults to :8740
+	WebBasePath       string        `yaml:"base-path,omitempty"`
+	WebPublicURL      string        `yaml:"public-url,omitempty"` // for links sent outside the web, e.g. dashboard logins
+	WebTLSCert        string        `yaml:"tls-cert,omitempty"`
+	WebTLSKey         string        `yaml:"tls-key,omitempty"`
+	WebTrustedProxies []string      `yaml:"trusted-proxies,omitempty"`
+	WebReadTimeout    time.Duration `yaml:"read-timeout,omitempty"` // e.g. 10s
+	WebWriteTimeout   time.Duration `yaml:"write-timeout,omitempty"`
+}
+
+// DDTVConf DDTV instances allowed to post webhooks.
+type DDTVConf struct {
+	DDTVSenders []DDTVSenderConf `yaml:"senders,omitempty"`
+}
+
+type DDTVSenderConf struct {
+	Name             string   `yaml:"name"`
+	Secret           string   `yaml:"secret"`
+	RequireSignature bool     `yaml:"require-signature,omitempty"`
+	AllowedIPs       []string `yaml:"allowed-ips,omitempty"` // IPs or CIDRs
+}
+
+// HooksConf Inbound webhook endpoints, received at /hooks/<name>.
+type HooksConf struct {
+	HookEndpoints []HookEndpointConf `yaml:"endpoints,omitempty"`
+}
+
+type HookEndpointConf struct {
+	Name             string   `yaml:"name"`
+	Decoder          string   `yaml:"decoder,omitempty"` // json, form or raw
+	Secret           string   `yaml:"secret,omitempty"`
+	RequireSignature bool     `yaml:"require-signature,omitempty"`
+	SignatureHeader  string   `yaml:"signature-header,omitempty"`
+	AllowedIPs       []string `yaml:"allowed-ips,omitempty"` // IPs or CIDRs
+}
+
+// ExternalPluginConf An executable talking the external plugin protocol on its stdio.
+type ExternalPluginConf struct {
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+	Dir     string   `yaml:"dir,omitempty"`
+	Env     []string `yaml:"env,omitempty"` // KEY=value
+}
+
 var credInternal Cred
 
 func GetCred(fileLocation string) (*Cred, error) {
@@ -41,6 +123,12 @@ func GetCred(fileLocation string) (*Cred, error) {
 			core.Logger.Panicf("Error unmarshalling cred file: %v", err)
 			return nil, err
 		}
+		if credInternal.DiscordToken.Value == "" {
+			credInternal.DiscordCred = credInternal.ShortDiscordCred
+		}
+		if credInternal.MongoURI.Value == "" {
+			credInternal.MongoCred = credInternal.ShortMongoCred
+		}
 	}
 
 	return &credInternal, nil
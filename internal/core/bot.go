@@ -5,6 +5,8 @@ package core
 
 import (
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 const VERSION = "2.0.0"
@@ -26,6 +28,8 @@ type Bot struct {
 	PluginRegistry  *PluginRegistry  // PluginRegistry A Plugin provider maintained by Bot
 	DispatcherChan  chan Trigger     // DispatcherChan channel for dispatching Trigger to Plugin
 
+	stats     dispatcherStats
+	shutdowns chan ShutdownRequest
 	//todo: add a channel that listening to auditing messages, or add an AuditService in Bot
 }
 
@@ -34,6 +38,7 @@ func NewBot() *Bot {
 	bot := &Bot{
 		ServiceRegistry: NewServiceRegistry(),
 		PluginRegistry:  NewPluginRegistry(),
+		shutdowns:       make(chan ShutdownRequest, 1),
 	}
 	return bot
 }
@@ -45,6 +50,7 @@ func NewBot() *Bot {
 // Run start the bot and listen to incoming events.
 func (b *Bot) Run() {
 	b.DispatcherChan, _ = b.ServiceRegistry.InstallTriggerChanForAll()
+	b.stats.start()
 	//loop until channel closed.
 	go func(ch <-chan Trigger) {
 		for {
@@ -55,7 +61,9 @@ func (b *Bot) Run() {
 				// receives a trigger, dispatching...
 				Logger.Debugf("trigger:%v", trigger)
 				trigger.Bot = b //inject bot instance to Trigger.
-				for _, plugin := range b.PluginRegistry.GetPlugins() {
+				plugins := b.PluginRegistry.EnabledPlugins()
+				b.stats.record(trigger, plugins)
+				for _, plugin := range plugins {
 					go plugin.Trigger(trigger)
 				}
 			}
@@ -76,6 +84,80 @@ func (b *Bot) GracefulShutDown() {
 	b.ServiceRegistry.StopAll() // stop all services.
 }
 
+// Stats Return what the dispatcher has done since Run.
+func (b *Bot) Stats() DispatcherStats {
+	stats := b.stats.snapshot()
+	stats.Pending, stats.Capacity = len(b.DispatcherChan), cap(b.DispatcherChan)
+	return stats
+}
+
+// ShutdownRequest Ask the process to stop the bot, and start it again if Restart.
+type ShutdownRequest struct {
+	Restart bool
+	Reason  string
+}
+
+// RequestShutdown Ask the process owning the bot for a graceful shutdown, see ShutdownRequests.
+// Only the first request is kept until it's received.
+func (b *Bot) RequestShutdown(req ShutdownRequest) {
+	select {
+	case b.shutdowns <- req:
+	default:
+	}
+}
+
+// ShutdownRequests Shutdowns requested by plugins. The process should call GracefulShutDown on receiving one.
+func (b *Bot) ShutdownRequests() <-chan ShutdownRequest {
+	return b.shutdowns
+}
+
+// DispatcherStats Triggers dispatched by the bot.
+type DispatcherStats struct {
+	Since      time.Time              // when Run was called
+	Triggers   map[TriggerType]uint64 // triggers dispatched, by type
+	Deliveries uint64                 // triggers handed to a plugin accepting them
+	Pending    int                    // triggers waiting in DispatcherChan
+	Capacity   int                    // size of DispatcherChan
+}
+
+type dispatcherStats struct {
+	lock       sync.Mutex
+	since      time.Time
+	triggers   map[TriggerType]uint64
+	deliveries uint64
+}
+
+func (d *dispatcherStats) start() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.since = time.Now()
+	d.triggers = make(map[TriggerType]uint64)
+}
+
+func (d *dispatcherStats) record(trigger Trigger, plugins []IPlugin) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.triggers == nil {
+		d.triggers = make(map[TriggerType]uint64)
+	}
+	d.triggers[trigger.Type]++
+	for _, plugin := range plugins {
+		if plugin.AcceptTrigger(trigger.Type) {
+			d.deliveries++
+		}
+	}
+}
+
+func (d *dispatcherStats) snapshot() DispatcherStats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	triggers := make(map[TriggerType]uint64, len(d.triggers))
+	for triggerType, count := range d.triggers {
+		triggers[triggerType] = count
+	}
+	return DispatcherStats{Since: d.since, Triggers: triggers, Deliveries: d.deliveries}
+}
+
 // MessengerConfig Basic config for messenger, records command prefix, separator, and botID.
 type MessengerConfig struct {
 	Prefix    string
@@ -1,23 +1,29 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 )
 
 // PluginRegistry Plugin controller embedded in the Bot.
 type PluginRegistry struct {
+	lock        sync.RWMutex
 	plugins     map[reflect.Type]IPlugin // store valid plugin instances
 	pluginTypes []reflect.Type           // record plguin regsitration order
+	disabled    map[reflect.Type]bool    // plugins not receiving triggers
 }
 
 // NewPluginRegistry Return a raw PluginRegistry
 func NewPluginRegistry() *PluginRegistry {
-	return &PluginRegistry{plugins: make(map[reflect.Type]IPlugin)}
+	return &PluginRegistry{plugins: make(map[reflect.Type]IPlugin), disabled: make(map[reflect.Type]bool)}
 }
 
 // RegisterPlugin Register plugin to registry.
 func (s *PluginRegistry) RegisterPlugin(plugin IPlugin) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	kind := reflect.TypeOf(plugin)
 	if _, exists := s.plugins[kind]; exists {
 		return fmt.Errorf("plugin already exists: %v", kind)
@@ -28,11 +34,58 @@ func (s *PluginRegistry) RegisterPlugin(plugin IPlugin) error {
 	return nil
 }
 
-// GetPlugins Get all plugins registered.
+// GetPlugins Get all plugins registered, enabled or not.
 func (s *PluginRegistry) GetPlugins() map[reflect.Type]IPlugin {
 	return s.plugins
 }
 
+// Plugins Return all plugins registered, in registration order.
+func (s *PluginRegistry) Plugins() []IPlugin {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	plugins := make([]IPlugin, 0, len(s.pluginTypes))
+	for _, kind := range s.pluginTypes {
+		plugins = append(plugins, s.plugins[kind])
+	}
+	return plugins
+}
+
+// EnabledPlugins Return the plugins receiving triggers, in registration order.
+func (s *PluginRegistry) EnabledPlugins() []IPlugin {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	plugins := make([]IPlugin, 0, len(s.pluginTypes))
+	for _, kind := range s.pluginTypes {
+		if !s.disabled[kind] {
+			plugins = append(plugins, s.plugins[kind])
+		}
+	}
+	return plugins
+}
+
+// IsPluginEnabled Return true if the plugin receives triggers.
+func (s *PluginRegistry) IsPluginEnabled(plugin IPlugin) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return !s.disabled[reflect.TypeOf(plugin)]
+}
+
+// SetPluginEnabled Start or stop dispatching triggers to the plugin of the name.
+// Disabled plugins stay registered, their services and commands are left untouched.
+func (s *PluginRegistry) SetPluginEnabled(name string, enabled bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, kind := range s.pluginTypes {
+		if s.plugins[kind].GetName() == name {
+			s.disabled[kind] = !enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("plugin %s:%w", name, ErrPluginNotFound)
+}
+
+var ErrPluginNotFound = errors.New("plugin not found in registry")
+
 // Plugin Basic command struct with no function
 type Plugin struct {
 	Name                 string
@@ -54,12 +107,18 @@ func (p *Plugin) AcceptTrigger(t TriggerType) bool {
 	return false
 }
 
+// AcceptedTriggers Return the types of Trigger the Plugin accepts.
+func (p *Plugin) AcceptedTriggers() []TriggerType {
+	return p.AcceptedTriggerTypes
+}
+
 // IPlugin Top-level plugin interface
 type IPlugin interface {
 
 	// GetName all plugins have their name.
 	GetName() string                  // provided by Plugin
 	AcceptTrigger(t TriggerType) bool // provided by Plugin
+	AcceptedTriggers() []TriggerType  // provided by Plugin
 
 	Init(reg *ServiceRegistry) error // should be implemented
 	Trigger(trigger Trigger)         // should be implemented.
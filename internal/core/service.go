@@ -70,19 +70,28 @@ func (s *ServiceRegistry) StopAll() {
 	Logger.Infof("ALL services stopped.")
 }
 
+// Services Return all services registered, in registration order.
+func (s *ServiceRegistry) Services() []Service {
+	services := make([]Service, 0, len(s.serviceTypes))
+	for _, kind := range s.serviceTypes {
+		services = append(services, s.services[kind])
+	}
+	return services
+}
+
 // FetchService takes in a struct pointer and sets the value of that pointer
 // to a service currently stored in the service registry. This ensures the input argument is
 // set to the right pointer that refers to the originally registered service.
 func (s *ServiceRegistry) FetchService(service interface{}) error {
 	if reflect.TypeOf(service).Kind() != reflect.Ptr {
-		return fmt.Errorf("provided type %s:%v", reflect.TypeOf(service), ErrServiceFetchNonPointer)
+		return fmt.Errorf("provided type %s:%w", reflect.TypeOf(service), ErrServiceFetchNonPointer)
 	}
 	element := reflect.ValueOf(service).Elem()
 	if running, ok := s.services[element.Type()]; ok {
 		element.Set(reflect.ValueOf(running))
 		return nil
 	}
-	return fmt.Errorf("provided type %s:%v", reflect.TypeOf(service), ErrServiceFetchUnknownService)
+	return fmt.Errorf("provided type %s:%w", reflect.TypeOf(service), ErrServiceFetchUnknownService)
 }
 
 var (
@@ -118,11 +127,33 @@ type Trigggerable interface {
 // TriggerableEmbedUtil An util that stores trigger channel.
 type TriggerableEmbedUtil struct {
 	TriggerChan chan<- Trigger
+	installOnce sync.Once
+	installed   chan struct{}
 }
 
 // InstallTriggerChan Install the given Trigger channel
 func (t *TriggerableEmbedUtil) InstallTriggerChan(triggers chan<- Trigger) {
 	t.TriggerChan = triggers
+	select {
+	case <-t.installedChan():
+		// reinstalled, already marked.
+	default:
+		close(t.installedChan())
+	}
+}
+
+// SendTrigger Send the trigger, waiting for the Trigger channel if it's not installed yet.
+// Services producing triggers right after Start should use it instead of writing TriggerChan directly.
+func (t *TriggerableEmbedUtil) SendTrigger(trigger Trigger) {
+	<-t.installedChan()
+	t.TriggerChan <- trigger
+}
+
+func (t *TriggerableEmbedUtil) installedChan() chan struct{} {
+	t.installOnce.Do(func() {
+		t.installed = make(chan struct{})
+	})
+	return t.installed
 }
 
 const (
@@ -262,6 +262,8 @@ type PagerAction int
 const (
 	PagerPrevPage PagerAction = iota
 	PagerNextPage
+	PagerFirstPage
+	PagerLastPage
 )
 
 // CombinedKey a simple wrapper for key combination.
@@ -0,0 +1,213 @@
+package plugins
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/ddtv"
+	"dalian-bot/internal/services/discord"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AdminPlugin Operate the bot from Discord.
+// Discord: can be triggered by `$admin`, only in the admin channel or by the configured owners.
+type AdminPlugin struct {
+	core.Plugin
+	DiscordService *discord.Service
+	DDTVService    *ddtv.Service // optional, for webhook stats
+	core.StartWithMatchUtil
+	discord.IDiscordHelper
+}
+
+const adminUsage = "Usage: %sadmin services|plugins|enable <plugin>|disable <plugin>|resync|stats|restart|shutdown"
+
+// DoPlainMessage `$admin <command> [plugin]` support. Messages from anyone else are ignored silently.
+func (p *AdminPlugin) DoPlainMessage(b *core.Bot, m *discordgo.MessageCreate) error {
+	config := p.DiscordService.MessengerConfig(m.GuildID)
+	if matched, _ := p.MatchText(m.Content, config); !matched {
+		return nil
+	}
+	if !p.DiscordService.IsAdminMessage(m.Message) {
+		core.Logger.Warnf("Admin command from user %s in channel %s ignored.", m.Author.ID, m.ChannelID)
+		return nil
+	}
+	args := strings.Fields(m.Content)
+	if len(args) < 2 {
+		_, err := p.DiscordService.ChannelMessageSend(m.ChannelID, fmt.Sprintf(adminUsage, config.Prefix))
+		return err
+	}
+	var reply string
+	switch args[1] {
+	case "services":
+		_, err := p.DiscordService.ChannelMessageSendCodeBlock(m.ChannelID, listServices(b))
+		return err
+	case "plugins":
+		_, err := p.DiscordService.ChannelMessageSendCodeBlock(m.ChannelID, listPlugins(b))
+		return err
+	case "enable", "disable":
+		if len(args) < 3 {
+			reply = fmt.Sprintf("Usage: %sadmin %s <plugin>", config.Prefix, args[1])
+			break
+		}
+		if args[2] == p.Name {
+			reply = "The admin plugin can't be switched off."
+			break
+		}
+		if err := b.PluginRegistry.SetPluginEnabled(args[2], args[1] == "enable"); err != nil {
+			reply = fmt.Sprintf("No plugin named %s.", args[2])
+			break
+		}
+		core.Logger.Infof("Plugin [%s] %sd by %s.", args[2], args[1], m.Author.ID)
+		reply = fmt.Sprintf("Plugin %s %sd.", args[2], args[1])
+	case "resync":
+		if err := p.DiscordService.SyncCommands(); err != nil {
+			reply = fmt.Sprintf("Slash command sync failed: %v", err)
+			break
+		}
+		reply = "Slash commands are in sync."
+	case "stats":
+		stats := formatDispatcherStats(b.Stats())
+		if p.DDTVService != nil {
+			stats += "\n" + formatWebhookStats(p.DDTVService.AuthStats())
+		}
+		_, err := p.DiscordService.ChannelMessageSendCodeBlock(m.ChannelID, stats)
+		return err
+	case "restart", "shutdown":
+		restart := args[1] == "restart"
+		if _, err := p.DiscordService.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Dalian is going down for a %s...", args[1])); err != nil {
+			core.Logger.Warnf("Error announcing %s: %v", args[1], err)
+		}
+		b.RequestShutdown(core.ShutdownRequest{Restart: restart, Reason: fmt.Sprintf("%s requested by %s", args[1], m.Author.ID)})
+		return nil
+	default:
+		reply = fmt.Sprintf(adminUsage, config.Prefix)
+	}
+	_, err := p.DiscordService.ChannelMessageSend(m.ChannelID, reply)
+	return err
+}
+
+// listServices one line per service with its status, in registration order.
+func listServices(b *core.Bot) string {
+	var lines []string
+	for _, service := range b.ServiceRegistry.Services() {
+		status := "OK"
+		if err := service.Status(); err != nil {
+			status = "DOWN: " + err.Error()
+		}
+		lines = append(lines, fmt.Sprintf("%-10s %s", service.Name(), status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listPlugins one line per plugin with its accepted triggers, in registration order.
+func listPlugins(b *core.Bot) string {
+	var lines []string
+	for _, plugin := range b.PluginRegistry.Plugins() {
+		state := "enabled"
+		if !b.PluginRegistry.IsPluginEnabled(plugin) {
+			state = "disabled"
+		}
+		var triggers []string
+		for _, triggerType := range plugin.AcceptedTriggers() {
+			triggers = append(triggers, string(triggerType))
+		}
+		lines = append(lines, fmt.Sprintf("%-10s %-8s %s", plugin.GetName(), state, strings.Join(triggers, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatDispatcherStats(stats core.DispatcherStats) string {
+	lines := []string{
+		fmt.Sprintf("running for %s", time.Since(stats.Since).Round(time.Second)),
+		fmt.Sprintf("queue      %d/%d", stats.Pending, stats.Capacity),
+		fmt.Sprintf("deliveries %d", stats.Deliveries),
+	}
+	var triggerTypes []string
+	for triggerType := range stats.Triggers {
+		triggerTypes = append(triggerTypes, string(triggerType))
+	}
+	sort.Strings(triggerTypes)
+	for _, triggerType := range triggerTypes {
+		lines = append(lines, fmt.Sprintf("%-10s %d", triggerType, stats.Triggers[core.TriggerType(triggerType)]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatWebhookStats DDTV webhook calls by sender, and rejected ones by reason.
+func formatWebhookStats(stats ddtv.AuthStats) string {
+	lines := []string{"ddtv webhooks"}
+	for _, counts := range []struct {
+		prefix string
+		counts map[string]uint64
+	}{{"accepted", stats.Accepted}, {"rejected", stats.Rejected}} {
+		var keys []string
+		for key := range counts.counts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("%s %-10s %d", counts.prefix, key, counts.counts[key]))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *AdminPlugin) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&p.DiscordService); err != nil {
+		return err
+	}
+	_ = reg.FetchService(&p.DDTVService)
+	p.Name = "admin"
+	p.Identifiers = []string{"admin"}
+	p.AcceptedTriggerTypes = []core.TriggerType{discord.TriggerTypeDiscord}
+	prefix := p.DiscordService.DiscordAccountConfig.Prefix
+	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
+		PluginHelp: "Operate Dalian, in the admin channel or as an owner.",
+		CommandHelps: []discord.CommandHelp{
+			{
+				Name: "admin",
+				FormattedHelp: fmt.Sprintf("*Call*: %sadmin services|plugins\rlist services with their status, plugins with their triggers\r"+
+					"*Call*: %sadmin enable|disable <plugin>\rstart or stop dispatching triggers to the plugin\r"+
+					"*Call*: %sadmin resync\rbring slash commands up to date\r"+
+					"*Call*: %sadmin stats\rdisplay dispatcher stats\r"+
+					"*Call*: %sadmin restart|shutdown\rgracefully restart or stop Dalian", prefix, prefix, prefix, prefix, prefix),
+			},
+		},
+	})
+	return nil
+}
+
+func (p *AdminPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	switch trigger.Type {
+	case discord.TriggerTypeDiscord:
+		discordEvent := discord.UnboxEvent(trigger)
+		switch discordEvent.EventType {
+		case discord.EventTypeMessageCreate:
+			if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+				return
+			}
+			if err := p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate); err != nil {
+				core.Logger.Warnf("Error executing admin command: %v", err)
+			}
+		default:
+			//not handling any other type of discordEvent.
+			return
+		}
+	default:
+		core.Logger.Warnf(core.LogPromptUnknownTrigger, trigger.Type)
+	}
+}
+
+func NewAdminPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var admin AdminPlugin
+	if err := (&admin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("Admin plugin MUST have all required service(s) injected!")
+	}
+	return &admin
+}
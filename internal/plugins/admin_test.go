@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"dalian-bot/internal/services/discord/discordtest"
+	"strings"
+	"testing"
+)
+
+func lastSend(t *testing.T, h *discordtest.Harness) string {
+	t.Helper()
+	sends := h.Session.CallsOf("ChannelMessageSend")
+	if len(sends) == 0 {
+		t.Fatalf("nothing sent")
+	}
+	return sends[len(sends)-1].Content
+}
+
+func TestAdminCommandsOnlyInAdminChannel(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	h.RegisterPlugin(NewAdminPlugin)
+	h.RegisterPlugin(NewPingPlugin)
+
+	h.Say(discordtest.ChannelID, "$admin plugins")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("admin command answered outside the admin channel: %v", sends)
+	}
+
+	h.DiscordService.AdminChannel = discordtest.ChannelID
+	h.Say(discordtest.ChannelID, "$admin plugins")
+	if got := lastSend(t, h); !strings.Contains(got, "admin      enabled  discord") || !strings.Contains(got, "ping       enabled") {
+		t.Fatalf("unexpected plugin list: %q", got)
+	}
+	h.Say(discordtest.ChannelID, "$admin services")
+	if got := lastSend(t, h); !strings.Contains(got, "discord    OK") {
+		t.Fatalf("unexpected service list: %q", got)
+	}
+
+	h.Say(discordtest.ChannelID, "$admin disable ping")
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$ping")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("disabled plugin answered: %v", sends)
+	}
+	h.Say(discordtest.ChannelID, "$admin disable admin")
+	if got := lastSend(t, h); got != "The admin plugin can't be switched off." {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+	h.Say(discordtest.ChannelID, "$admin enable ping")
+	h.Say(discordtest.ChannelID, "$ping")
+	if got := lastSend(t, h); got != "Pong!" {
+		t.Fatalf("enabled plugin didn't answer: %q", got)
+	}
+}
+
+func TestAdminOwnerRequestsShutdown(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	h.DiscordService.OwnerIDs = []string{discordtest.UserID}
+	h.RegisterPlugin(NewAdminPlugin)
+
+	h.Say(discordtest.ChannelID, "$admin restart")
+	select {
+	case req := <-h.Bot.ShutdownRequests():
+		if !req.Restart {
+			t.Fatalf("expected a restart, got %+v", req)
+		}
+	default:
+		t.Fatalf("no shutdown requested")
+	}
+
+	h.Bot.Run()
+	defer h.Bot.GracefulShutDown()
+	h.Say(discordtest.ChannelID, "$admin stats")
+	if got := lastSend(t, h); !strings.Contains(got, "queue      0/100") {
+		t.Fatalf("unexpected stats: %q", got)
+	}
+	if stats := h.Bot.Stats(); stats.Triggers == nil || stats.Since.IsZero() {
+		t.Fatalf("stats not started: %+v", stats)
+	}
+}
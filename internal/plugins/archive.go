@@ -2,37 +2,49 @@ package plugins
 
 import (
 	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/data"
 	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/forward"
+	"dalian-bot/internal/services/web"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/context"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
 
 // ArchivePlugin Create archives for specific contents.
 // Discord: Related commands are stored in command group `archive`
+// Console: `$archive list [tags]` lists the sites of every user
 type ArchivePlugin struct {
 	core.Plugin
 	DiscordService *discord.Service
+	ConsoleService *console.Service
 	DataService    *data.Service
+	ForwardService *forward.Service // optional
+	WebService     *web.Service     // optional, serves the REST API
 	discord.SlashCommandUtil
 	discord.IDiscordHelper
+	core.StartWithMatchUtil
 	core.ArgParseUtil
 	core.StageUtil
 }
 
 func (p *ArchivePlugin) handleSaveSite(i *discordgo.Interaction, optionsMap map[string]*discordgo.ApplicationCommandInteractionDataOption) error {
+	// without an url, fill a form instead
+	if _, ok := optionsMap["url"]; !ok {
+		return p.DiscordService.InteractionRespondModal(i, archiveSaveModalID, "Save a site", archiveModalInputs(nil)...)
+	}
 	// must have a valid url
-	if _, err := url.ParseRequestURI(optionsMap["url"].StringValue()); err != nil {
-		p.DiscordService.InteractionRespond(i, "You must provide a *valid* url!")
-		return nil
+	if !validSiteURL(optionsMap["url"].StringValue()) {
+		return p.DiscordService.InteractionRespond(i, "You must provide a *valid* url!")
 	}
 	aPo := archivePO{
 		GuildID:   i.GuildID,
@@ -43,52 +55,203 @@ func (p *ArchivePlugin) handleSaveSite(i *discordgo.Interaction, optionsMap map[
 	aPo.Site = optionsMap["url"].StringValue()
 	// set tags
 	if tagsOption, ok := optionsMap["tags"]; ok {
-		ephemeralTags := p.SeparateArgs(tagsOption.StringValue(), p.DiscordService.DiscordAccountConfig.Separator)
+		ephemeralTags := p.SeparateArgs(tagsOption.StringValue(), p.DiscordService.MessengerConfig(i.GuildID).Separator)
 		aPo.Tags = ephemeralTags
 	}
 	if tagsOption, ok := optionsMap["note"]; ok {
 		aPo.Note = tagsOption.StringValue()
 	}
-	// set time
-	aPo.setTime(true)
-	result := p.insertOneArchivePo(aPo)
-	if result.Err() != nil {
-		core.Logger.Warnf("Error inserting archive document: %v", result.Err())
+	if err := p.saveSite(&aPo); err != nil {
+		core.Logger.Warnf("Error inserting archive document: %v", err)
 		p.DiscordService.InteractionRespond(i, "Internal error inserting! Please contact admin for help.")
-		return result.Err()
-	}
-	// todo: replace it with actual title saving
-	aPo.Title = "Temporary Title"
-	p.DiscordService.InteractionRespondEmbed(i, &discordgo.MessageEmbed{
-		Title:       "Site saved",
-		Description: "The following site has been saved",
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Color:       discord.EmbedColorNormal,
-		Fields: []*discordgo.MessageEmbedField{{
-			Name:   "Temp Title", // todo: site title through snapshot or other ways
-			Value:  aPo.essentialInfoForEmbed(),
-			Inline: false,
-		}},
-	}, nil)
+		return err
+	}
+	// todo: site title through snapshot or other ways
+	return discord.SuccessEmbed("Site saved").
+		Description("The following site has been saved").
+		Field(aPo.displayTitle(), aPo.essentialInfo()).
+		Respond(p.DiscordService, i)
+}
+
+// validSiteURL sites are saved with absolute urls only, through commands, forms and the API alike.
+func validSiteURL(site string) bool {
+	_, err := url.ParseRequestURI(site)
+	return err == nil
+}
+
+// saveSite insert the record and forward it to the guild.
+func (p *ArchivePlugin) saveSite(aPo *archivePO) error {
+	aPo.setTime(true)
+	id, err := p.insertOneArchivePo(*aPo)
+	if err != nil {
+		return err
+	}
+	aPo.BsonID = id
+	if p.ForwardService != nil {
+		if err := p.ForwardService.Publish(aPo.GuildID, forward.EventArchiveSave, aPo); err != nil {
+			core.Logger.Warnf("Error forwarding archive document: %v", err)
+		}
+	}
 	return nil
 }
 
+// handleArchiveLinks save every link of the target message, tagged with the channel name and noted with a jump link.
+func (p *ArchivePlugin) handleArchiveLinks(i *discordgo.InteractionCreate) error {
+	m, ok := discord.TargetMessage(i)
+	if !ok {
+		return errors.New("message command without a target message")
+	}
+	// saving many links may take a while
+	r := p.DiscordService.NewResponder(i.Interaction, true)
+	defer r.Done()
+	links := messageLinks(m)
+	if len(links) == 0 {
+		return r.RespondText("No link found in this message.")
+	}
+	var tags []string
+	if channel, err := p.DiscordService.Session.Channel(i.ChannelID); err != nil {
+		core.Logger.Warnf("Error fetching channel, saving links without tags: %v", err)
+	} else if channel.Name != "" {
+		tags = []string{channel.Name}
+	}
+	jumpLink := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", i.GuildID, m.ChannelID, m.ID)
+	b := discord.SuccessEmbed("Links saved").Descriptionf("The following sites of [the message](%s) have been saved", jumpLink)
+	for _, link := range links {
+		aPo := archivePO{
+			Site:      link,
+			Tags:      tags,
+			Note:      jumpLink,
+			GuildID:   i.GuildID,
+			ChannelID: i.ChannelID,
+			UserID:    i.Member.User.ID,
+		}
+		if err := p.saveSite(&aPo); err != nil {
+			core.Logger.Warnf("Error inserting archive document: %v", err)
+			r.RespondText("Internal error inserting! Please contact admin for help.")
+			return err
+		}
+		b.Field(aPo.displayTitle(), aPo.essentialInfo())
+	}
+	return r.RespondEmbed(b.Build())
+}
+
+// linkPattern urls in message texts. Trailing punctuation and markdown brackets are not part of them.
+var linkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]*[^\s<>()\[\]"'` + "`" + `.,;:!?*_~|]`)
+
+// messageLinks valid urls found in the content and embeds of the message, without duplicates, in order.
+func messageLinks(m *discordgo.Message) []string {
+	texts := []string{m.Content}
+	for _, embed := range m.Embeds {
+		texts = append(texts, embed.URL, embed.Description)
+		for _, field := range embed.Fields {
+			texts = append(texts, field.Value)
+		}
+	}
+	var links []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, link := range linkPattern.FindAllString(text, -1) {
+			if _, err := url.ParseRequestURI(link); err != nil || seen[link] {
+				continue
+			}
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
 func (p *ArchivePlugin) handleListSite(i *discordgo.Interaction, optionsMap map[string]*discordgo.ApplicationCommandInteractionDataOption) error {
-	query := bson.M{"user_id": i.Member.User.ID, "guild_id": i.GuildID}
-	//if found optional tags, add it to the query
-	//set tags
+	var tags string
 	if tagsOption, ok := optionsMap["tags"]; ok {
-		parsedTags := p.SeparateArgs(tagsOption.StringValue(), p.DiscordService.DiscordAccountConfig.Separator)
-		query["tags"] = bson.M{"$all": parsedTags}
+		tags = tagsOption.StringValue()
+	}
+	pageSize := defaultArchivePageSize
+	if sizeOption, ok := optionsMap["page-size"]; ok {
+		pageSize = int(sizeOption.IntValue())
+	}
+	// the list is private to the user, and counting a large archive may take a while
+	r := p.DiscordService.NewResponder(i, true)
+	defer r.Done()
+	return p.startSiteListPager(r, p.newSiteListPager(i.Member.User.ID, i.GuildID, tags, pageSize))
+}
+
+// handleListSiteText the text counterpart of handleListSite: $archive list [tags], paged with reactions.
+func (p *ArchivePlugin) handleListSiteText(m *discordgo.Message) error {
+	prefix := p.DiscordService.MessengerConfig(m.GuildID).Prefix
+	args := p.SeparateArgs(m.Content, " ")
+	if len(args) < 2 || args[1] != "list" {
+		_, err := p.DiscordService.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Usage: %sarchive list [tags]", prefix))
+		return err
+	}
+	// the tags are split on the separator by newSiteListPager
+	tags := strings.Join(args[2:], " ")
+	archiveListPager := p.newSiteListPager(m.Author.ID, m.GuildID, tags, defaultArchivePageSize)
+	archiveListPager.UseReactions = true
+	return p.startSiteListPager(m, archiveListPager)
+}
+
+// handleListSiteConsole $archive list [tags] on the console, the operator of the bot, lists the sites of every user.
+func (p *ArchivePlugin) handleListSiteConsole(m *console.Message) error {
+	config := p.ConsoleService.ConsoleAccountConfig
+	args := p.SeparateArgs(m.Content, " ")
+	if len(args) < 2 || args[1] != "list" {
+		return p.ConsoleService.Reply(m, fmt.Sprintf("Usage: %sarchive list [tags]", config.Prefix))
+	}
+	var query data.Filter
+	if tags := p.SeparateArgs(strings.Join(args[2:], " "), config.Separator); len(tags) > 0 {
+		query = data.Where(data.All("tags", tags))
+	}
+	count, err := p.getCollection().Count(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	sites, err := p.findArchivePoPage(query, 0, consoleArchiveListLimit)
+	if err != nil {
+		return err
+	}
+	lines := []string{fmt.Sprintf("%d site(s) found.", count)}
+	for k, part := range sites {
+		site := part.(*archivePO)
+		lines = append(lines, fmt.Sprintf("%d. %s %s [%s] (guild %s, user %s)",
+			k+1, site.displayTitle(), site.Site, strings.Join(site.Tags, ","), site.GuildID, site.UserID))
+	}
+	if count > int64(len(sites)) {
+		lines = append(lines, fmt.Sprintf("... and %d more.", count-int64(len(sites))))
+	}
+	return p.ConsoleService.Reply(m, strings.Join(lines, "\n"))
+}
 
+// newSiteListPager a pager over the sites of the user in the guild, filtered by tags when not empty.
+func (p *ArchivePlugin) newSiteListPager(userID, guildID, tags string, pageSize int) *discord.Pager {
+	query := data.Where(data.Eq("user_id", userID), data.Eq("guild_id", guildID))
+	//if found optional tags, add it to the query
+	if parsedTags := p.SeparateArgs(tags, p.DiscordService.MessengerConfig(guildID).Separator); len(parsedTags) > 0 {
+		query = query.And(data.All("tags", parsedTags))
 	}
-	archiveListPager := discord.Pager{
-		IPagerLoader: &archivePoPagerLoader{
-			query:     query,
-			queryFunc: p.findArchivePo,
+	return &discord.Pager{
+		IPagerLoader: &discord.LazyPagerLoader{
+			CountFunc: func() (int, error) {
+				count, err := p.getCollection().Count(context.Background(), query)
+				return int(count), err
+			},
+			LoadFunc: func(skip, limit int) ([]discord.IPagerPart, error) {
+				return p.findArchivePoPage(query, skip, limit)
+			},
 		},
 		PageNow: 1,
-		Limit:   7,
+		Limit:   pageSize,
+		FirstPageButton: discordgo.Button{
+			Label:    discord.EmojiFirstPage,
+			Style:    discordgo.SecondaryButton,
+			CustomID: lsButtonIDFirst,
+		},
+		LastPageButton: discordgo.Button{
+			Label:    discord.EmojiLastPage,
+			Style:    discordgo.SecondaryButton,
+			CustomID: lsButtonIDLast,
+		},
+		PageSelectID: lsSelectIDPage,
 		PrevPageButton: discordgo.Button{
 			Label:    discord.EmojiLeftArrow,
 			Style:    discordgo.PrimaryButton,
@@ -99,14 +262,14 @@ func (p *ArchivePlugin) handleListSite(i *discordgo.Interaction, optionsMap map[
 			Style:    discordgo.PrimaryButton,
 			CustomID: lsButtonIDNext,
 		},
-		EmbedFrame: &discordgo.MessageEmbed{
-			Title:     "ls-site result",
-			Color:     discord.EmbedColorNormal,
-			Timestamp: time.Now().Format(time.RFC3339),
-		},
-		Overtime: time.Duration(5) * time.Minute,
+		EmbedFrame: discord.InfoEmbed("ls-site result").Build(),
+		Overtime:   time.Duration(5) * time.Minute,
 	}
-	if err := archiveListPager.Setup(i, p.DiscordService); err != nil {
+}
+
+// startSiteListPager send the first page as a response to the trigger, a discord.Responder or a message.
+func (p *ArchivePlugin) startSiteListPager(trigger any, archiveListPager *discord.Pager) error {
+	if err := archiveListPager.Setup(trigger, p.DiscordService); err != nil {
 		core.Logger.Warnf("Error setup pager: %v", err)
 		return err
 	}
@@ -116,7 +279,7 @@ func (p *ArchivePlugin) handleListSite(i *discordgo.Interaction, optionsMap map[
 	//	stage.Init(&archiveListPager, p)
 	//}
 	var stage archiveQueryStage
-	stage.Init(&archiveListPager, p)
+	stage.Init(archiveListPager, p)
 	return nil
 }
 
@@ -137,11 +300,21 @@ func (p *ArchivePlugin) handleModifySite(i *discordgo.Interaction, optionsMap ma
 	}
 	rawStage, _ := p.StageUtil.GetStage(key)
 	aqs := rawStage.(*archiveQueryStage)
-	if id <= 0 || id > len(aqs.Pager.CompleteItemSlice) {
+	item, err := aqs.Pager.ItemAt(id)
+	if errors.Is(err, discord.ErrPagerItemOutOfRange) {
 		p.DiscordService.InteractionRespond(i, "Malformed relative-ID. Check your last query?")
 		return nil
+	} else if err != nil {
+		core.Logger.Warnf("Error loading archive document: %v", err)
+		p.DiscordService.InteractionRespond(i, "Internal error loading the site! Please contact admin for help.")
+		return err
+	}
+	modifyingPo := item.(*archivePO)
+	// with nothing to change in the options, edit the site in a form
+	if len(optionsMap) == 1 {
+		return p.DiscordService.InteractionRespondModal(i, archiveModifyModalPrefix+modifyingPo.BsonID.Hex(),
+			"Modify a site", archiveModalInputs(modifyingPo)...)
 	}
-	modifyingPo := (*aqs.Pager.CompleteItemSlice[id-1]).(*archivePO)
 	tempTags, ok := optionsMap["tags"]
 	if ok {
 		tagsStr := tempTags.StringValue()
@@ -149,7 +322,7 @@ func (p *ArchivePlugin) handleModifySite(i *discordgo.Interaction, optionsMap ma
 			//clean up
 			modifyingPo.Tags = []string{}
 		} else {
-			ephemeralTags := p.SeparateArgs(tagsStr, p.DiscordService.DiscordAccountConfig.Separator)
+			ephemeralTags := p.SeparateArgs(tagsStr, p.DiscordService.MessengerConfig(i.GuildID).Separator)
 			modifyingPo.Tags = ephemeralTags
 		}
 	}
@@ -163,62 +336,122 @@ func (p *ArchivePlugin) handleModifySite(i *discordgo.Interaction, optionsMap ma
 			modifyingPo.Note = noteStr
 		}
 	}
-	res := p.updateArchivePoWithID(*modifyingPo)
-	if res.Err() != nil {
-		p.DiscordService.InteractionRespond(i, res.Err().Error())
+	if err := p.updateArchivePoWithID(*modifyingPo); err != nil {
+		p.DiscordService.InteractionRespond(i, err.Error())
+		return nil
+	}
+	return discord.SuccessEmbed("Site record updated").
+		Description("The following site has been updated").
+		Field(modifyingPo.displayTitle(), modifyingPo.essentialInfo()).
+		Respond(p.DiscordService, i)
+}
+
+// archiveModalInputs inputs of the save and modify forms, pre-filled with the site if any.
+// Tags are separated by commas, an empty field clears them.
+func archiveModalInputs(aPo *archivePO) []discordgo.TextInput {
+	inputs := []discordgo.TextInput{
+		{CustomID: "url", Label: "URL", Style: discordgo.TextInputShort, Required: true, Placeholder: "https://"},
+		{CustomID: "title", Label: "Title", Style: discordgo.TextInputShort, MaxLength: discord.EmbedFieldNameLimit},
+		{CustomID: "tags", Label: "Tags", Style: discordgo.TextInputShort, Placeholder: "tags, separated by commas"},
+		{CustomID: "note", Label: "Note", Style: discordgo.TextInputParagraph, MaxLength: discord.TextInputValueLimit},
+	}
+	if aPo != nil {
+		inputs[0].Value = aPo.Site
+		inputs[1].Value = aPo.Title
+		inputs[2].Value = strings.Join(aPo.Tags, ", ")
+		inputs[3].Value = aPo.Note
+	}
+	return inputs
+}
+
+// handleSiteModal save or update the site submitted through a form of archiveModalInputs.
+func (p *ArchivePlugin) handleSiteModal(i *discordgo.Interaction) error {
+	customID := i.ModalSubmitData().CustomID
+	values := discord.ModalValues(i)
+	if !validSiteURL(strings.TrimSpace(values["url"])) {
+		return p.DiscordService.InteractionRespondEphemeral(i, "You must provide a *valid* url!")
+	}
+	var aPo *archivePO
+	if customID == archiveSaveModalID {
+		aPo = &archivePO{GuildID: i.GuildID, ChannelID: i.ChannelID, UserID: i.Member.User.ID}
+	} else {
+		id, err := primitive.ObjectIDFromHex(strings.TrimPrefix(customID, archiveModifyModalPrefix))
+		if err != nil {
+			return fmt.Errorf("malformed archive modal id %s: %w", customID, err)
+		}
+		// only the owner edits the site, as in the list
+		results, err := p.findArchivePo(data.Where(data.Eq("_id", id), data.Eq("user_id", i.Member.User.ID)))
+		if err != nil {
+			p.DiscordService.InteractionRespond(i, "Internal error loading the site! Please contact admin for help.")
+			return err
+		}
+		if len(results) == 0 {
+			return p.DiscordService.InteractionRespondEphemeral(i, "This site no longer exists.")
+		}
+		aPo = results[0]
+	}
+	aPo.Site = strings.TrimSpace(values["url"])
+	aPo.Title = strings.TrimSpace(values["title"])
+	aPo.Note = strings.TrimSpace(values["note"])
+	aPo.Tags = []string{}
+	for _, tag := range strings.Split(values["tags"], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			aPo.Tags = append(aPo.Tags, tag)
+		}
+	}
+
+	if customID == archiveSaveModalID {
+		if err := p.saveSite(aPo); err != nil {
+			core.Logger.Warnf("Error inserting archive document: %v", err)
+			p.DiscordService.InteractionRespond(i, "Internal error inserting! Please contact admin for help.")
+			return err
+		}
+		return discord.SuccessEmbed("Site saved").
+			Description("The following site has been saved").
+			Field(aPo.displayTitle(), aPo.essentialInfo()).
+			Respond(p.DiscordService, i)
+	}
+	aPo.setTime(false)
+	if err := p.updateArchivePoWithID(*aPo); err != nil {
+		p.DiscordService.InteractionRespond(i, err.Error())
 		return nil
 	}
-	return p.DiscordService.InteractionRespondEmbed(i, &discordgo.MessageEmbed{
-		Title:       "Site record updated",
-		Description: "The following site has been updated",
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Color:       discord.EmbedColorNormal,
-		Fields: []*discordgo.MessageEmbedField{{
-			Name:   modifyingPo.Title,
-			Value:  modifyingPo.essentialInfoForEmbed(),
-			Inline: false,
-		}},
-	}, nil)
+	return discord.SuccessEmbed("Site record updated").
+		Description("The following site has been updated").
+		Field(aPo.displayTitle(), aPo.essentialInfo()).
+		Respond(p.DiscordService, i)
 }
 
 func (p *ArchivePlugin) handleRemoveSite(i *discordgo.Interaction, optionsMap map[string]*discordgo.ApplicationCommandInteractionDataOption) error {
 	tempID, ok := optionsMap["relative-id"]
 	if !ok {
-		p.DiscordService.InteractionRespond(i, "no relative-ID provided!")
-		return nil
+		return p.DiscordService.InteractionRespond(i, "no relative-ID provided!")
 	}
 	id := int(tempID.IntValue())
 
 	//new logic
 	key := p.findActiveRelativeID(i)
 	if key == "" {
-		p.DiscordService.InteractionRespond(i, "No active query for you! Run a new query first?")
-		return nil
+		return p.DiscordService.InteractionRespond(i, "No active query for you! Run a new query first?")
 	}
 	rawStage, _ := p.StageUtil.GetStage(key)
 	aqs := rawStage.(*archiveQueryStage)
-	if id <= 0 || id > len(aqs.Pager.CompleteItemSlice) {
-		p.DiscordService.InteractionRespond(i, "Malformed relative-ID. Check your last query?")
-		return nil
+	item, err := aqs.Pager.ItemAt(id)
+	if errors.Is(err, discord.ErrPagerItemOutOfRange) {
+		return p.DiscordService.InteractionRespond(i, "Malformed relative-ID. Check your last query?")
+	} else if err != nil {
+		core.Logger.Warnf("Error loading archive document: %v", err)
+		p.DiscordService.InteractionRespond(i, "Internal error loading the site! Please contact admin for help.")
+		return err
 	}
-	deletingPo := (*aqs.Pager.CompleteItemSlice[id-1]).(*archivePO)
-	delResult := p.deleteArchivePoWithID(*deletingPo)
-	if delResult.Err() != nil {
-		p.DiscordService.InteractionRespond(i, delResult.Err().Error())
-		return nil
+	deletingPo := item.(*archivePO)
+	if err := p.deleteArchivePoWithID(*deletingPo); err != nil {
+		return p.DiscordService.InteractionRespond(i, err.Error())
 	}
-	p.DiscordService.InteractionRespondEmbed(i, &discordgo.MessageEmbed{
-		Title:       "Site record deleted",
-		Description: "The following site has been deleted",
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Color:       discord.EmbedColorNormal,
-		Fields: []*discordgo.MessageEmbedField{{
-			Name:   deletingPo.Title,
-			Value:  deletingPo.essentialInfoForEmbed(),
-			Inline: false,
-		}},
-	}, nil)
-	return nil
+	return discord.DangerEmbed("Site record deleted").
+		Description("The following site has been deleted").
+		Field(deletingPo.Title, deletingPo.essentialInfo()).
+		Respond(p.DiscordService, i)
 }
 
 func (p *ArchivePlugin) findActiveRelativeID(i *discordgo.Interaction) core.CombinedKey {
@@ -250,6 +483,8 @@ func (p *ArchivePlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.Interaction
 		case "archive":
 			cmdOption := i.ApplicationCommandData().Options[0]
 			switch cmdOption.Name {
+			case "token":
+				return p.handleAPIToken(i.Interaction, p.ParseOptionsMap(cmdOption.Options))
 			case "site":
 				cmdOption := cmdOption.Options[0]
 				optionsMap := p.ParseOptionsMap(cmdOption.Options)
@@ -269,22 +504,88 @@ func (p *ArchivePlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.Interaction
 	return nil
 }
 
+// DoAutocomplete suggest tags of the sites of the user in the guild. Only the tag after the last separator is completed.
+func (p *ArchivePlugin) DoAutocomplete(i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if focused.Name != "tags" {
+		return nil, nil
+	}
+	separator := p.DiscordService.MessengerConfig(i.GuildID).Separator
+	typed := discord.FocusedValue(focused)
+	var typedTags []string
+	query := typed
+	if cut := strings.LastIndex(typed, separator); cut >= 0 {
+		typedTags = p.SeparateArgs(typed[:cut], separator)
+		query = typed[cut+len(separator):]
+	}
+	sites, err := p.findArchivePo(data.Where(data.Eq("user_id", i.Member.User.ID), data.Eq("guild_id", i.GuildID)))
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range typedTags {
+		seen[tag] = true
+	}
+	for _, site := range sites {
+		for _, tag := range site.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, tag := range tags {
+		value := strings.Join(append(append([]string(nil), typedTags...), tag), separator)
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: tag, Value: value})
+	}
+	return discord.FilterChoices(query, choices), nil
+}
+
+func (p *ArchivePlugin) DoMessageCommand(_ *core.Bot, i *discordgo.InteractionCreate) error {
+	if match, name := p.MatchMessageCommand(i); match && name == archiveLinksCommand {
+		return p.handleArchiveLinks(i)
+	}
+	return nil
+}
+
 func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 	// services
-	//discordService is a MUST have. return error if not found.
-	if err := reg.FetchService(&p.DiscordService); err != nil {
-		return err
+	//discordService and consoleService are both optional, but at least one of them is required.
+	discordErr := reg.FetchService(&p.DiscordService)
+	if err := reg.FetchService(&p.ConsoleService); err != nil && discordErr != nil {
+		return discordErr
 	}
 	// DataService is also a MUST have. return error if not found.
 	if err := reg.FetchService(&p.DataService); err != nil {
 		return err
 	}
+	if err := p.DataService.RegisterSchema(archiveSchema); err != nil {
+		return err
+	}
+	// ForwardService is optional, saved sites are forwarded when it's registered.
+	_ = reg.FetchService(&p.ForwardService)
+	// WebService is optional, the REST API is served when it's registered.
+	if err := reg.FetchService(&p.WebService); err == nil {
+		p.mountAPI()
+	}
 	// core plugin type
-	p.Plugin = core.Plugin{
-		Name:                 "archive",
-		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord},
+	p.Plugin = core.Plugin{Name: "archive"}
+	prefix := "$"
+	// shown in the descriptions of tag options
+	var separator string
+	if p.ConsoleService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, console.TriggerTypeConsole)
+		prefix = p.ConsoleService.ConsoleAccountConfig.Prefix
+	}
+	if p.DiscordService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, discord.TriggerTypeDiscord)
+		prefix = p.DiscordService.DiscordAccountConfig.Prefix
+		separator = p.DiscordService.DiscordAccountConfig.Separator
 	}
 	// utils
+	p.Identifiers = []string{"archive"}
 	p.ArgParseUtil = core.ArgParseUtil{}
 	p.StageUtil = core.NewStageUtil()
 
@@ -294,6 +595,19 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 		Name:        "archive",
 		Description: "archive certain things",
 		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "token",
+				Description: "Issue a personal token for the archive API, replacing the previous one.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "revoke",
+						Description: "Revoke your token instead.",
+						Required:    false,
+					},
+				},
+			},
 			{
 				Name:        "site",
 				Description: "site-saving commands",
@@ -307,15 +621,16 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 							{
 								Type:        discordgo.ApplicationCommandOptionString,
 								Name:        "url",
-								Description: "The valid Url to be stored into database.",
-								Required:    true,
+								Description: "The valid Url to be stored into database. Leave it out to fill a form.",
+								Required:    false,
 							},
 							{
-								Type: discordgo.ApplicationCommandOptionString,
-								Name: "tags",
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "tags",
+								Autocomplete: true,
 								//late init, replace %s with separator
 								Description: fmt.Sprintf("Add tags for this site, separated by default separator."+
-									" Current separator:[%s]", p.DiscordService.DiscordAccountConfig.Separator),
+									" Current separator:[%s]", separator),
 								Required: false,
 							},
 							{
@@ -323,7 +638,7 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 								Name: "note",
 								//same as above
 								Description: fmt.Sprintf("Add note for this site."+
-									" Current separator:[%s]", p.DiscordService.DiscordAccountConfig.Separator),
+									" Current separator:[%s]", separator),
 								Required: false,
 							},
 							{
@@ -339,13 +654,22 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 						Description: "List all sites",
 						Options: []*discordgo.ApplicationCommandOption{
 							{
-								Type: discordgo.ApplicationCommandOptionString,
-								Name: "tags",
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "tags",
+								Autocomplete: true,
 								//late init, replace %s with separator
 								Description: fmt.Sprintf("Search tags for this site, separated by default separator."+
-									" Current separator:[%s]", p.DiscordService.DiscordAccountConfig.Separator),
+									" Current separator:[%s]", separator),
 								Required: false,
 							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "page-size",
+								Description: fmt.Sprintf("Sites shown in a page, %d by default.", defaultArchivePageSize),
+								Required:    false,
+								MinValue:    &minArchivePageSize,
+								MaxValue:    discord.EmbedFieldCountLimit,
+							},
 						},
 					},
 					{
@@ -356,7 +680,7 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 							{
 								Type:        discordgo.ApplicationCommandOptionInteger,
 								Name:        "relative-id",
-								Description: "The relative id of item in the last query",
+								Description: "The relative id of item in the last query. Give it alone to edit in a form.",
 								Required:    true,
 							},
 							{
@@ -366,11 +690,12 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 								Required:    false,
 							},
 							{
-								Type: discordgo.ApplicationCommandOptionString,
-								Name: "tags",
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "tags",
+								Autocomplete: true,
 								//late init, replace %s with separator
 								Description: fmt.Sprintf("Add tags for this site, separated by default separator."+
-									" Current separator:[%s]", p.DiscordService.DiscordAccountConfig.Separator),
+									" Current separator:[%s]", separator),
 								Required: false,
 							},
 							{
@@ -378,7 +703,7 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 								Name: "note",
 								//same as above
 								Description: fmt.Sprintf("Add note for this site."+
-									" Current separator:[%s]", p.DiscordService.DiscordAccountConfig.Separator),
+									" Current separator:[%s]", separator),
 								Required: false,
 							},
 							{
@@ -412,17 +737,29 @@ func (p *ArchivePlugin) Init(reg *core.ServiceRegistry) error {
 		},
 	})
 
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name: archiveLinksCommand,
+		Type: discordgo.MessageApplicationCommand,
+	})
+
 	// discord helps
 	formattedHelpSiteSet := `*archive site save*: /archive site save
-Save the given website to dalian database. You will have the option to save a snapshot of it.`
-	formattedHelpSiteList := `*archive site list*: /archive site list
-List all sites archived by dalian. You can filter with tags.`
+Save the given website to dalian database. You will have the option to save a snapshot of it.
+Leave the url out to fill a form with a title, tags and a multi-line note instead.`
+	formattedHelpSiteList := `*archive site list*: /archive site list, ` + prefix + `archive list [tags]
+List all sites archived by dalian. You can filter with tags.
+Results of the text command are paged with reactions.`
+	formattedHelpLinks := `*archive links*: right-click a message, Apps > ` + archiveLinksCommand + `
+Save every link of the message, tagged with the channel name. The note links back to the message.`
 	formattedHelpSiteModify := `*archive site modify*: /archive site modify
-Modify a site archived by dalian.
+Modify a site archived by dalian. Give the relative-ID alone to edit the site in a pre-filled form.
 You MUST first run a query with *archive site list* to get an active relative-ID for the site`
 	formattedHelpSiteDelete := `*archive site delete*: /archive site delete
 Delete a site archived by dalian.
 You MUST first run a query with *archive site list* to get an active relative-ID for the site`
+	formattedHelpToken := `*archive token*: /archive token [revoke]
+Issue a personal token for the REST API at ` + archiveAPIPath + `/sites of the web service, for the sites saved in this server.
+Bookmarklets and scripts can save, list, modify and delete sites with it. A new token replaces the previous one.`
 
 	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
 		PluginHelp: "Archive online resources.",
@@ -435,6 +772,10 @@ You MUST first run a query with *archive site list* to get an active relative-ID
 				Name:          "archive site list",
 				FormattedHelp: formattedHelpSiteList,
 			},
+			{
+				Name:          "archive links",
+				FormattedHelp: formattedHelpLinks,
+			},
 			{
 				Name:          "archive site modify",
 				FormattedHelp: formattedHelpSiteModify,
@@ -443,8 +784,15 @@ You MUST first run a query with *archive site list* to get an active relative-ID
 				Name:          "archive site delete",
 				FormattedHelp: formattedHelpSiteDelete,
 			},
+			{
+				Name:          "archive token",
+				FormattedHelp: formattedHelpToken,
+			},
 		},
 	})
+	if p.DiscordService == nil {
+		return nil
+	}
 	return p.DiscordService.RegisterSlashCommand(p)
 }
 
@@ -453,9 +801,36 @@ func (p *ArchivePlugin) Trigger(trigger core.Trigger) {
 	if !p.AcceptTrigger(trigger.Type) {
 		return
 	}
+	if trigger.Type == console.TriggerTypeConsole {
+		m := console.UnboxEvent(trigger).Message
+		if match, _ := p.MatchText(m.Content, p.ConsoleService.ConsoleAccountConfig); match {
+			if err := p.handleListSiteConsole(m); err != nil {
+				core.Logger.Warnf("Error executing console command: %v", err)
+			}
+		}
+		return
+	}
 	discordEvent := discord.UnboxEvent(trigger)
 	switch discordEvent.EventType {
-	// only accepting interactionCreate for discord trigers
+	case discord.EventTypeMessageCreate:
+		m := discordEvent.MessageCreate.Message
+		if p.DiscordService.IsGuildMessageFromBotOrSelf(m) || m.GuildID == "" {
+			return
+		}
+		if match, _ := p.MatchText(m.Content, p.DiscordService.MessengerConfig(m.GuildID)); match {
+			if err := p.handleListSiteText(m); err != nil {
+				core.Logger.Warnf("Error executing text command: %v", err)
+			}
+		}
+	case discord.EventTypeMessageReactionAdd:
+		// reaction (text pager)
+		if stage, ok := p.StageUtil.GetStage(p.getPagerKey(discordEvent.MessageReactionAdd.MessageID)); ok {
+			stage.Process(discordEvent.MessageReactionAdd)
+		}
+	case discord.EventTypeMessageCommand:
+		if err := p.DoMessageCommand(trigger.Bot, discordEvent.InteractionCreate); err != nil {
+			core.Logger.Warnf("Error executing message command: %v", err)
+		}
 	case discord.EventTypeInteractionCreate:
 		switch discordEvent.InteractionCreate.Type {
 		case discordgo.InteractionApplicationCommand:
@@ -463,6 +838,14 @@ func (p *ArchivePlugin) Trigger(trigger core.Trigger) {
 			if err := p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate); err != nil {
 				core.Logger.Warnf("Error executing slash command: %v", err)
 			}
+		case discordgo.InteractionModalSubmit:
+			// save and modify forms
+			if customID := discordEvent.InteractionCreate.ModalSubmitData().CustomID; customID == archiveSaveModalID ||
+				strings.HasPrefix(customID, archiveModifyModalPrefix) {
+				if err := p.handleSiteModal(discordEvent.InteractionCreate.Interaction); err != nil {
+					core.Logger.Warnf("Error executing archive form: %v", err)
+				}
+			}
 		case discordgo.InteractionMessageComponent:
 			// message component (pager)
 			if stage, ok := p.StageUtil.GetStage(p.getPagerKey(discordEvent.InteractionCreate.Message.ID)); ok {
@@ -473,33 +856,32 @@ func (p *ArchivePlugin) Trigger(trigger core.Trigger) {
 			return
 		}
 	default:
-		// does not handle messageCreate or anything like that.
 		return
 	}
 }
 
 type archivePO struct {
 	//Display Info
-	BsonID primitive.ObjectID `bson:"_id,omitempty"`
-	Site   string             `bson:"site"`
-	Tags   []string           `bson:"tags"`
-	Note   string             `bson:"note"`
+	BsonID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Site   string             `bson:"site" json:"site"`
+	Tags   []string           `bson:"tags" json:"tags"`
+	Note   string             `bson:"note" json:"note"`
 	//Retrieved Info
-	Title       string `bson:"title"`
-	SnapshotURL string `bson:"snapshot_url,omitempty"`
+	Title       string `bson:"title" json:"title"`
+	SnapshotURL string `bson:"snapshot_url,omitempty" json:"snapshot_url,omitempty"`
 	//Credential Info
-	GuildID   string `bson:"guild_id"`
-	ChannelID string `bson:"channel_id"`
-	UserID    string `bson:"user_id"`
+	GuildID   string `bson:"guild_id" json:"guild_id"`
+	ChannelID string `bson:"channel_id" json:"channel_id"`
+	UserID    string `bson:"user_id" json:"user_id"`
 	//Auditing info
-	CreatedTime      time.Time `bson:"created_time"`
-	LastModifiedTime time.Time `bson:"last_modified_time"`
+	CreatedTime      time.Time `bson:"created_time" json:"created_time"`
+	LastModifiedTime time.Time `bson:"last_modified_time" json:"last_modified_time"`
 }
 
 func (ap *archivePO) ToMessageEmbedField(displayID int) *discordgo.MessageEmbedField {
 	return &discordgo.MessageEmbedField{
-		Name:   fmt.Sprintf("%d. Temporary Title", displayID),
-		Value:  ap.essentialInfoForEmbed(),
+		Name:   fmt.Sprintf("%d. %s", displayID, ap.displayTitle()),
+		Value:  ap.essentialInfo(),
 		Inline: false,
 	}
 }
@@ -512,26 +894,16 @@ func (ap *archivePO) setTime(isCreate bool) {
 	ap.LastModifiedTime = currentTime
 }
 
-func (ap *archivePO) essentialInfo() string {
-	var tags, note string
-	if len(ap.Tags) == 0 {
-		tags = "*None*"
-	} else {
-		tags = "[" + strings.Join(ap.Tags, ",") + "]"
-	}
-
-	if ap.Note == "" {
-		note = "*None*"
-	} else {
-		note = ap.Note
+// displayTitle the title, or a placeholder for sites saved without one.
+func (ap *archivePO) displayTitle() string {
+	if ap.Title == "" {
+		return "Temporary Title"
 	}
-	essentialInfo := "> Site: %s\r" +
-		"> Tags: %s\r" +
-		"> Note: %s"
-	return fmt.Sprintf(essentialInfo, ap.Site, tags, note)
+	return ap.Title
 }
 
-func (ap *archivePO) essentialInfoForEmbed() string {
+// essentialInfo site, tags, note and snapshot link of the record, as an embed field value.
+func (ap *archivePO) essentialInfo() string {
 	var tags, note, optSnapshot string
 	if len(ap.Tags) == 0 {
 		tags = "*None*"
@@ -554,26 +926,95 @@ func (ap *archivePO) essentialInfoForEmbed() string {
 	return fmt.Sprintf(essentialInfo, ap.Site, tags, note, optSnapshot)
 }
 
-func (p *ArchivePlugin) getCollection() *mongo.Collection {
-	return p.DataService.GetCollection("site_collection")
+const archiveCollection = "site_collection"
+
+// archiveSchema the list query filters on user, guild and, optionally, tags. API tokens are looked up by digest.
+var archiveSchema = data.Schema{
+	Namespace: "archive",
+	Migrations: []data.Migration{{
+		Version:     1,
+		Description: "drop empty snapshot_url values, sites without a snapshot have no snapshot_url",
+		Up:          migrateArchiveSnapshotURL,
+	}},
+	Indexes: []data.Index{{
+		Collection: archiveCollection,
+		Name:       "user_guild_tags",
+		Keys:       []data.SortField{{Field: "user_id"}, {Field: "guild_id"}, {Field: "tags"}},
+	}, {
+		Collection: archiveTokenCollection,
+		Name:       "token_hash",
+		Keys:       []data.SortField{{Field: "token_hash"}},
+		Unique:     true,
+	}, {
+		Collection: archiveTokenCollection,
+		Name:       "user_guild",
+		Keys:       []data.SortField{{Field: "user_id"}, {Field: "guild_id"}},
+		Unique:     true,
+	}},
 }
 
-func (p *ArchivePlugin) insertOneArchivePo(po archivePO) data.Result {
-	return p.DataService.InsertOne(po, p.getCollection(), context.Background())
+// migrateArchiveSnapshotURL see archiveSchema. Sites saved before snapshot_url was omitted when empty have it as "" or null.
+func migrateArchiveSnapshotURL(ctx context.Context, store data.Store) error {
+	collection := store.Collection(archiveCollection)
+	var sites []bson.M
+	if err := collection.Find(ctx, &sites, data.Where(data.Exists("snapshot_url", true))); err != nil {
+		return err
+	}
+	for _, site := range sites {
+		if snapshot, _ := site["snapshot_url"].(string); snapshot != "" {
+			continue
+		}
+		if _, err := collection.UnsetOne(ctx, data.ByID(site["_id"]), "snapshot_url"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (p *ArchivePlugin) findArchivePo(query any) ([]*archivePO, error) {
+func (p *ArchivePlugin) getCollection() data.Collection {
+	return p.DataService.Collection(archiveCollection)
+}
+
+func (p *ArchivePlugin) insertOneArchivePo(po archivePO) (primitive.ObjectID, error) {
+	id, err := p.getCollection().InsertOne(context.Background(), po)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return id.(primitive.ObjectID), nil
+}
+
+func (p *ArchivePlugin) findArchivePo(query data.Filter) ([]*archivePO, error) {
 	var results []*archivePO
-	err := p.DataService.Find(&results, p.getCollection(), context.Background(), query)
+	err := p.getCollection().Find(context.Background(), &results, query)
 	return results, err
 }
 
-func (p *ArchivePlugin) updateArchivePoWithID(po archivePO) data.Result {
-	return p.DataService.UpdateByID(bson.D{{"$set", data.ToBsonDocForce(po)}}, po.BsonID, p.getCollection(), context.Background())
+// findArchivePoPage a page of the query results, oldest first.
+func (p *ArchivePlugin) findArchivePoPage(query data.Filter, skip, limit int) ([]discord.IPagerPart, error) {
+	var results []*archivePO
+	err := p.getCollection().Find(context.Background(), &results, query, data.FindOptions{
+		Sort:  []data.SortField{{Field: "_id"}},
+		Skip:  int64(skip),
+		Limit: int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]discord.IPagerPart, len(results))
+	for k, v := range results {
+		parts[k] = v
+	}
+	return parts, nil
+}
+
+func (p *ArchivePlugin) updateArchivePoWithID(po archivePO) error {
+	_, err := p.getCollection().UpdateOne(context.Background(), data.ByID(po.BsonID), po, false)
+	return err
 }
 
-func (p *ArchivePlugin) deleteArchivePoWithID(po archivePO) data.Result {
-	return p.DataService.DeleteOne(p.getCollection(), context.Background(), bson.M{"_id": po.BsonID})
+func (p *ArchivePlugin) deleteArchivePoWithID(po archivePO) error {
+	_, err := p.getCollection().DeleteOne(context.Background(), data.ByID(po.BsonID))
+	return err
 }
 
 type archiveQueryStage struct {
@@ -582,12 +1023,12 @@ type archiveQueryStage struct {
 	ChannelID   string
 	GuildID     string
 	CreatedTime time.Time
-	triggerChan chan *discordgo.Interaction
+	triggerChan chan any // *discordgo.Interaction or *discordgo.MessageReactionAdd
 	plugin      *ArchivePlugin
 }
 
 func (a *archiveQueryStage) Process(t any) {
-	a.triggerChan <- t.(*discordgo.Interaction)
+	a.triggerChan <- t
 }
 
 func (a *archiveQueryStage) Init(pager *discord.Pager, plugin *ArchivePlugin) {
@@ -596,7 +1037,7 @@ func (a *archiveQueryStage) Init(pager *discord.Pager, plugin *ArchivePlugin) {
 	a.ChannelID = pager.AttachedMessage.ChannelID
 	a.GuildID = pager.AttachedMessage.GuildID
 	a.CreatedTime = time.Now()
-	a.triggerChan = make(chan *discordgo.Interaction, 1)
+	a.triggerChan = make(chan any, 1)
 	a.plugin = plugin
 	key := a.plugin.getPagerKey(pager.AttachedMessage.ID)
 	go func() {
@@ -604,19 +1045,24 @@ func (a *archiveQueryStage) Init(pager *discord.Pager, plugin *ArchivePlugin) {
 		func() {
 			for {
 				select {
-				case interaction, ok := <-a.triggerChan:
+				case t, ok := <-a.triggerChan:
 					if !ok {
 						fmt.Println("Aborted")
 						return
 					}
-					switch interaction.MessageComponentData().CustomID {
-					case lsButtonIDPrev:
-						a.Pager.SwitchPage(core.PagerPrevPage, interaction)
-					case lsButtonIDNext:
-						a.Pager.SwitchPage(core.PagerNextPage, interaction)
-					default:
-						core.Logger.Warnf("Unknown customID: %n" + interaction.MessageComponentData().CustomID)
-						return
+					switch t := t.(type) {
+					case *discordgo.Interaction:
+						if handled, err := a.Pager.HandleComponent(t); err != nil {
+							core.Logger.Warnf("Error switching page: %v", err)
+						} else if !handled {
+							core.Logger.Warnf("Unknown customID: %s", t.MessageComponentData().CustomID)
+							return
+						}
+					case *discordgo.MessageReactionAdd:
+						// other reactions are left alone
+						if _, err := a.Pager.HandleReaction(t); err != nil {
+							core.Logger.Warnf("Error switching page: %v", err)
+						}
 					}
 				case <-time.After(a.Pager.Overtime):
 					//overtime termination sign
@@ -630,35 +1076,29 @@ func (a *archiveQueryStage) Init(pager *discord.Pager, plugin *ArchivePlugin) {
 	}()
 }
 
+// minArchivePageSize addressable for ApplicationCommandOption.MinValue.
+var minArchivePageSize float64 = 1
+
 const (
-	lsButtonIDPrev = "ls-archive-prev"
-	lsButtonIDNext = "ls-archive-next"
+	lsButtonIDPrev  = "ls-archive-prev"
+	lsButtonIDNext  = "ls-archive-next"
+	lsButtonIDFirst = "ls-archive-first"
+	lsButtonIDLast  = "ls-archive-last"
+	lsSelectIDPage  = "ls-archive-page"
+	// defaultArchivePageSize sites in a page of the list unless page-size is given.
+	defaultArchivePageSize = 7
+	// consoleArchiveListLimit sites printed by the console list, the oldest first.
+	consoleArchiveListLimit = 20
+	// archiveLinksCommand name of the message command, shown in the context menu of messages.
+	archiveLinksCommand = "Archive links"
+	// archiveSaveModalID custom id of the save form, the modify form adds the id of the site to archiveModifyModalPrefix.
+	archiveSaveModalID       = "archive-save-modal"
+	archiveModifyModalPrefix = "archive-modify-modal:"
 )
 
-type archivePoPagerLoader struct {
-	query          any
-	queryFunc      func(query any) ([]*archivePO, error)
-	resultsStorage []*archivePO
-	discord.DefaultPageRenderer
-}
-
-func (s *archivePoPagerLoader) LoadPager(pager *discord.Pager) error {
-	var err error
-	s.resultsStorage, err = s.queryFunc(s.query)
-	if err != nil {
-		return err
-	}
-	for _, v := range s.resultsStorage {
-		var tempVar discord.IPagerPart
-		tempVar = v
-		pager.CompleteItemSlice = append(pager.CompleteItemSlice, &tempVar)
-	}
-	return nil
-}
-
 func NewArchivePlugin(reg *core.ServiceRegistry) core.IPlugin {
 	var archivePlugin ArchivePlugin
-	if err := (&archivePlugin).Init(reg); err != nil && errors.As(err, &core.ErrServiceFetchUnknownService) {
+	if err := (&archivePlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
 		core.Logger.Panicf("Archive plugin MUST have all required service(s) injected!")
 		panic("Archive plugin initialization failed.")
 	}
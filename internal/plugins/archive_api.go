@@ -0,0 +1,355 @@
+package plugins
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"encoding/hex"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	archiveTokenCollection = "archive_tokens"
+	// archiveAPIPath the REST API is mounted at <base path>/api/archive of the web service.
+	archiveAPIPath = "/api/archive"
+	// archiveAPITokenKey the archiveTokenPO of the caller in the gin context.
+	archiveAPITokenKey    = "archiveToken"
+	defaultAPIPageSize    = 20
+	maxAPIPageSize        = 100
+	archiveAPITokenLength = 32
+)
+
+// archiveTokenPO An API token of a user, scoped to the guild it's issued in. Only its SHA-256 digest is stored.
+type archiveTokenPO struct {
+	BsonID      primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash   string             `bson:"token_hash"`
+	UserID      string             `bson:"user_id"`
+	GuildID     string             `bson:"guild_id"`
+	ChannelID   string             `bson:"channel_id"` // sites saved with the token are recorded in this channel
+	CreatedTime time.Time          `bson:"created_time"`
+}
+
+// archiveSiteRequest body of create and update calls. Fields left out are not changed by updates.
+type archiveSiteRequest struct {
+	Site  *string   `json:"site"`
+	Title *string   `json:"title"`
+	Tags  *[]string `json:"tags"`
+	Note  *string   `json:"note"`
+}
+
+// archiveSiteList a page of the sites of the caller.
+type archiveSiteList struct {
+	Sites    []*archivePO `json:"sites"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+	Total    int          `json:"total"`
+}
+
+func hashArchiveToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+// issueAPIToken Generate a new token for the user in the guild, revoking the previous one.
+func (p *ArchivePlugin) issueAPIToken(userID, guildID, channelID string) (string, error) {
+	raw := make([]byte, archiveAPITokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	_, err := p.DataService.Collection(archiveTokenCollection).UpdateOne(context.Background(),
+		data.Where(data.Eq("user_id", userID), data.Eq("guild_id", guildID)),
+		archiveTokenPO{
+			TokenHash:   hashArchiveToken(token),
+			UserID:      userID,
+			GuildID:     guildID,
+			ChannelID:   channelID,
+			CreatedTime: time.Now(),
+		}, true)
+	return token, err
+}
+
+func (p *ArchivePlugin) revokeAPIToken(userID, guildID string) (bool, error) {
+	deleted, err := p.DataService.Collection(archiveTokenCollection).DeleteOne(context.Background(),
+		data.Where(data.Eq("user_id", userID), data.Eq("guild_id", guildID)))
+	return deleted > 0, err
+}
+
+func (p *ArchivePlugin) handleAPIToken(i *discordgo.Interaction, optionsMap map[string]*discordgo.ApplicationCommandInteractionDataOption) error {
+	if i.Member == nil {
+		return p.DiscordService.InteractionRespondEphemeral(i, "API tokens are issued in a server, for the sites saved there.")
+	}
+	if revoke, ok := optionsMap["revoke"]; ok && revoke.BoolValue() {
+		revoked, err := p.revokeAPIToken(i.Member.User.ID, i.GuildID)
+		if err != nil {
+			p.DiscordService.InteractionRespondEphemeral(i, "Internal error revoking the token! Please contact admin for help.")
+			return err
+		}
+		if !revoked {
+			return p.DiscordService.InteractionRespondEphemeral(i, "You have no API token in this server.")
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i, "Your API token has been revoked.")
+	}
+	token, err := p.issueAPIToken(i.Member.User.ID, i.GuildID, i.ChannelID)
+	if err != nil {
+		p.DiscordService.InteractionRespondEphemeral(i, "Internal error issuing the token! Please contact admin for help.")
+		return err
+	}
+	return p.DiscordService.InteractionRespondEphemeral(i, "Your new API token, replacing any previous one. It won't be shown again:\n"+
+		"`"+token+"`\nSend it as `Authorization: Bearer <token>` to the archive API at `"+archiveAPIPath+"/sites`.")
+}
+
+// mountAPI register the REST API routes on the web service.
+func (p *ArchivePlugin) mountAPI() {
+	api := p.WebService.Group(archiveAPIPath, p.authenticateAPI)
+	api.GET("/sites", p.handleAPIListSites)
+	api.POST("/sites", p.handleAPICreateSite)
+	api.GET("/sites/:id", p.handleAPIGetSite)
+	api.PATCH("/sites/:id", p.handleAPIUpdateSite)
+	api.DELETE("/sites/:id", p.handleAPIDeleteSite)
+}
+
+func apiError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// authenticateAPI resolve the bearer token of the call to its archiveTokenPO.
+func (p *ArchivePlugin) authenticateAPI(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		apiError(c, http.StatusUnauthorized, "missing bearer token")
+		return
+	}
+	var tokenPo archiveTokenPO
+	err := p.DataService.Collection(archiveTokenCollection).FindOne(c.Request.Context(), &tokenPo,
+		data.Where(data.Eq("token_hash", hashArchiveToken(token))))
+	if errors.Is(err, data.ErrNotFound) {
+		apiError(c, http.StatusUnauthorized, "invalid token")
+		return
+	} else if err != nil {
+		core.Logger.Warnf("Error loading archive API token: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.Set(archiveAPITokenKey, &tokenPo)
+	c.Next()
+}
+
+func apiToken(c *gin.Context) *archiveTokenPO {
+	return c.MustGet(archiveAPITokenKey).(*archiveTokenPO)
+}
+
+// ownedSiteFilter the site of the path, if it belongs to the caller in the guild of the token.
+func ownedSiteFilter(c *gin.Context) (data.Filter, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, false
+	}
+	token := apiToken(c)
+	return data.Where(data.Eq("_id", id), data.Eq("user_id", token.UserID), data.Eq("guild_id", token.GuildID)), true
+}
+
+// findOwnedSite respond 404 and return nil when the site is not found.
+func (p *ArchivePlugin) findOwnedSite(c *gin.Context) *archivePO {
+	filter, ok := ownedSiteFilter(c)
+	if !ok {
+		apiError(c, http.StatusNotFound, "site not found")
+		return nil
+	}
+	results, err := p.findArchivePo(filter)
+	if err != nil {
+		core.Logger.Warnf("Error loading archive document: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return nil
+	}
+	if len(results) == 0 {
+		apiError(c, http.StatusNotFound, "site not found")
+		return nil
+	}
+	return results[0]
+}
+
+// apply copy the given fields into the site, validated as in handleSaveSite.
+func (r archiveSiteRequest) apply(aPo *archivePO) error {
+	if r.Site != nil {
+		aPo.Site = strings.TrimSpace(*r.Site)
+	}
+	if !validSiteURL(aPo.Site) {
+		return errors.New("site must be a valid url")
+	}
+	if r.Title != nil {
+		aPo.Title = strings.TrimSpace(*r.Title)
+	}
+	if r.Note != nil {
+		aPo.Note = strings.TrimSpace(*r.Note)
+	}
+	if r.Tags != nil {
+		aPo.Tags = []string{}
+		for _, tag := range *r.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				aPo.Tags = append(aPo.Tags, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// archiveSiteQuery sites of the user in the guild having every tag, and containing the text in their url,
+// title or note, case-insensitively.
+type archiveSiteQuery struct {
+	UserID  string
+	GuildID string
+	Tags    []string
+	Text    string
+}
+
+// searchArchiveSites a page of the sites matching the query, oldest first, and the count of all matching sites.
+func searchArchiveSites(ctx context.Context, collection data.Collection, q archiveSiteQuery, skip, limit int) ([]*archivePO, int, error) {
+	query := data.Where(data.Eq("user_id", q.UserID), data.Eq("guild_id", q.GuildID))
+	if len(q.Tags) > 0 {
+		query = query.And(data.All("tags", q.Tags))
+	}
+	sites := []*archivePO{}
+	// the stores have no text search, texts are matched here over the tag results
+	if text := strings.ToLower(strings.TrimSpace(q.Text)); text != "" {
+		var results []*archivePO
+		if err := collection.Find(ctx, &results, query, data.FindOptions{Sort: []data.SortField{{Field: "_id"}}}); err != nil {
+			return nil, 0, err
+		}
+		var matched []*archivePO
+		for _, aPo := range results {
+			if strings.Contains(strings.ToLower(aPo.Site+"\n"+aPo.Title+"\n"+aPo.Note), text) {
+				matched = append(matched, aPo)
+			}
+		}
+		if skip < len(matched) {
+			end := skip + limit
+			if end > len(matched) {
+				end = len(matched)
+			}
+			sites = matched[skip:end]
+		}
+		return sites, len(matched), nil
+	}
+	count, err := collection.Count(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = collection.Find(ctx, &sites, query, data.FindOptions{
+		Sort:  []data.SortField{{Field: "_id"}},
+		Skip:  int64(skip),
+		Limit: int64(limit),
+	})
+	return sites, int(count), err
+}
+
+// splitArchiveTags tags separated by commas, as in forms and urls.
+func splitArchiveTags(tags string) []string {
+	var results []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			results = append(results, tag)
+		}
+	}
+	return results
+}
+
+// handleAPIListSites GET /sites?tags=a,b&q=text&page=1&page_size=20, see archiveSiteQuery.
+func (p *ArchivePlugin) handleAPIListSites(c *gin.Context) {
+	token := apiToken(c)
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		apiError(c, http.StatusBadRequest, "page must be a positive integer")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultAPIPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxAPIPageSize {
+		apiError(c, http.StatusBadRequest, "page_size must be between 1 and "+strconv.Itoa(maxAPIPageSize))
+		return
+	}
+	sites, total, err := searchArchiveSites(c.Request.Context(), p.getCollection(), archiveSiteQuery{
+		UserID:  token.UserID,
+		GuildID: token.GuildID,
+		Tags:    splitArchiveTags(c.Query("tags")),
+		Text:    c.Query("q"),
+	}, (page-1)*pageSize, pageSize)
+	if err != nil {
+		core.Logger.Warnf("Error loading archive documents: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusOK, archiveSiteList{Sites: sites, Page: page, PageSize: pageSize, Total: total})
+}
+
+// handleAPICreateSite POST /sites, the site is saved and forwarded as with /archive site save.
+func (p *ArchivePlugin) handleAPICreateSite(c *gin.Context) {
+	var request archiveSiteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apiError(c, http.StatusBadRequest, "malformed body: "+err.Error())
+		return
+	}
+	token := apiToken(c)
+	aPo := archivePO{GuildID: token.GuildID, ChannelID: token.ChannelID, UserID: token.UserID}
+	if err := request.apply(&aPo); err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := p.saveSite(&aPo); err != nil {
+		core.Logger.Warnf("Error inserting archive document: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusCreated, aPo)
+}
+
+func (p *ArchivePlugin) handleAPIGetSite(c *gin.Context) {
+	if aPo := p.findOwnedSite(c); aPo != nil {
+		c.JSON(http.StatusOK, aPo)
+	}
+}
+
+// handleAPIUpdateSite PATCH /sites/:id, only the fields in the body are changed.
+func (p *ArchivePlugin) handleAPIUpdateSite(c *gin.Context) {
+	var request archiveSiteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apiError(c, http.StatusBadRequest, "malformed body: "+err.Error())
+		return
+	}
+	aPo := p.findOwnedSite(c)
+	if aPo == nil {
+		return
+	}
+	if err := request.apply(aPo); err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	aPo.setTime(false)
+	if err := p.updateArchivePoWithID(*aPo); err != nil {
+		core.Logger.Warnf("Error updating archive document: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusOK, aPo)
+}
+
+func (p *ArchivePlugin) handleAPIDeleteSite(c *gin.Context) {
+	aPo := p.findOwnedSite(c)
+	if aPo == nil {
+		return
+	}
+	if err := p.deleteArchivePoWithID(*aPo); err != nil {
+		core.Logger.Warnf("Error deleting archive document: %v", err)
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package plugins
+
+import (
+	"dalian-bot/internal/services/discord/discordtest"
+	"dalian-bot/internal/services/web"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newArchiveAPIHarness(t *testing.T) (*discordtest.Harness, *web.Service) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	gin.SetMode(gin.TestMode)
+	webService := &web.Service{}
+	if err := webService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatalf("web service init failed: %v", err)
+	}
+	h.RegisterPlugin(NewArchivePlugin)
+	return h, webService
+}
+
+var archiveTokenPattern = regexp.MustCompile("`([0-9a-f]{64})`")
+
+func issueArchiveToken(t *testing.T, h *discordtest.Harness) string {
+	t.Helper()
+	h.Interact(discordtest.SlashCommand("archive", discordtest.SubCommand("token")))
+	match := archiveTokenPattern.FindStringSubmatch(h.LastResponse().Content)
+	if match == nil {
+		t.Fatalf("no token in response: %q", h.LastResponse().Content)
+	}
+	return match[1]
+}
+
+func callArchiveAPI(s *web.Service, token, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	s.GinEngine.ServeHTTP(w, req)
+	return w
+}
+
+func TestArchiveAPI(t *testing.T) {
+	h, s := newArchiveAPIHarness(t)
+	if w := callArchiveAPI(s, "", http.MethodGet, "/api/archive/sites", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("call without token: status = %d", w.Code)
+	}
+	stale := issueArchiveToken(t, h)
+	token := issueArchiveToken(t, h)
+	if w := callArchiveAPI(s, stale, http.MethodGet, "/api/archive/sites", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replaced token still accepted: status = %d", w.Code)
+	}
+
+	if w := callArchiveAPI(s, token, http.MethodPost, "/api/archive/sites", `{"site":"not a url"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid url: status = %d", w.Code)
+	}
+	var created archivePO
+	for _, body := range []string{
+		`{"site":"https://example.com/go","title":"Go notes","tags":["go"," "]}`,
+		`{"site":"https://example.com/rust","tags":["rust"],"note":"borrow checker"}`,
+	} {
+		w := callArchiveAPI(s, token, http.MethodPost, "/api/archive/sites", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: status = %d, body %s", w.Code, w.Body)
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+	}
+	if created.UserID != discordtest.UserID || created.GuildID != discordtest.GuildID || created.Site != "https://example.com/rust" {
+		t.Fatalf("unexpected created site: %+v", created)
+	}
+
+	for target, want := range map[string]string{
+		"/api/archive/sites?tags=go":                      "https://example.com/go",
+		"/api/archive/sites?q=BORROW":                     "https://example.com/rust",
+		"/api/archive/sites?page=2&page_size=1":           "https://example.com/rust",
+		"/api/archive/sites?q=example&page_size=1":        "https://example.com/go",
+		"/api/archive/sites?tags=go,rust&q=anything":      "",
+		"/api/archive/sites?q=example&page=3&page_size=1": "",
+	} {
+		w := callArchiveAPI(s, token, http.MethodGet, target, "")
+		var list archiveSiteList
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("list %s: status = %d, body %s", target, w.Code, w.Body)
+		}
+		var got string
+		if len(list.Sites) > 0 {
+			got = list.Sites[0].Site
+		}
+		if got != want || len(list.Sites) > 1 {
+			t.Errorf("list %s: got %d sites starting with %q, want %q", target, len(list.Sites), got, want)
+		}
+	}
+
+	sitePath := "/api/archive/sites/" + created.BsonID.Hex()
+	if w := callArchiveAPI(s, token, http.MethodPatch, sitePath, `{"note":""}`); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "borrow") {
+		t.Fatalf("update: status = %d, body %s", w.Code, w.Body)
+	}
+	if w := callArchiveAPI(s, token, http.MethodGet, sitePath, ""); !strings.Contains(w.Body.String(), `"tags":["rust"]`) {
+		t.Fatalf("update changed fields left out: %s", w.Body)
+	}
+	if w := callArchiveAPI(s, token, http.MethodDelete, sitePath, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", w.Code)
+	}
+	if w := callArchiveAPI(s, token, http.MethodGet, sitePath, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted site: status = %d", w.Code)
+	}
+
+	h.Interact(discordtest.SlashCommand("archive", discordtest.SubCommand("token", discordtest.BoolOption("revoke", true))))
+	if w := callArchiveAPI(s, token, http.MethodGet, "/api/archive/sites", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token still accepted: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,292 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/discord/discordtest"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"testing"
+)
+
+func newArchiveHarness(t *testing.T) (*discordtest.Harness, *ArchivePlugin) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	plugin := h.RegisterPlugin(NewArchivePlugin).(*ArchivePlugin)
+	return h, plugin
+}
+
+func archiveSiteCommand(sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return discordtest.SlashCommand("archive", discordtest.SubCommandGroup("site", discordtest.SubCommand(sub, options...)))
+}
+
+func TestArchiveRegistersSlashCommand(t *testing.T) {
+	h, _ := newArchiveHarness(t)
+	names := map[string]discordgo.ApplicationCommandType{}
+	for _, cmd := range h.Session.Commands("") {
+		names[cmd.Name] = cmd.Type
+	}
+	if len(names) != 2 || names["archive"] != discordgo.ChatApplicationCommand || names[archiveLinksCommand] != discordgo.MessageApplicationCommand {
+		t.Fatalf("want archive and message commands registered, got %+v", names)
+	}
+}
+
+func TestArchiveLinksMessageCommand(t *testing.T) {
+	h, plugin := newArchiveHarness(t)
+	h.Session.AddChannel(&discordgo.Channel{ID: discordtest.ChannelID, Name: "reading"})
+	m := h.Say(discordtest.ChannelID, "see https://example.com/a, and (https://example.com/b) twice https://example.com/a")
+	m.Embeds = []*discordgo.MessageEmbed{{URL: "https://example.com/c", Description: "no link here"}}
+
+	h.Interact(discordtest.MessageCommand(archiveLinksCommand, m))
+	resp := h.LastResponse()
+	if resp.Message.Flags&discordgo.MessageFlagsEphemeral == 0 || len(resp.Embeds) != 1 || len(resp.Embeds[0].Fields) != 3 {
+		t.Fatalf("want an ephemeral embed listing 3 sites, got %+v", resp)
+	}
+	results, err := plugin.findArchivePo(data.Where(data.Eq("guild_id", discordtest.GuildID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jumpLink := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", discordtest.GuildID, discordtest.ChannelID, m.ID)
+	var sites []string
+	for _, r := range results {
+		if len(r.Tags) != 1 || r.Tags[0] != "reading" || r.Note != jumpLink {
+			t.Errorf("unexpected tags %v or note %q", r.Tags, r.Note)
+		}
+		sites = append(sites, r.Site)
+	}
+	if fmt.Sprint(sites) != "[https://example.com/a https://example.com/b https://example.com/c]" {
+		t.Errorf("unexpected sites %v", sites)
+	}
+
+	// slash handlers never see message commands
+	h.Session.Reset()
+	h.Interact(discordtest.MessageCommand("archive", m))
+	if n := len(h.Session.CallsOf("InteractionRespond")); n != 0 {
+		t.Errorf("want no response, got %d", n)
+	}
+}
+
+func TestArchiveSaveRejectsInvalidURL(t *testing.T) {
+	h, _ := newArchiveHarness(t)
+	h.Interact(archiveSiteCommand("save", discordtest.StringOption("url", "not a url")))
+	if got := h.LastResponse().Content; got != "You must provide a *valid* url!" {
+		t.Errorf("unexpected response: %q", got)
+	}
+}
+
+func TestArchiveModifyWithoutQuery(t *testing.T) {
+	h, _ := newArchiveHarness(t)
+	h.Interact(archiveSiteCommand("modify", discordtest.IntOption("relative-id", 1)))
+	if got := h.LastResponse().Content; !strings.HasPrefix(got, "No active query for you!") {
+		t.Errorf("unexpected response: %q", got)
+	}
+}
+
+func TestArchiveListPageSize(t *testing.T) {
+	h, plugin := newArchiveHarness(t)
+	for k := 1; k <= 5; k++ {
+		h.Interact(archiveSiteCommand("save", discordtest.StringOption("url", fmt.Sprintf("https://example.com/%d", k))))
+	}
+	h.Interact(archiveSiteCommand("list", discordtest.IntOption("page-size", 2)))
+	pagerMessage := h.LastResponse().Message
+	if fields := pagerMessage.Embeds[0].Fields; len(fields) != 2 || !strings.HasPrefix(fields[0].Value, "https://example.com/1") {
+		t.Fatalf("want the 2 oldest sites, got %+v", fields)
+	}
+	waitFor(t, "pager stage", func() bool {
+		_, ok := plugin.StageUtil.GetStage(plugin.getPagerKey(pagerMessage.ID))
+		return ok
+	})
+
+	h.Session.Reset()
+	h.Interact(discordtest.ButtonClick(pagerMessage, lsButtonIDLast))
+	waitFor(t, "last page", func() bool { return len(h.Session.CallsOf("InteractionRespond")) == 1 })
+	embed := h.LastResponse().Embeds[0]
+	if embed.Footer.Text != "page: 3/3" || len(embed.Fields) != 1 || embed.Fields[0].Name != "5. Temporary Title" {
+		t.Errorf("unexpected last page: %s %+v", embed.Footer.Text, embed.Fields)
+	}
+}
+
+func TestArchiveSaveListModifyRemove(t *testing.T) {
+	h, plugin := newArchiveHarness(t)
+	for k := 1; k <= 8; k++ {
+		h.Interact(archiveSiteCommand("save",
+			discordtest.StringOption("url", fmt.Sprintf("https://example.com/%d", k)),
+			discordtest.StringOption("tags", "go$bot")))
+		if resp := h.LastResponse(); len(resp.Embeds) != 1 || resp.Embeds[0].Title != "Site saved" {
+			t.Fatalf("unexpected save response: %+v", resp)
+		}
+	}
+
+	// list: first page of two, paginated with buttons and a page select menu
+	h.Interact(archiveSiteCommand("list", discordtest.StringOption("tags", "bot")))
+	listResp := h.LastResponse()
+	if len(listResp.Embeds) != 1 || len(listResp.Embeds[0].Fields) != 7 || len(listResp.Components) != 2 {
+		t.Fatalf("unexpected list response: %+v", listResp)
+	}
+	pagerMessage := listResp.Message
+	waitFor(t, "pager stage", func() bool {
+		_, ok := plugin.StageUtil.GetStage(plugin.getPagerKey(pagerMessage.ID))
+		return ok
+	})
+
+	// next page
+	h.Session.Reset()
+	h.Interact(discordtest.ButtonClick(pagerMessage, lsButtonIDNext))
+	waitFor(t, "page switch", func() bool { return len(h.Session.CallsOf("InteractionRespond")) == 1 })
+	if footer := h.LastResponse().Embeds[0].Footer.Text; footer != "page: 2/2" {
+		t.Errorf("unexpected footer after switching page: %s", footer)
+	}
+
+	// back to the first page through the select menu
+	h.Session.Reset()
+	h.Interact(discordtest.SelectMenuChoice(pagerMessage, lsSelectIDPage, "1"))
+	waitFor(t, "page select", func() bool { return len(h.Session.CallsOf("InteractionRespond")) == 1 })
+	if footer := h.LastResponse().Embeds[0].Footer.Text; footer != "page: 1/2" {
+		t.Errorf("unexpected footer after selecting page: %s", footer)
+	}
+
+	// modify with relative id, on a page not displayed anymore
+	h.Interact(archiveSiteCommand("modify", discordtest.IntOption("relative-id", 8), discordtest.StringOption("note", "the last one")))
+	modifyResp := h.LastResponse()
+	if len(modifyResp.Embeds) != 1 || !strings.Contains(modifyResp.Embeds[0].Fields[0].Value, "the last one") {
+		t.Fatalf("unexpected modify response: %+v", modifyResp)
+	}
+
+	// remove with relative id
+	h.Interact(archiveSiteCommand("remove", discordtest.IntOption("relative-id", 1)))
+	if resp := h.LastResponse(); len(resp.Embeds) != 1 || resp.Embeds[0].Title != "Site record deleted" {
+		t.Fatalf("unexpected remove response: %+v", resp)
+	}
+	sites, err := plugin.findArchivePo(data.Where(data.Eq("user_id", discordtest.UserID)))
+	if err != nil || len(sites) != 7 {
+		t.Errorf("want 7 sites left, got %d (%v)", len(sites), err)
+	}
+}
+
+func TestArchiveTextListPagedWithReactions(t *testing.T) {
+	h, plugin := newArchiveHarness(t)
+	for k := 1; k <= 8; k++ {
+		h.Interact(archiveSiteCommand("save", discordtest.StringOption("url", fmt.Sprintf("https://example.com/%d", k))))
+	}
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$archive list")
+	sends := h.Session.CallsOf("ChannelMessageSend")
+	if len(sends) != 1 || len(sends[0].Embeds) != 1 || len(sends[0].Components) != 0 {
+		t.Fatalf("want the first page sent without components, got %+v", sends)
+	}
+	pagerMessage := sends[0].Message
+	if reactions := h.Session.CallsOf("MessageReactionAdd"); len(reactions) != 4 || reactions[2].Emoji != discord.EmojiRightArrow {
+		t.Fatalf("want navigation reactions added, got %+v", reactions)
+	}
+	waitFor(t, "pager stage", func() bool {
+		_, ok := plugin.StageUtil.GetStage(plugin.getPagerKey(pagerMessage.ID))
+		return ok
+	})
+
+	// only the owner can switch pages, reactions of others are removed
+	h.React(pagerMessage, discord.EmojiRightArrow, "someone-else")
+	waitFor(t, "reaction removal", func() bool { return len(h.Session.CallsOf("MessageReactionRemove")) == 1 })
+	if edits := h.Session.CallsOf("ChannelMessageEdit"); len(edits) != 0 {
+		t.Fatalf("want no page switch, got %+v", edits)
+	}
+	h.React(pagerMessage, discord.EmojiRightArrow, discordtest.UserID)
+	waitFor(t, "page switch", func() bool { return len(h.Session.CallsOf("ChannelMessageEdit")) == 1 })
+	if footer := h.Session.CallsOf("ChannelMessageEdit")[0].Embeds[0].Footer.Text; footer != "page: 2/2" {
+		t.Errorf("unexpected footer after switching page: %s", footer)
+	}
+
+	// relative ids work with text pagers too
+	h.Interact(archiveSiteCommand("remove", discordtest.IntOption("relative-id", 8)))
+	if resp := h.LastResponse(); len(resp.Embeds) != 1 || resp.Embeds[0].Title != "Site record deleted" {
+		t.Fatalf("unexpected remove response: %+v", resp)
+	}
+}
+
+func TestArchiveSaveAndModifyWithForms(t *testing.T) {
+	h, plugin := newArchiveHarness(t)
+	h.Interact(archiveSiteCommand("save"))
+	form := h.LastResponse()
+	if form.Response.Type != discordgo.InteractionResponseModal || form.Response.Data.CustomID != archiveSaveModalID || len(form.Components) != 4 {
+		t.Fatalf("want the save form, got %+v", form.Response)
+	}
+	h.Interact(discordtest.ModalSubmit(archiveSaveModalID, map[string]string{
+		"url": "https://example.com/form", "title": "Form", "tags": "go, bot,", "note": "first line\nsecond line",
+	}))
+	if resp := h.LastResponse(); len(resp.Embeds) != 1 || resp.Embeds[0].Fields[0].Name != "Form" {
+		t.Fatalf("unexpected save response: %+v", resp)
+	}
+
+	h.Interact(archiveSiteCommand("list"))
+	pagerMessage := h.LastResponse().Message
+	waitFor(t, "pager stage", func() bool {
+		_, ok := plugin.StageUtil.GetStage(plugin.getPagerKey(pagerMessage.ID))
+		return ok
+	})
+	// the modify form is pre-filled with the site
+	h.Interact(archiveSiteCommand("modify", discordtest.IntOption("relative-id", 1)))
+	form = h.LastResponse()
+	if form.Response.Type != discordgo.InteractionResponseModal || !strings.HasPrefix(form.Response.Data.CustomID, archiveModifyModalPrefix) {
+		t.Fatalf("want the modify form, got %+v", form.Response)
+	}
+	tags := form.Components[2].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+	if tags.Value != "go, bot" {
+		t.Errorf("want the tags pre-filled, got %q", tags.Value)
+	}
+	h.Interact(discordtest.ModalSubmit(form.Response.Data.CustomID, map[string]string{
+		"url": "https://example.com/form", "title": "Renamed", "tags": "", "note": "",
+	}))
+	if resp := h.LastResponse(); len(resp.Embeds) != 1 || resp.Embeds[0].Title != "Site record updated" {
+		t.Fatalf("unexpected modify response: %+v", resp)
+	}
+	sites, err := plugin.findArchivePo(data.Where(data.Eq("user_id", discordtest.UserID)))
+	if err != nil || len(sites) != 1 || sites[0].Title != "Renamed" || len(sites[0].Tags) != 0 || sites[0].Note != "" {
+		t.Errorf("want the site updated, got %+v (%v)", sites, err)
+	}
+}
+
+func TestArchiveTagsAutocomplete(t *testing.T) {
+	h, _ := newArchiveHarness(t)
+	h.Interact(archiveSiteCommand("save", discordtest.StringOption("url", "https://example.com/1"), discordtest.StringOption("tags", "go$bot")))
+	h.Interact(archiveSiteCommand("save", discordtest.StringOption("url", "https://example.com/2"), discordtest.StringOption("tags", "golang$discord")))
+
+	h.Interact(discordtest.Autocomplete("archive", discordtest.SubCommandGroup("site",
+		discordtest.SubCommand("list", discordtest.Focus(discordtest.StringOption("tags", "bot$go"))))))
+	var values []string
+	for _, choice := range h.LastResponse().Response.Data.Choices {
+		values = append(values, choice.Value.(string))
+	}
+	// tags already typed are not suggested again
+	if fmt.Sprint(values) != "[bot$go bot$golang]" {
+		t.Errorf("unexpected tags %v", values)
+	}
+}
+
+func TestArchiveSnapshotURLMigration(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	dataService := registerTestDataService(t, h)
+	ctx := context.Background()
+	for _, doc := range []bson.M{
+		{"site": "https://example.com/empty", "snapshot_url": ""},
+		{"site": "https://example.com/null", "snapshot_url": nil},
+		{"site": "https://example.com/kept", "snapshot_url": "https://archive.example/kept"},
+	} {
+		if _, err := dataService.Collection(archiveCollection).InsertOne(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.RegisterPlugin(NewArchivePlugin)
+	if err := dataService.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	var remaining []archivePO
+	if err := dataService.Collection(archiveCollection).Find(ctx, &remaining, data.Where(data.Exists("snapshot_url", true))); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].SnapshotURL != "https://archive.example/kept" {
+		t.Errorf("sites with snapshot_url after migration: %+v", remaining)
+	}
+}
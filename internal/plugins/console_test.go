@@ -0,0 +1,141 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
+	"dalian-bot/internal/services/discord/discordtest"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// consoleScript drives plugins through a console service, one line at a time.
+type consoleScript struct {
+	t      *testing.T
+	input  *io.PipeWriter
+	output *bufio.Scanner
+}
+
+func newConsoleScript(t *testing.T, pluginFactories ...func(reg *core.ServiceRegistry) core.IPlugin) *consoleScript {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	bot := core.NewBot()
+	consoleService := console.Service{ServiceConfig: console.ServiceConfig{Input: inR, Output: outW}}
+	if err := consoleService.Init(bot.ServiceRegistry); err != nil {
+		t.Fatalf("console init failed: %v", err)
+	}
+	bot.ServiceRegistry.StartAll()
+	for _, f := range pluginFactories {
+		if err := bot.QuickRegisterPlugin(f); err != nil {
+			t.Fatalf("plugin registration failed: %v", err)
+		}
+	}
+	bot.Run()
+	t.Cleanup(func() {
+		inW.Close()
+		outW.Close()
+		bot.GracefulShutDown()
+	})
+	return &consoleScript{t: t, input: inW, output: bufio.NewScanner(outR)}
+}
+
+func (cs *consoleScript) send(line string) {
+	cs.t.Helper()
+	if _, err := io.WriteString(cs.input, line+"\n"); err != nil {
+		cs.t.Fatalf("write %q: %v", line, err)
+	}
+}
+
+func (cs *consoleScript) expect(contains string) {
+	cs.t.Helper()
+	lineChan := make(chan string, 1)
+	go func() {
+		if cs.output.Scan() {
+			lineChan <- cs.output.Text()
+		}
+	}()
+	select {
+	case line := <-lineChan:
+		if !strings.Contains(line, contains) {
+			cs.t.Errorf("got %q, want it to contain %q", line, contains)
+		}
+	case <-time.After(2 * time.Second):
+		cs.t.Fatalf("timed out waiting for %q", contains)
+	}
+}
+
+func TestConsolePing(t *testing.T) {
+	script := newConsoleScript(t, NewPingPlugin)
+	script.send("$ping")
+	script.expect("Pong!")
+}
+
+func TestConsoleWhat(t *testing.T) {
+	script := newConsoleScript(t, NewWhatPlugin)
+	script.send("hello there")
+	script.send("what")
+	script.expect("**hello there**")
+}
+
+func TestConsoleHelp(t *testing.T) {
+	script := newConsoleScript(t, NewHelpPlugin, NewPingPlugin)
+	script.send("$help ping")
+	script.expect("**ping**")
+}
+
+func TestConsoleArchiveAndDDTV(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	dataService := registerTestDataService(t, h)
+	output := &bytes.Buffer{}
+	consoleService := &console.Service{ServiceConfig: console.ServiceConfig{Input: strings.NewReader(""), Output: output}}
+	if err := consoleService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatalf("console init failed: %v", err)
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	consoleService.Start(wg)
+	t.Cleanup(func() {
+		wg.Add(1)
+		consoleService.Stop(wg)
+	})
+	h.RegisterPlugin(NewArchivePlugin)
+	h.RegisterPlugin(NewDDTVPlugin)
+
+	ctx := context.Background()
+	dataService.Collection(archiveCollection).InsertOne(ctx, archivePO{Site: "https://example.com/a", Tags: []string{"go"}, UserID: "1", GuildID: "g1"})
+	dataService.Collection(archiveCollection).InsertOne(ctx, archivePO{Site: "https://example.com/b", UserID: "2", GuildID: "g2"})
+	dataService.Collection(ddtvNotifyCollection).InsertOne(ctx, ddtvNotifyPo{Platform: platformTelegram, NotifyChannelID: "-100", FeaturedUIDs: []int64{42}})
+
+	run := func(line string) string {
+		output.Reset()
+		h.Trigger(core.Trigger{
+			Type:  console.TriggerTypeConsole,
+			Event: console.Event{EventType: console.EventTypeMessage, Message: &console.Message{ChannelID: console.ChannelStdin, Content: line}},
+		})
+		return output.String()
+	}
+	if got := run("$archive list"); !strings.Contains(got, "2 site(s) found.") || !strings.Contains(got, "https://example.com/b") {
+		t.Errorf("archive list = %q", got)
+	}
+	if got := run("$archive list go"); !strings.Contains(got, "1 site(s) found.") || strings.Contains(got, "https://example.com/b") {
+		t.Errorf("archive list go = %q", got)
+	}
+	if got := run("$ddtv status"); !strings.Contains(got, "telegram -100: featured streamers [42]") {
+		t.Errorf("ddtv status = %q", got)
+	}
+	if got := run("$ddtv remove telegram -100"); !strings.Contains(got, "webhook channel removed!") {
+		t.Errorf("ddtv remove = %q", got)
+	}
+	if got := run("$ddtv status"); !strings.Contains(got, "0 notify channel(s).") {
+		t.Errorf("ddtv status after remove = %q", got)
+	}
+}
@@ -0,0 +1,477 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomCmdPlugin Commands defined by guild managers at runtime, answering with a templated text.
+// Discord: Managed with command group `customcmd`. Custom commands are called by `$name args`,
+// and/or by the guild-scoped slash command `/name args`.
+type CustomCmdPlugin struct {
+	core.Plugin
+	DiscordService *discord.Service
+	DataService    *data.Service
+	discord.SlashCommandUtil
+	discord.IDiscordHelper
+}
+
+const (
+	customCmdKindText  = "text"
+	customCmdKindSlash = "slash"
+	customCmdKindBoth  = "both"
+	// customCmdResponseLimit discord message limit.
+	customCmdResponseLimit = 2000
+)
+
+// customCmdNamePattern slash command names allowed by discord, also used for text commands.
+var customCmdNamePattern = regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+func (p *CustomCmdPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCreate) error {
+	if isMatched, cmdName := p.DefaultMatchCommand(i); !isMatched || cmdName != "customcmd" {
+		return p.doCustomInteraction(i.Interaction)
+	}
+	if i.GuildID == "" {
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Custom commands belong to a guild, run it in a guild channel.")
+	}
+	cmdOption := i.ApplicationCommandData().Options[0]
+	optionsMap := p.ParseOptionsMap(cmdOption.Options)
+	switch cmdOption.Name {
+	case "add":
+		po := customCmdPo{
+			GuildID:   i.GuildID,
+			Name:      strings.ToLower(optionsMap["name"].StringValue()),
+			Response:  optionsMap["response"].StringValue(),
+			Kind:      customCmdKindBoth,
+			CreatedBy: i.Member.User.ID,
+		}
+		if kind, ok := optionsMap["kind"]; ok {
+			po.Kind = kind.StringValue()
+		}
+		if description, ok := optionsMap["description"]; ok {
+			po.Description = description.StringValue()
+		}
+		if problem := p.validateCustomCmd(po); problem != "" {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, problem)
+		}
+		if _, err := p.findCustomCmd(i.GuildID, po.Name); err == nil {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("`%s` already exists! Use */customcmd edit* instead?", po.Name))
+		} else if !errors.Is(err, data.ErrNotFound) {
+			return err
+		}
+		po.setTime(true)
+		id, err := p.getCollection().InsertOne(context.Background(), po)
+		if err != nil {
+			return err
+		}
+		// without its slash command, the record would answer text only: roll both back
+		if err := p.installCustomCmd(po); err != nil {
+			if po.hasSlash() {
+				if disposeErr := p.DiscordService.DisposeGuildCommand(po.GuildID, po.Name); disposeErr != nil {
+					core.Logger.Warnf("Error rolling back guild command %s: %v", po.Name, disposeErr)
+				}
+			}
+			if _, deleteErr := p.getCollection().DeleteOne(context.Background(), data.ByID(id)); deleteErr != nil {
+				core.Logger.Warnf("Error rolling back custom command %s: %v", po.Name, deleteErr)
+			}
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Custom command `%s` added!\r%s", po.Name, po.usage(p.DiscordService.MessengerConfig(i.GuildID).Prefix)))
+	case "edit":
+		po, err := p.findCustomCmd(i.GuildID, strings.ToLower(optionsMap["name"].StringValue()))
+		if errors.Is(err, data.ErrNotFound) {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No such custom command. Check */customcmd list*?")
+		} else if err != nil {
+			return err
+		}
+		previousKind := po.Kind
+		if response, ok := optionsMap["response"]; ok {
+			po.Response = response.StringValue()
+		}
+		if kind, ok := optionsMap["kind"]; ok {
+			po.Kind = kind.StringValue()
+		}
+		if description, ok := optionsMap["description"]; ok {
+			po.Description = description.StringValue()
+		}
+		if problem := p.validateCustomCmd(po); problem != "" {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, problem)
+		}
+		po.setTime(false)
+		if po.hasSlash() {
+			if err := p.installCustomCmd(po); err != nil {
+				return err
+			}
+		} else if previousKind != customCmdKindText {
+			if err := p.DiscordService.DisposeGuildCommand(i.GuildID, po.Name); err != nil {
+				return err
+			}
+		}
+		if _, err := p.getCollection().UpdateOne(context.Background(), data.ByID(po.BsonID), po, false); err != nil {
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Custom command `%s` updated!\r%s", po.Name, po.usage(p.DiscordService.MessengerConfig(i.GuildID).Prefix)))
+	case "list":
+		var commands []customCmdPo
+		if err := p.getCollection().Find(context.Background(), &commands, data.Where(data.Eq("guild_id", i.GuildID)),
+			data.FindOptions{Sort: []data.SortField{{Field: "name"}}}); err != nil {
+			return err
+		}
+		if len(commands) == 0 {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No custom command yet! Add one with */customcmd add*?")
+		}
+		var lines []string
+		for _, po := range commands {
+			lines = append(lines, fmt.Sprintf("`%s` (%s): %s", po.Name, po.Kind, truncate(po.Response, 80)))
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, strings.Join(lines, "\r"))
+	case "remove":
+		name := strings.ToLower(optionsMap["name"].StringValue())
+		po, err := p.findCustomCmd(i.GuildID, name)
+		if errors.Is(err, data.ErrNotFound) {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No such custom command. Check */customcmd list*?")
+		} else if err != nil {
+			return err
+		}
+		if po.hasSlash() {
+			if err := p.DiscordService.DisposeGuildCommand(i.GuildID, po.Name); err != nil {
+				return err
+			}
+		}
+		if _, err := p.getCollection().DeleteOne(context.Background(), data.ByID(po.BsonID)); err != nil {
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Custom command `%s` removed!", name))
+	}
+	return nil
+}
+
+// doCustomInteraction answer a guild-scoped custom slash command, if the interaction is one.
+func (p *CustomCmdPlugin) doCustomInteraction(i *discordgo.Interaction) error {
+	if i.GuildID == "" {
+		return nil
+	}
+	commandData := i.ApplicationCommandData()
+	po, err := p.findCustomCmd(i.GuildID, commandData.Name)
+	if errors.Is(err, data.ErrNotFound) || (err == nil && !po.hasSlash()) {
+		// a command of another plugin
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var args string
+	if argsOption, ok := p.ParseOptionsMap(commandData.Options)["args"]; ok {
+		args = argsOption.StringValue()
+	}
+	return p.DiscordService.InteractionRespondComplex(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         po.render(i.Member.User, i.ChannelID, args),
+			AllowedMentions: customCmdAllowedMentions(i.Member.User),
+		},
+	})
+}
+
+func (p *CustomCmdPlugin) DoPlainMessage(m *discordgo.MessageCreate) error {
+	prefix := p.DiscordService.MessengerConfig(m.GuildID).Prefix
+	if m.GuildID == "" || !strings.HasPrefix(m.Content, prefix) {
+		return nil
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(m.Content, prefix), " ")
+	if !customCmdNamePattern.MatchString(name) {
+		return nil
+	}
+	po, err := p.findCustomCmd(m.GuildID, name)
+	if errors.Is(err, data.ErrNotFound) || (err == nil && !po.hasText()) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, err = p.DiscordService.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:         po.render(m.Author, m.ChannelID, strings.TrimSpace(args)),
+		AllowedMentions: customCmdAllowedMentions(m.Author),
+	})
+	return err
+}
+
+// validateCustomCmd Return what's wrong with po for the user, or an empty string.
+func (p *CustomCmdPlugin) validateCustomCmd(po customCmdPo) string {
+	if !customCmdNamePattern.MatchString(po.Name) {
+		return "Names are 1 to 32 lowercase letters, digits, `-` or `_`."
+	}
+	if po.Name == "customcmd" || p.DiscordService.IsPluginCommand(po.Name) {
+		return fmt.Sprintf("`%s` is a built-in command, pick another name.", po.Name)
+	}
+	if po.Kind != customCmdKindText && po.Kind != customCmdKindSlash && po.Kind != customCmdKindBoth {
+		return fmt.Sprintf("Kind must be one of %s, %s or %s.", customCmdKindText, customCmdKindSlash, customCmdKindBoth)
+	}
+	if len([]rune(po.Description)) > 100 {
+		return "Descriptions are at most 100 characters."
+	}
+	if po.Response == "" || len([]rune(po.Response)) > customCmdResponseLimit {
+		return fmt.Sprintf("Responses are 1 to %d characters.", customCmdResponseLimit)
+	}
+	return ""
+}
+
+// installCustomCmd create or overwrite the guild slash command of po, if it has one.
+func (p *CustomCmdPlugin) installCustomCmd(po customCmdPo) error {
+	if !po.hasSlash() {
+		return nil
+	}
+	return p.DiscordService.RegisterGuildCommand(po.GuildID, po.applicationCommand())
+}
+
+func truncate(s string, limit int) string {
+	if runes := []rune(s); len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return s
+}
+
+func (p *CustomCmdPlugin) Init(reg *core.ServiceRegistry) error {
+	// DiscordService is a MUST have. return error if not found.
+	if err := reg.FetchService(&p.DiscordService); err != nil {
+		return err
+	}
+	// DataService is also a MUST have. return error if not found.
+	if err := reg.FetchService(&p.DataService); err != nil {
+		return err
+	}
+	if err := p.DataService.RegisterSchema(customCmdSchema); err != nil {
+		return err
+	}
+	p.Plugin = core.Plugin{
+		Name:                 "customcmd",
+		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord},
+	}
+
+	manageGuild := int64(discordgo.PermissionManageServer)
+	dmPermission := false
+	nameOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "name",
+		Description: "Name of the custom command.",
+		Required:    true,
+	}
+	kindOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "kind",
+		Description: "Call it as a text command, a slash command or both. Both by default.",
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: customCmdKindText, Value: customCmdKindText},
+			{Name: customCmdKindSlash, Value: customCmdKindSlash},
+			{Name: customCmdKindBoth, Value: customCmdKindBoth},
+		},
+	}
+	descriptionOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "description",
+		Description: "Shown in the slash command list.",
+	}
+	responseOption := func(required bool) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "response",
+			Description: "Reply text. {user}, {args} and {channel} are replaced when called.",
+			Required:    required,
+		}
+	}
+	p.SlashCommandUtil = discord.SlashCommandUtil{AppCommandsMap: map[string]*discordgo.ApplicationCommand{}}
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name:                     "customcmd",
+		Description:              "Manage the custom commands of this guild",
+		DefaultMemberPermissions: &manageGuild,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "add",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Create a custom command.",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption, responseOption(true), kindOption, descriptionOption},
+			},
+			{
+				Name:        "edit",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Change a custom command.",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption, responseOption(false), kindOption, descriptionOption},
+			},
+			{
+				Name:        "list",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "List the custom commands of this guild.",
+			},
+			{
+				Name:        "remove",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Delete a custom command.",
+				Options:     []*discordgo.ApplicationCommandOption{nameOption},
+			},
+		},
+	})
+
+	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
+		PluginHelp: "Commands defined by guild managers, replying with a templated text.",
+		CommandHelps: []discord.CommandHelp{
+			{
+				Name: "customcmd add",
+				FormattedHelp: `*customcmd add*: /customcmd add name response [kind] [description]
+Create a command called by ` + "`" + p.DiscordService.DiscordAccountConfig.Prefix + `name args` + "`" + ` and/or ` + "`/name args`" + `.
+In the response, {user} mentions the caller, {args} is what follows the command and {channel} links the channel.`,
+			},
+		},
+	})
+	if err := p.DiscordService.RegisterSlashCommand(p); err != nil {
+		return err
+	}
+	return p.installAllCustomCmds()
+}
+
+// installAllCustomCmds register the slash commands stored for every guild.
+func (p *CustomCmdPlugin) installAllCustomCmds() error {
+	var commands []customCmdPo
+	if err := p.getCollection().Find(context.Background(), &commands, nil); err != nil {
+		return err
+	}
+	for _, po := range commands {
+		if err := p.installCustomCmd(po); err != nil {
+			core.Logger.Warnf("Error installing custom command %s of guild %s: %v", po.Name, po.GuildID, err)
+		}
+	}
+	return nil
+}
+
+func (p *CustomCmdPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	discordEvent := discord.UnboxEvent(trigger)
+	var err error
+	switch discordEvent.EventType {
+	case discord.EventTypeMessageCreate:
+		if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+			return
+		}
+		err = p.DoPlainMessage(discordEvent.MessageCreate)
+	case discord.EventTypeInteractionCreate:
+		if discordEvent.InteractionCreate.Type != discordgo.InteractionApplicationCommand {
+			return
+		}
+		err = p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate)
+	}
+	if err != nil {
+		core.Logger.Warnf("Error executing custom command: %v", err)
+	}
+}
+
+type customCmdPo struct {
+	BsonID           primitive.ObjectID `bson:"_id,omitempty"`
+	GuildID          string             `bson:"guild_id"`
+	Name             string             `bson:"name"`
+	Description      string             `bson:"description"`
+	Response         string             `bson:"response"`
+	Kind             string             `bson:"kind"`
+	CreatedBy        string             `bson:"created_by"`
+	CreatedTime      time.Time          `bson:"created_time"`
+	LastModifiedTime time.Time          `bson:"last_modified_time"`
+}
+
+func (po *customCmdPo) setTime(isCreate bool) {
+	currentTime := time.Now()
+	if isCreate {
+		po.CreatedTime = currentTime
+	}
+	po.LastModifiedTime = currentTime
+}
+
+func (po customCmdPo) hasText() bool {
+	return po.Kind != customCmdKindSlash
+}
+
+func (po customCmdPo) hasSlash() bool {
+	return po.Kind != customCmdKindText
+}
+
+func (po customCmdPo) usage(prefix string) string {
+	var usages []string
+	if po.hasText() {
+		usages = append(usages, fmt.Sprintf("`%s%s [args]`", prefix, po.Name))
+	}
+	if po.hasSlash() {
+		usages = append(usages, fmt.Sprintf("`/%s [args]`", po.Name))
+	}
+	return "Call it with " + strings.Join(usages, " or ")
+}
+
+func (po customCmdPo) applicationCommand() *discordgo.ApplicationCommand {
+	description := po.Description
+	if description == "" {
+		description = truncate(po.Response, 100)
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        po.Name,
+		Description: description,
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "args",
+			Description: "Replaces {args} in the response.",
+		}},
+	}
+}
+
+// render fill the placeholders of the response, cut to a single message.
+// Send it with customCmdAllowedMentions, since args and responses can hold any mention.
+func (po customCmdPo) render(user *discordgo.User, channelID, args string) string {
+	return truncate(strings.NewReplacer(
+		"{user}", user.Mention(),
+		"{args}", args,
+		"{channel}", fmt.Sprintf("<#%s>", channelID),
+	).Replace(po.Response), discord.MessageLengthLimit)
+}
+
+// customCmdAllowedMentions only the caller is pinged by a response, never roles, @everyone or other users.
+func customCmdAllowedMentions(user *discordgo.User) *discordgo.MessageAllowedMentions {
+	return &discordgo.MessageAllowedMentions{Users: []string{user.ID}}
+}
+
+const customCmdCollection = "custom_commands"
+
+// customCmdSchema commands are looked up by guild and name on every prefixed message.
+var customCmdSchema = data.Schema{
+	Namespace: "customcmd",
+	Indexes: []data.Index{{
+		Collection: customCmdCollection,
+		Name:       "guild_name",
+		Keys:       []data.SortField{{Field: "guild_id"}, {Field: "name"}},
+		Unique:     true,
+	}},
+}
+
+func (p *CustomCmdPlugin) getCollection() data.Collection {
+	return p.DataService.Collection(customCmdCollection)
+}
+
+func (p *CustomCmdPlugin) findCustomCmd(guildID, name string) (customCmdPo, error) {
+	var po customCmdPo
+	err := p.getCollection().FindOne(context.Background(), &po, data.Where(data.Eq("guild_id", guildID), data.Eq("name", name)))
+	return po, err
+}
+
+func NewCustomCmdPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var customCmdPlugin CustomCmdPlugin
+	if err := (&customCmdPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("CustomCmd plugin MUST have all required service(s) injected!")
+		panic("CustomCmd plugin initialization failed.")
+	}
+	return &customCmdPlugin
+}
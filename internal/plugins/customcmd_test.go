@@ -0,0 +1,107 @@
+package plugins
+
+import (
+	"dalian-bot/internal/services/discord/discordtest"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func customCmdCommand(sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return discordtest.SlashCommand("customcmd", discordtest.SubCommand(sub, options...))
+}
+
+func TestCustomCmdLifecycle(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	h.RegisterPlugin(NewPingPlugin)
+	h.RegisterPlugin(NewCustomCmdPlugin)
+
+	h.Interact(customCmdCommand("add", discordtest.StringOption("name", "ping"), discordtest.StringOption("response", "pong")))
+	if got := h.LastResponse().Content; !strings.Contains(got, "built-in") {
+		t.Fatalf("want built-in names rejected, got %q", got)
+	}
+	h.Interact(customCmdCommand("add", discordtest.StringOption("name", "FAQ"),
+		discordtest.StringOption("response", "Hi {user}, see {args} in {channel}")))
+	if got := h.LastResponse().Content; !strings.HasPrefix(got, "Custom command `faq` added!") {
+		t.Fatalf("unexpected response: %q", got)
+	}
+	if commands := h.Session.Commands(discordtest.GuildID); len(commands) != 1 || commands[0].Name != "faq" {
+		t.Fatalf("want faq registered in the guild, got %+v", commands)
+	}
+
+	// text command
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$faq the rules")
+	want := "Hi <@" + discordtest.UserID + ">, see the rules in <#" + discordtest.ChannelID + ">"
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 || sends[0].Content != want {
+		t.Fatalf("want %q sent, got %+v", want, sends)
+	}
+	// slash command
+	h.Interact(discordtest.SlashCommand("faq", discordtest.StringOption("args", "the pins")))
+	if got := h.LastResponse().Content; got != strings.Replace(want, "the rules", "the pins", 1) {
+		t.Fatalf("unexpected slash response: %q", got)
+	}
+
+	// text only: the guild command is gone, the text command still answers
+	h.Interact(customCmdCommand("edit", discordtest.StringOption("name", "faq"), discordtest.StringOption("kind", customCmdKindText),
+		discordtest.StringOption("response", "Read the FAQ")))
+	if commands := h.Session.Commands(discordtest.GuildID); len(commands) != 0 {
+		t.Fatalf("want the guild command disposed, got %+v", commands)
+	}
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$faq")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 || sends[0].Content != "Read the FAQ" {
+		t.Fatalf("unexpected sends after edit: %+v", sends)
+	}
+
+	h.Interact(customCmdCommand("list"))
+	if got := h.LastResponse().Content; got != "`faq` (text): Read the FAQ" {
+		t.Fatalf("unexpected list: %q", got)
+	}
+	h.Interact(customCmdCommand("remove", discordtest.StringOption("name", "faq")))
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$faq")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("want no answer after removal, got %+v", sends)
+	}
+}
+
+func TestCustomCmdOnlyMentionsTheCaller(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	h.RegisterPlugin(NewCustomCmdPlugin)
+	h.Interact(customCmdCommand("add", discordtest.StringOption("name", "shout"), discordtest.StringOption("response", "@everyone {user} says {args}")))
+
+	want := &discordgo.MessageAllowedMentions{Users: []string{discordtest.UserID}}
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$shout <@&900000000000000009>")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 || !reflect.DeepEqual(sends[0].AllowedMentions, want) {
+		t.Fatalf("want text responses mentioning the caller only, got %+v", sends)
+	}
+	h.Interact(discordtest.SlashCommand("shout", discordtest.StringOption("args", "@here")))
+	if got := h.LastResponse().Response.Data.AllowedMentions; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want slash responses mentioning the caller only, got %+v", got)
+	}
+}
+
+func TestCustomCmdAddRollsBackWhenRegistrationFails(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	h.RegisterPlugin(NewCustomCmdPlugin)
+	h.Session.FailNext("ApplicationCommandBulkOverwrite", errors.New("discord is down"))
+	h.Interact(customCmdCommand("add", discordtest.StringOption("name", "faq"), discordtest.StringOption("response", "pong")))
+
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$faq")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("want the failed command rolled back, got %+v", sends)
+	}
+	h.Interact(customCmdCommand("add", discordtest.StringOption("name", "faq"), discordtest.StringOption("response", "pong")))
+	if got := h.LastResponse().Content; !strings.HasPrefix(got, "Custom command `faq` added!") {
+		t.Fatalf("want the command added on retry, got %q", got)
+	}
+}
@@ -0,0 +1,478 @@
+package plugins
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/ddtv"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/settings"
+	"dalian-bot/internal/services/web"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed templates/dashboard/*.tmpl
+var dashboardTemplateFS embed.FS
+
+const (
+	dashboardPath        = "/dashboard"
+	dashboardCookie      = "dalian_dashboard"
+	dashboardLoginTTL    = 10 * time.Minute
+	dashboardSessionTTL  = 12 * time.Hour
+	dashboardPageSize    = 25
+	dashboardSessionKey  = "dashboardSession"
+	dashboardTokenLength = 32
+)
+
+// DashboardPlugin A web UI to browse and edit archived sites, and to see the DDTV notify channels of a guild.
+// Discord: `/dashboard` sends a one-time login link by direct message, the session is scoped to the guild.
+type DashboardPlugin struct {
+	core.Plugin
+	DiscordService  *discord.Service
+	DataService     *data.Service
+	WebService      *web.Service
+	SettingsService *settings.Service // optional, times are shown in the guild timezone
+	discord.SlashCommandUtil
+	discord.IDiscordHelper
+	templates *template.Template
+	base      string // path of the dashboard, including the base path of the web service
+	logins    dashboardSessions
+	sessions  dashboardSessions
+}
+
+// dashboardSession a login link, or a logged-in browser.
+type dashboardSession struct {
+	UserID  string
+	GuildID string
+	CSRF    string // posted back by every form
+	Expires time.Time
+}
+
+// dashboardSessions Sessions by their random token, in memory: a restart logs everyone out.
+type dashboardSessions struct {
+	lock     sync.Mutex
+	sessions map[string]*dashboardSession
+}
+
+func newDashboardToken() (string, error) {
+	raw := make([]byte, dashboardTokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// add Store the session for ttl and return its token. Expired sessions are dropped on the way.
+func (s *dashboardSessions) add(session dashboardSession, ttl time.Duration) (string, error) {
+	token, err := newDashboardToken()
+	if err != nil {
+		return "", err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*dashboardSession)
+	}
+	now := time.Now()
+	for k, v := range s.sessions {
+		if now.After(v.Expires) {
+			delete(s.sessions, k)
+		}
+	}
+	session.Expires = now.Add(ttl)
+	s.sessions[token] = &session
+	return token, nil
+}
+
+func (s *dashboardSessions) get(token string) (*dashboardSession, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[token]
+	if !ok || time.Now().After(session.Expires) {
+		return nil, false
+	}
+	return session, true
+}
+
+// take Return the session and forget it, for one-time tokens.
+func (s *dashboardSessions) take(token string) (*dashboardSession, bool) {
+	session, ok := s.get(token)
+	s.remove(token)
+	return session, ok
+}
+
+func (s *dashboardSessions) remove(token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sessions, token)
+}
+
+// dashboardPage Data of every template, the page itself is in Data.
+type dashboardPage struct {
+	Title    string
+	Base     string
+	CSRF     string // empty when logged out
+	Error    string
+	Location *time.Location
+	Data     any
+}
+
+type dashboardArchive struct {
+	Sites     []*archivePO
+	Text      string
+	Tags      string
+	Total     int
+	Page      int
+	PageCount int
+	PrevURL   string
+	NextURL   string
+}
+
+type dashboardSite struct {
+	Site *archivePO
+}
+
+type dashboardNotifyChannel struct {
+	Channel    string
+	Streamers  []string
+	HookTypes  []string
+	UseThreads bool
+}
+
+func (p *DashboardPlugin) handleDashboardCommand(i *discordgo.Interaction) error {
+	if i.Member == nil {
+		return p.DiscordService.InteractionRespondEphemeral(i, "The dashboard shows the data of a server, run it in a server channel.")
+	}
+	loginURL, err := p.WebService.URL(dashboardPath + "/login")
+	if err != nil {
+		core.Logger.Warnf("Dashboard login requested: %v", err)
+		return p.DiscordService.InteractionRespondEphemeral(i, "The dashboard is unavailable: the public url of the web server is not configured.")
+	}
+	token, err := p.logins.add(dashboardSession{UserID: i.Member.User.ID, GuildID: i.GuildID}, dashboardLoginTTL)
+	if err != nil {
+		p.DiscordService.InteractionRespondEphemeral(i, "Internal error creating the login link! Please contact admin for help.")
+		return err
+	}
+	if _, err := p.DiscordService.DirectMessageSend(i.Member.User.ID, fmt.Sprintf(
+		"Your dashboard login link, valid once in the next %d minutes. Don't share it:\n%s?token=%s",
+		int(dashboardLoginTTL.Minutes()), loginURL, token)); err != nil {
+		p.logins.remove(token)
+		core.Logger.Warnf("Error sending dashboard login link: %v", err)
+		return p.DiscordService.InteractionRespondEphemeral(i, "I couldn't send you a direct message. Do you accept them from server members?")
+	}
+	return p.DiscordService.InteractionRespondEphemeral(i, "Check your direct messages for a login link.")
+}
+
+// mountDashboard register the pages on the web service. Every page but the login needs a session.
+func (p *DashboardPlugin) mountDashboard() {
+	group := p.WebService.Group(dashboardPath)
+	p.base = group.BasePath()
+	group.GET("/login", p.handleLogin)
+	pages := group.Group("", p.requireSession)
+	pages.GET("", func(c *gin.Context) { c.Redirect(http.StatusSeeOther, p.base+"/archive") })
+	pages.GET("/archive", p.handleArchivePage)
+	pages.GET("/archive/:id", p.handleSitePage)
+	pages.POST("/archive/:id", p.handleSiteEdit)
+	pages.GET("/ddtv", p.handleDDTVPage)
+	pages.POST("/logout", p.handleLogout)
+}
+
+// render a page of the templates. The title, base path, CSRF token and location are filled in.
+func (p *DashboardPlugin) render(c *gin.Context, status int, name string, page dashboardPage) {
+	page.Base = p.base
+	page.Location = time.Local
+	if value, _ := c.Get(dashboardSessionKey); value != nil {
+		session := value.(*dashboardSession)
+		page.CSRF = session.CSRF
+		if p.SettingsService != nil {
+			page.Location = p.SettingsService.Location(session.GuildID)
+		}
+	}
+	c.Render(status, render.HTML{Template: p.templates, Name: name, Data: page})
+}
+
+func (p *DashboardPlugin) renderMessage(c *gin.Context, status int, title, message string) {
+	p.render(c, status, "message", dashboardPage{Title: title, Data: message})
+	c.Abort()
+}
+
+// handleLogin trade a one-time login link for a session cookie.
+func (p *DashboardPlugin) handleLogin(c *gin.Context) {
+	login, ok := p.logins.take(c.Query("token"))
+	if !ok {
+		p.renderMessage(c, http.StatusUnauthorized, "Login failed",
+			"This login link is invalid, used or expired. Run /dashboard in Discord for a new one.")
+		return
+	}
+	csrf, err := newDashboardToken()
+	if err != nil {
+		p.renderMessage(c, http.StatusInternalServerError, "Login failed", "Internal error, please try again.")
+		return
+	}
+	token, err := p.sessions.add(dashboardSession{UserID: login.UserID, GuildID: login.GuildID, CSRF: csrf}, dashboardSessionTTL)
+	if err != nil {
+		p.renderMessage(c, http.StatusInternalServerError, "Login failed", "Internal error, please try again.")
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(dashboardCookie, token, int(dashboardSessionTTL.Seconds()), p.base, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusSeeOther, p.base+"/archive")
+}
+
+// requireSession resolve the session cookie, and check the CSRF token of forms.
+func (p *DashboardPlugin) requireSession(c *gin.Context) {
+	token, err := c.Cookie(dashboardCookie)
+	session, ok := p.sessions.get(token)
+	if err != nil || !ok {
+		p.renderMessage(c, http.StatusUnauthorized, "Logged out",
+			"Your session is over. Run /dashboard in Discord for a login link.")
+		return
+	}
+	if c.Request.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(c.PostForm("csrf")), []byte(session.CSRF)) != 1 {
+		p.renderMessage(c, http.StatusForbidden, "Forbidden", "This form has expired, reload the page and try again.")
+		return
+	}
+	c.Set(dashboardSessionKey, session)
+	c.Next()
+}
+
+func dashboardSessionOf(c *gin.Context) *dashboardSession {
+	return c.MustGet(dashboardSessionKey).(*dashboardSession)
+}
+
+func (p *DashboardPlugin) handleLogout(c *gin.Context) {
+	if token, err := c.Cookie(dashboardCookie); err == nil {
+		p.sessions.remove(token)
+	}
+	c.SetCookie(dashboardCookie, "", -1, p.base, "", c.Request.TLS != nil, true)
+	c.Set(dashboardSessionKey, nil)
+	p.render(c, http.StatusOK, "message", dashboardPage{Title: "Logged out", Data: "See you!"})
+}
+
+// handleArchivePage GET /archive?q=text&tags=a,b&page=1, see archiveSiteQuery.
+func (p *DashboardPlugin) handleArchivePage(c *gin.Context) {
+	session := dashboardSessionOf(c)
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	archive := dashboardArchive{Text: c.Query("q"), Tags: c.Query("tags"), Page: page}
+	sites, total, err := searchArchiveSites(c.Request.Context(), p.DataService.Collection(archiveCollection), archiveSiteQuery{
+		UserID:  session.UserID,
+		GuildID: session.GuildID,
+		Tags:    splitArchiveTags(archive.Tags),
+		Text:    archive.Text,
+	}, (page-1)*dashboardPageSize, dashboardPageSize)
+	if err != nil {
+		core.Logger.Warnf("Error loading archive documents: %v", err)
+		p.renderMessage(c, http.StatusInternalServerError, "Archive", "Internal error loading your sites.")
+		return
+	}
+	archive.Sites = sites
+	archive.Total = total
+	archive.PageCount = (total + dashboardPageSize - 1) / dashboardPageSize
+	if archive.PageCount == 0 {
+		archive.PageCount = 1
+	}
+	pageURL := func(page int) string {
+		query := url.Values{"page": {strconv.Itoa(page)}}
+		if archive.Text != "" {
+			query.Set("q", archive.Text)
+		}
+		if archive.Tags != "" {
+			query.Set("tags", archive.Tags)
+		}
+		return p.base + "/archive?" + query.Encode()
+	}
+	if page > 1 {
+		archive.PrevURL = pageURL(page - 1)
+	}
+	if page < archive.PageCount {
+		archive.NextURL = pageURL(page + 1)
+	}
+	p.render(c, http.StatusOK, "archive", dashboardPage{Title: "Archive", Data: archive})
+}
+
+// findSessionSite the site of the path, if it belongs to the user in the guild of the session.
+// The not found page is rendered when it doesn't.
+func (p *DashboardPlugin) findSessionSite(c *gin.Context) *archivePO {
+	session := dashboardSessionOf(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		p.renderMessage(c, http.StatusNotFound, "Not found", "This site doesn't exist.")
+		return nil
+	}
+	var aPo archivePO
+	err = p.DataService.Collection(archiveCollection).FindOne(c.Request.Context(), &aPo,
+		data.Where(data.Eq("_id", id), data.Eq("user_id", session.UserID), data.Eq("guild_id", session.GuildID)))
+	if errors.Is(err, data.ErrNotFound) {
+		p.renderMessage(c, http.StatusNotFound, "Not found", "This site doesn't exist.")
+		return nil
+	} else if err != nil {
+		core.Logger.Warnf("Error loading archive document: %v", err)
+		p.renderMessage(c, http.StatusInternalServerError, "Archive", "Internal error loading the site.")
+		return nil
+	}
+	return &aPo
+}
+
+func (p *DashboardPlugin) handleSitePage(c *gin.Context) {
+	if aPo := p.findSessionSite(c); aPo != nil {
+		p.render(c, http.StatusOK, "site", dashboardPage{Title: "Edit site", Data: dashboardSite{Site: aPo}})
+	}
+}
+
+// handleSiteEdit save the form of the site page, validated as the API.
+func (p *DashboardPlugin) handleSiteEdit(c *gin.Context) {
+	aPo := p.findSessionSite(c)
+	if aPo == nil {
+		return
+	}
+	site, title, note := c.PostForm("site"), c.PostForm("title"), c.PostForm("note")
+	tags := splitArchiveTags(c.PostForm("tags"))
+	if err := (archiveSiteRequest{Site: &site, Title: &title, Tags: &tags, Note: &note}).apply(aPo); err != nil {
+		p.render(c, http.StatusBadRequest, "site", dashboardPage{Title: "Edit site", Error: err.Error(), Data: dashboardSite{Site: aPo}})
+		return
+	}
+	aPo.setTime(false)
+	if _, err := p.DataService.Collection(archiveCollection).UpdateOne(c.Request.Context(), data.ByID(aPo.BsonID), aPo, false); err != nil {
+		core.Logger.Warnf("Error updating archive document: %v", err)
+		p.render(c, http.StatusInternalServerError, "site", dashboardPage{Title: "Edit site", Error: "Internal error saving the site.", Data: dashboardSite{Site: aPo}})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, p.base+"/archive")
+}
+
+// handleDDTVPage the discord notify channels of the guild, with the names of featured streamers and webhook types.
+func (p *DashboardPlugin) handleDDTVPage(c *gin.Context) {
+	session := dashboardSessionOf(c)
+	var notifies []*ddtvNotifyPo
+	if err := p.DataService.Collection(ddtvNotifyCollection).Find(c.Request.Context(), &notifies,
+		data.Where(data.Eq("guild_id", session.GuildID), data.Eq("platform", platformDiscord))); err != nil {
+		core.Logger.Warnf("Error loading ddtv notify channels: %v", err)
+		p.renderMessage(c, http.StatusInternalServerError, "DDTV", "Internal error loading the notify channels.")
+		return
+	}
+	var streamers []*ddtvStreamerPo
+	if err := p.DataService.Collection(ddtvStreamerCollection).Find(c.Request.Context(), &streamers, data.Where()); err != nil {
+		core.Logger.Warnf("Error loading ddtv streamers: %v", err)
+	}
+	unames := make(map[int64]string)
+	for _, streamer := range streamers {
+		unames[streamer.UID] = streamer.Uname
+	}
+	var channels []dashboardNotifyChannel
+	for _, notify := range notifies {
+		channel := dashboardNotifyChannel{Channel: notify.NotifyChannelID, UseThreads: notify.UseThreads}
+		if dc, err := p.DiscordService.Session.Channel(notify.NotifyChannelID); err == nil && dc.Name != "" {
+			channel.Channel = "#" + dc.Name
+		}
+		for _, uid := range notify.FeaturedUIDs {
+			if uname, ok := unames[uid]; ok {
+				channel.Streamers = append(channel.Streamers, fmt.Sprintf("%s (%d)", uname, uid))
+			} else {
+				channel.Streamers = append(channel.Streamers, strconv.FormatInt(uid, 10))
+			}
+		}
+		for _, hookType := range notify.FeaturedHookTypes {
+			channel.HookTypes = append(channel.HookTypes, ddtv.HookType(hookType).Name())
+		}
+		channels = append(channels, channel)
+	}
+	p.render(c, http.StatusOK, "ddtv", dashboardPage{Title: "DDTV notify channels", Data: channels})
+}
+
+func (p *DashboardPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCreate) error {
+	if isMatched, cmdName := p.DefaultMatchCommand(i); !isMatched || cmdName != "dashboard" {
+		return nil
+	}
+	return p.handleDashboardCommand(i.Interaction)
+}
+
+func (p *DashboardPlugin) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&p.DiscordService); err != nil {
+		return err
+	}
+	if err := reg.FetchService(&p.DataService); err != nil {
+		return err
+	}
+	if err := reg.FetchService(&p.WebService); err != nil {
+		return err
+	}
+	_ = reg.FetchService(&p.SettingsService)
+	p.Plugin = core.Plugin{
+		Name:                 "dashboard",
+		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord},
+	}
+	templates, err := template.New("dashboard").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"formatTime": func(loc *time.Location, t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.In(loc).Format("2006-01-02 15:04")
+		},
+	}).ParseFS(dashboardTemplateFS, "templates/dashboard/*.tmpl")
+	if err != nil {
+		return err
+	}
+	p.templates = templates
+	p.mountDashboard()
+
+	dmPermission := false
+	p.SlashCommandUtil = discord.SlashCommandUtil{AppCommandsMap: map[string]*discordgo.ApplicationCommand{}}
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name:         "dashboard",
+		Description:  "Get a login link to the web dashboard of this server by direct message",
+		DMPermission: &dmPermission,
+	})
+	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
+		PluginHelp: "A web dashboard of your archived sites and the DDTV notify channels.",
+		CommandHelps: []discord.CommandHelp{
+			{
+				Name: "dashboard",
+				FormattedHelp: "*dashboard*: /dashboard\r" +
+					"get a one-time login link by direct message, to browse, search and edit your sites of this server, " +
+					"and see its DDTV notify channels",
+			},
+		},
+	})
+	return p.DiscordService.RegisterSlashCommand(p)
+}
+
+func (p *DashboardPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	discordEvent := discord.UnboxEvent(trigger)
+	if discordEvent.EventType != discord.EventTypeInteractionCreate ||
+		discordEvent.InteractionCreate.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	if err := p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate); err != nil {
+		core.Logger.Warnf("Error executing dashboard command: %v", err)
+	}
+}
+
+func NewDashboardPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var dashboardPlugin DashboardPlugin
+	if err := (&dashboardPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("Dashboard plugin MUST have all required service(s) injected!")
+	}
+	return &dashboardPlugin
+}
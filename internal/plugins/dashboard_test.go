@@ -0,0 +1,127 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord/discordtest"
+	"dalian-bot/internal/services/web"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var dashboardLinkPattern = regexp.MustCompile(`https://dalian\.example/bot(/dashboard/login\?token=[0-9a-f]+)`)
+
+// dashboardRequest request the target with the cookie, if any, and return the response.
+func dashboardRequest(s *web.Service, cookie *http.Cookie, method, target string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	s.GinEngine.ServeHTTP(w, req)
+	return w
+}
+
+func TestDashboardLoginAndPages(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	dataService := registerTestDataService(t, h)
+	gin.SetMode(gin.TestMode)
+	webService := &web.Service{ServiceConfig: web.ServiceConfig{BasePath: "/bot", PublicURL: "https://dalian.example"}}
+	if err := webService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatalf("web service init failed: %v", err)
+	}
+	h.RegisterPlugin(NewDashboardPlugin)
+	h.Session.AddChannel(&discordgo.Channel{ID: "notify", Name: "lives"})
+
+	ctx := context.Background()
+	siteID, err := dataService.Collection(archiveCollection).InsertOne(ctx, archivePO{
+		Site: "https://example.com/a", Title: "A", Tags: []string{"go"}, UserID: discordtest.UserID, GuildID: discordtest.GuildID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataService.Collection(archiveCollection).InsertOne(ctx, archivePO{
+		Site: "https://example.com/other", UserID: "someone else", GuildID: discordtest.GuildID})
+	dataService.Collection(ddtvNotifyCollection).InsertOne(ctx, ddtvNotifyPo{
+		Platform: platformDiscord, GuildID: discordtest.GuildID, NotifyChannelID: "notify", FeaturedUIDs: []int64{42, 7}, FeaturedHookTypes: []int{0}})
+	dataService.Collection(ddtvStreamerCollection).InsertOne(ctx, ddtvStreamerPo{UID: 42, Uname: "Streamer42"})
+
+	if w := dashboardRequest(webService, nil, http.MethodGet, "/bot/dashboard/archive", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("archive without session: status = %d", w.Code)
+	}
+
+	h.Interact(discordtest.SlashCommand("dashboard"))
+	if got := h.LastResponse().Content; got != "Check your direct messages for a login link." {
+		t.Fatalf("unexpected response: %q", got)
+	}
+	dms := h.Session.CallsOf("ChannelMessageSend")
+	if len(dms) != 1 || dms[0].ChannelID != "dm-"+discordtest.UserID {
+		t.Fatalf("want one direct message, got %+v", dms)
+	}
+	link := dashboardLinkPattern.FindStringSubmatch(dms[0].Content)
+	if link == nil {
+		t.Fatalf("no login link in %q", dms[0].Content)
+	}
+	loginPath, err := url.PathUnescape(link[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := dashboardRequest(webService, nil, http.MethodGet, "/bot"+loginPath, nil)
+	if w.Code != http.StatusSeeOther || len(w.Result().Cookies()) != 1 {
+		t.Fatalf("login: status = %d, cookies %v", w.Code, w.Result().Cookies())
+	}
+	cookie := w.Result().Cookies()[0]
+	if w := dashboardRequest(webService, nil, http.MethodGet, "/bot"+loginPath, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login link used twice: status = %d", w.Code)
+	}
+
+	w = dashboardRequest(webService, cookie, http.MethodGet, "/bot/dashboard/archive?tags=go", nil)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, "https://example.com/a") || strings.Contains(body, "other") {
+		t.Fatalf("archive page: status = %d, body %s", w.Code, body)
+	}
+	csrf := regexp.MustCompile(`name="csrf" value="([0-9a-f]+)"`).FindStringSubmatch(w.Body.String())
+	if csrf == nil {
+		t.Fatalf("no csrf token in %s", w.Body)
+	}
+
+	sitePath := "/bot/dashboard/archive/" + siteID.(primitive.ObjectID).Hex()
+	edit := url.Values{"site": {"https://example.com/b"}, "title": {"B"}, "tags": {"go, web"}, "note": {"edited"}}
+	if w := dashboardRequest(webService, cookie, http.MethodPost, sitePath, edit); w.Code != http.StatusForbidden {
+		t.Fatalf("edit without csrf: status = %d", w.Code)
+	}
+	edit.Set("csrf", csrf[1])
+	if w := dashboardRequest(webService, cookie, http.MethodPost, sitePath, edit); w.Code != http.StatusSeeOther {
+		t.Fatalf("edit: status = %d, body %s", w.Code, w.Body)
+	}
+	var edited archivePO
+	if err := dataService.Collection(archiveCollection).FindOne(ctx, &edited, data.ByID(siteID)); err != nil {
+		t.Fatal(err)
+	}
+	if edited.Site != "https://example.com/b" || edited.Note != "edited" || strings.Join(edited.Tags, ",") != "go,web" {
+		t.Errorf("unexpected edited site: %+v", edited)
+	}
+	edit.Set("site", "not a url")
+	if w := dashboardRequest(webService, cookie, http.MethodPost, sitePath, edit); w.Code != http.StatusBadRequest {
+		t.Fatalf("edit with invalid url: status = %d", w.Code)
+	}
+
+	w = dashboardRequest(webService, cookie, http.MethodGet, "/bot/dashboard/ddtv", nil)
+	if body := w.Body.String(); !strings.Contains(body, "#lives") || !strings.Contains(body, "Streamer42 (42), 7") || !strings.Contains(body, "StartLive") {
+		t.Fatalf("ddtv page: status = %d, body %s", w.Code, body)
+	}
+
+	dashboardRequest(webService, cookie, http.MethodPost, "/bot/dashboard/logout", url.Values{"csrf": {csrf[1]}})
+	if w := dashboardRequest(webService, cookie, http.MethodGet, "/bot/dashboard/archive", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("archive after logout: status = %d", w.Code)
+	}
+}
@@ -3,34 +3,224 @@ package plugins
 import (
 	"context"
 	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/data"
 	"dalian-bot/internal/services/ddtv"
 	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/forward"
+	"dalian-bot/internal/services/settings"
+	"dalian-bot/internal/services/telegram"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/exp/slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // DDTVPlugin Receives DDTV Webhook and notify in channel
 // Discord: related command can be found under command group of `ddtv`
+// Telegram: `$ddtv set`, `$ddtv remove` and `$ddtv status` in the chat to be notified.
+// Console: `$ddtv status` and `$ddtv remove <platform> <channel id>` over the notify channels of every platform.
 type DDTVPlugin struct {
 	core.Plugin
-	DiscordService *discord.Service
-	DataService    *data.Service
+	DiscordService  *discord.Service
+	DataService     *data.Service
+	TelegramService *telegram.Service // optional
+	ConsoleService  *console.Service  // optional
+	ForwardService  *forward.Service  // optional
+	SettingsService *settings.Service // optional
 	discord.SlashCommandUtil
 	core.ArgParseUtil
+	core.StartWithMatchUtil
 	discord.IDiscordHelper
 }
 
+// DoTelegramMessage `$ddtv set|remove|status` and the featured lists for telegram chats.
+// Commands changing the chat are reserved to its admins.
+func (p *DDTVPlugin) DoTelegramMessage(m *telegram.Message) error {
+	config := p.TelegramService.TelegramAccountConfig
+	if matched, _ := p.MatchText(m.Text, config); !matched {
+		return nil
+	}
+	chatID := strconv.FormatInt(m.Chat.ID, 10)
+	// command words are split on spaces, the separator splits lists
+	args := strings.Fields(m.Text)
+	usage := fmt.Sprintf("Usage: %sddtv set|remove|status\n%sddtv streamers|webhooks add|remove|set <values separated by %s>",
+		config.Prefix, config.Prefix, config.Separator)
+	if len(args) < 2 {
+		_, err := p.TelegramService.SendMessage(m.Chat.ID, usage)
+		return err
+	}
+	if args[1] != "status" {
+		isAdmin, err := p.TelegramService.IsChatAdmin(m)
+		if err != nil {
+			core.Logger.Warnf("Error checking telegram chat admin: %v", err)
+			return err
+		}
+		if !isAdmin {
+			_, err := p.TelegramService.SendMessage(m.Chat.ID, "Only admins of this chat can do that.")
+			return err
+		}
+	}
+	var reply string
+	switch args[1] {
+	case "set":
+		var adminID string
+		if m.From != nil {
+			adminID = strconv.FormatInt(m.From.ID, 10)
+		}
+		updateResult, err := p.upsertOneWebhookNotifyChannel(ddtvNotifyPo{
+			Platform:        platformTelegram,
+			AdminUserID:     adminID,
+			NotifyChannelID: chatID,
+		})
+		if err != nil {
+			core.Logger.Warnf("Error inserting webhook chat record: %v", err)
+			return err
+		}
+		if updateResult.UpsertedCount > 0 {
+			reply = "webhook chat created!"
+		} else {
+			reply = "already a webhook chat!"
+		}
+	case "remove":
+		deletedCount, err := p.deleteOneWebhookNotifyChannelOnPlatform(platformTelegram, chatID)
+		if err != nil {
+			core.Logger.Warnf("Error deleting webhook chat record: %v", err)
+			return err
+		}
+		if deletedCount > 0 {
+			reply = "webhook chat removed!"
+		} else {
+			reply = "not a webhook chat yet!"
+		}
+	case "status", "streamers", "webhooks":
+		notifyPo, err := p.findOneWebhookNotifyChannelOnPlatform(platformTelegram, chatID)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				reply = fmt.Sprintf("This is not a notification chat yet! Consider making it one by using %sddtv set?", config.Prefix)
+				break
+			}
+			core.Logger.Warnf("Error finding webhook chat record: %v", err)
+			return err
+		}
+		if args[1] != "status" {
+			if len(args) < 4 {
+				reply = usage
+				break
+			}
+			values := p.SeparateArgs(strings.Join(args[3:], " "), config.Separator)
+			if args[1] == "streamers" {
+				notifyPo.FeaturedUIDs, err = modifyFeatured(notifyPo.FeaturedUIDs, args[2], values, func(v string) (int64, error) {
+					return strconv.ParseInt(v, 10, 64)
+				})
+			} else {
+				notifyPo.FeaturedHookTypes, err = modifyFeatured(notifyPo.FeaturedHookTypes, args[2], values, strconv.Atoi)
+			}
+			if err != nil {
+				reply = err.Error()
+				break
+			}
+			if _, err := p.upsertOneWebhookNotifyChannel(notifyPo); err != nil {
+				core.Logger.Warnf("Error updating webhook chat featured list: %v", err)
+				return err
+			}
+		}
+		reply = fmt.Sprintf("Featured streamers: %v\nFeatured webhook types: %v\n(empty list means ALL)",
+			notifyPo.FeaturedUIDs, notifyPo.FeaturedHookTypes)
+	default:
+		reply = fmt.Sprintf("Unknown subcommand %s. %s", args[1], usage)
+	}
+	_, err := p.TelegramService.SendMessage(m.Chat.ID, reply)
+	return err
+}
+
+// DoConsoleMessage `$ddtv status|remove` for the operator of the bot, over the notify channels of every platform.
+func (p *DDTVPlugin) DoConsoleMessage(m *console.Message) error {
+	config := p.ConsoleService.ConsoleAccountConfig
+	if matched, _ := p.MatchText(m.Content, config); !matched {
+		return nil
+	}
+	args := strings.Fields(m.Content)
+	usage := fmt.Sprintf("Usage: %sddtv status\n%sddtv remove %s|%s <channel id>", config.Prefix, config.Prefix, platformDiscord, platformTelegram)
+	if len(args) < 2 {
+		return p.ConsoleService.Reply(m, usage)
+	}
+	switch args[1] {
+	case "status":
+		channels, err := p.fetchDDTVWebhookNotifyChannels()
+		if err != nil {
+			core.Logger.Warnf("Retrieve webhook channels failed!: %v", err)
+			return err
+		}
+		lines := []string{fmt.Sprintf("%d notify channel(s).", len(channels))}
+		for _, channel := range channels {
+			lines = append(lines, fmt.Sprintf("%s %s: featured streamers %v, featured webhook types %v",
+				channel.Platform, channel.NotifyChannelID, channel.FeaturedUIDs, channel.FeaturedHookTypes))
+		}
+		return p.ConsoleService.Reply(m, strings.Join(lines, "\n"))
+	case "remove":
+		if len(args) != 4 {
+			return p.ConsoleService.Reply(m, usage)
+		}
+		deletedCount, err := p.deleteOneWebhookNotifyChannelOnPlatform(args[2], args[3])
+		if err != nil {
+			core.Logger.Warnf("Error deleting webhook channel record: %v", err)
+			return err
+		}
+		if deletedCount == 0 {
+			return p.ConsoleService.Reply(m, "not a webhook channel!")
+		}
+		return p.ConsoleService.Reply(m, "webhook channel removed!")
+	default:
+		return p.ConsoleService.Reply(m, fmt.Sprintf("Unknown subcommand %s. %s", args[1], usage))
+	}
+}
+
+// modifyFeatured add values to the featured list, remove them, or set the list to them. `-` alone clears the list.
+func modifyFeatured[T int | int64](featured []T, action string, values []string, parse func(string) (T, error)) ([]T, error) {
+	var parsed []T
+	if !(len(values) == 1 && values[0] == "-") {
+		for _, v := range values {
+			value, err := parse(v)
+			if err != nil {
+				return nil, fmt.Errorf("\"%s\" is not a valid number!", v)
+			}
+			if !slices.Contains(parsed, value) {
+				parsed = append(parsed, value)
+			}
+		}
+	}
+	switch action {
+	case "add":
+		for _, v := range parsed {
+			if !slices.Contains(featured, v) {
+				featured = append(featured, v)
+			}
+		}
+		return featured, nil
+	case "remove":
+		kept := featured[:0]
+		for _, v := range featured {
+			if !slices.Contains(parsed, v) {
+				kept = append(kept, v)
+			}
+		}
+		return kept, nil
+	case "set":
+		return parsed, nil
+	default:
+		return nil, fmt.Errorf("unknown action %s, use add, remove or set", action)
+	}
+}
+
 func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCreate) (e error) {
 	if isMatched, cmdName := p.DefaultMatchCommand(i); !isMatched {
 		//fmt.Printf("nothing matched: %v", i)
@@ -45,9 +235,10 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 				switch cmdOption.Name {
 				case "set":
 					updateResult, err := p.upsertOneWebhookNotifyChannel(ddtvNotifyPo{
-						AdminDiscordUserID: i.Interaction.Member.User.ID,
-						GuildID:            i.Interaction.GuildID,
-						NotifyChannelID:    i.Interaction.ChannelID,
+						Platform:        platformDiscord,
+						AdminUserID:     i.Interaction.Member.User.ID,
+						GuildID:         i.Interaction.GuildID,
+						NotifyChannelID: i.Interaction.ChannelID,
 					})
 					if err != nil {
 						core.Logger.Warnf("Error inserting webhook channel record: %v", err)
@@ -58,13 +249,33 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					} else {
 						p.DiscordService.InteractionRespond(i.Interaction, "already a webhook channel!")
 					}
+				case "threads":
+					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
+					if err != nil {
+						if errors.Is(err, data.ErrNotFound) {
+							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
+							return nil
+						}
+						core.Logger.Warnf("Error finding webhook channel record: %v", err)
+						return err
+					}
+					notifyPo.UseThreads = p.ParseOptionsMap(cmdOption.Options)["enabled"].BoolValue()
+					if _, err := p.upsertOneWebhookNotifyChannel(notifyPo); err != nil {
+						core.Logger.Warnf("Error updating webhook channel threads: %v", err)
+						return err
+					}
+					if notifyPo.UseThreads {
+						p.DiscordService.InteractionRespond(i.Interaction, "Every live session now gets its own thread.")
+					} else {
+						p.DiscordService.InteractionRespond(i.Interaction, "Notifications are now posted in the channel.")
+					}
 				case "remove":
-					deleteResult, err := p.deleteOneWebhookNotifyChannel(i.Interaction.ChannelID)
+					deletedCount, err := p.deleteOneWebhookNotifyChannel(i.Interaction.ChannelID)
 					if err != nil {
 						core.Logger.Warnf("Error deleting webhook channel record: %v", err)
 						return err
 					}
-					if deleteResult.DeletedCount > 0 {
+					if deletedCount > 0 {
 						p.DiscordService.InteractionRespond(i.Interaction, "webhook channel removed!")
 					} else {
 						p.DiscordService.InteractionRespond(i.Interaction, "not a webhook channel yet!")
@@ -80,7 +291,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// fetch and validate ddtvNotifyPo
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
+						if errors.Is(err, data.ErrNotFound) {
 							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
@@ -109,7 +320,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 						//clean up
 						uids = []int64{}
 					} else {
-						rawUidsStrings := p.SeparateArgs(uidsStr, p.DiscordService.DiscordAccountConfig.Separator)
+						rawUidsStrings := p.SeparateArgs(uidsStr, p.DiscordService.MessengerConfig(i.GuildID).Separator)
 						// iter through and validate uids
 						for _, v := range rawUidsStrings {
 							parsedInt64, err := strconv.ParseInt(v, 10, 64)
@@ -125,7 +336,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// find and modify notifyPo when necessary
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
+						if errors.Is(err, data.ErrNotFound) {
 							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
@@ -151,6 +362,9 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					p.DiscordService.InteractionRespond(i.Interaction, fmt.Sprintf("Updated featured list: %v", notifyPo.FeaturedUIDs))
 
 				case "status":
+					// status replies are only shown to the user asking
+					r := p.DiscordService.NewResponder(i.Interaction, true)
+					defer r.Done()
 					dumpFlag := false
 					if dump, ok := optionsMap["dump"]; ok {
 						dumpFlag = dump.BoolValue()
@@ -158,8 +372,8 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// fetch and validate ddtvNotifyPo
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
-							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
+						if errors.Is(err, data.ErrNotFound) {
+							r.RespondText("This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
 						core.Logger.Warnf("Error finding webhook channel record: %v", err)
@@ -168,19 +382,24 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					currentUIDs := notifyPo.FeaturedUIDs
 					// if nothing to show
 					if len(notifyPo.FeaturedUIDs) == 0 {
-						p.DiscordService.InteractionRespond(i.Interaction, "Featured list empty. Push ALL webhook notifications by default.")
+						r.RespondText("Featured list empty. Push ALL webhook notifications by default.")
 						return nil
 					}
 					sort.Slice(notifyPo.FeaturedUIDs, func(i, j int) bool { return notifyPo.FeaturedUIDs[i] < notifyPo.FeaturedUIDs[j] })
-					ansStr := fmt.Sprintf("%d streamers featured: %v", len(currentUIDs), currentUIDs)
-					if dumpFlag {
-						var strSlice []string
-						for _, v := range currentUIDs {
-							strSlice = append(strSlice, strconv.FormatInt(v, 10))
-						}
-						ansStr += fmt.Sprintf("\rHere's the dump for you:\r```%s```", strings.Join(strSlice[:], p.DiscordService.DiscordAccountConfig.Separator))
+					if !dumpFlag {
+						r.RespondText(fmt.Sprintf("%d streamers featured: %v", len(currentUIDs), currentUIDs))
+						return nil
+					}
+					var strSlice []string
+					for _, v := range currentUIDs {
+						strSlice = append(strSlice, strconv.FormatInt(v, 10))
+					}
+					r.RespondText(fmt.Sprintf("%d streamers featured. Here's the dump for you:", len(currentUIDs)))
+					// a long dump is split into several follow-ups, or attached as a file
+					if err := r.FollowupCodeBlock(strings.Join(strSlice, p.DiscordService.MessengerConfig(i.GuildID).Separator)); err != nil {
+						core.Logger.Warnf("Error sending streamers dump: %v", err)
+						return err
 					}
-					p.DiscordService.InteractionRespond(i.Interaction, ansStr)
 				}
 			case "webhooks":
 				cmdOption := cmdOption.Options[0]
@@ -192,7 +411,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// fetch and validate ddtvNotifyPo
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
+						if errors.Is(err, data.ErrNotFound) {
 							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
@@ -222,7 +441,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 						//clean up
 						hookTypes = []int{}
 					} else {
-						rawHooksStrings := p.SeparateArgs(hookTypesStr, p.DiscordService.DiscordAccountConfig.Separator)
+						rawHooksStrings := p.SeparateArgs(hookTypesStr, p.DiscordService.MessengerConfig(i.GuildID).Separator)
 						// iter through and validate webhook types
 						for _, v := range rawHooksStrings {
 							parsedInt, err := strconv.Atoi(v)
@@ -238,7 +457,7 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// find and modify notifyPo when necessary
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
+						if errors.Is(err, data.ErrNotFound) {
 							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
@@ -264,6 +483,9 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					p.DiscordService.InteractionRespond(i.Interaction, fmt.Sprintf("Updated featured list: %v", notifyPo.FeaturedHookTypes))
 
 				case "status":
+					// status replies are only shown to the user asking
+					r := p.DiscordService.NewResponder(i.Interaction, true)
+					defer r.Done()
 					dumpFlag := false
 					if dump, ok := optionsMap["dump"]; ok {
 						dumpFlag = dump.BoolValue()
@@ -271,8 +493,8 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					// fetch and validate ddtvNotifyPo
 					notifyPo, err := p.findOneWebhookNotifyChannelByChannelID(i.Interaction.ChannelID)
 					if err != nil {
-						if err == mongo.ErrNoDocuments {
-							p.DiscordService.InteractionRespond(i.Interaction, "This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
+						if errors.Is(err, data.ErrNotFound) {
+							r.RespondText("This is not a notification channel yet! Consider making it one by using *ddtv webhook-channel set*?")
 							return nil
 						}
 						core.Logger.Warnf("Error finding webhook channel record: %v", err)
@@ -281,19 +503,24 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 					currentWebhookTypes := notifyPo.FeaturedHookTypes
 					// if nothing to show
 					if len(currentWebhookTypes) == 0 {
-						p.DiscordService.InteractionRespond(i.Interaction, "Featured list empty. Push ALL webhook notifications by default.")
+						r.RespondText("Featured list empty. Push ALL webhook notifications by default.")
 						return nil
 					}
 					sort.Slice(currentWebhookTypes, func(i, j int) bool { return currentWebhookTypes[i] < currentWebhookTypes[j] })
-					ansStr := fmt.Sprintf("%d webhook types featured: %v", len(currentWebhookTypes), currentWebhookTypes)
-					if dumpFlag {
-						var strSlice []string
-						for _, v := range currentWebhookTypes {
-							strSlice = append(strSlice, strconv.Itoa(v))
-						}
-						ansStr += fmt.Sprintf("\rHere's the dump for you:\r```%s```", strings.Join(strSlice[:], p.DiscordService.DiscordAccountConfig.Separator))
+					if !dumpFlag {
+						r.RespondText(fmt.Sprintf("%d webhook types featured: %v", len(currentWebhookTypes), currentWebhookTypes))
+						return nil
+					}
+					var strSlice []string
+					for _, v := range currentWebhookTypes {
+						strSlice = append(strSlice, strconv.Itoa(v))
+					}
+					r.RespondText(fmt.Sprintf("%d webhook types featured. Here's the dump for you:", len(currentWebhookTypes)))
+					// a long dump is split into several follow-ups, or attached as a file
+					if err := r.FollowupCodeBlock(strings.Join(strSlice, p.DiscordService.MessengerConfig(i.GuildID).Separator)); err != nil {
+						core.Logger.Warnf("Error sending webhook types dump: %v", err)
+						return err
 					}
-					p.DiscordService.InteractionRespond(i.Interaction, ansStr)
 				}
 			}
 
@@ -304,18 +531,41 @@ func (p *DDTVPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 }
 
 func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
-	// DiscordService is a MUST have. return error if not found.
-	if err := reg.FetchService(&p.DiscordService); err != nil {
+	// DataService is a MUST have. return error if not found.
+	if err := reg.FetchService(&p.DataService); err != nil {
 		return err
 	}
-	// DataService is also a MUST have. return error if not found.
-	if err := reg.FetchService(&p.DataService); err != nil {
+	if err := p.DataService.RegisterSchema(ddtvSchema); err != nil {
 		return err
 	}
 	// ddtvService is not used to perform actions actively in the plugin, so not imported.
 
-	p.AcceptedTriggerTypes = []core.TriggerType{discord.TriggerTypeDiscord, ddtv.TriggerTypeDDTV}
+	p.AcceptedTriggerTypes = []core.TriggerType{ddtv.TriggerTypeDDTV}
+	// DiscordService and TelegramService are optional, but channels of one of them at least are needed to notify.
+	discordErr := reg.FetchService(&p.DiscordService)
+	if discordErr == nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, discord.TriggerTypeDiscord)
+	}
+	if err := reg.FetchService(&p.TelegramService); err == nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, telegram.TriggerTypeTelegram)
+	} else if discordErr != nil {
+		return discordErr
+	}
+	// ConsoleService is optional, the operator manages the notify channels of every platform with it.
+	if err := reg.FetchService(&p.ConsoleService); err == nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, console.TriggerTypeConsole)
+	}
+	// ForwardService is optional, accepted webhooks are forwarded to guilds when it's registered.
+	_ = reg.FetchService(&p.ForwardService)
+	// SettingsService is optional, thread names are in the timezone of the bot without it.
+	_ = reg.FetchService(&p.SettingsService)
 	p.Name = "ddtv"
+	p.Identifiers = []string{"ddtv"}
+	// shown in the descriptions of list options
+	var separator string
+	if p.DiscordService != nil {
+		separator = p.DiscordService.DiscordAccountConfig.Separator
+	}
 	p.AppCommandsMap = make(map[string]*discordgo.ApplicationCommand)
 	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
 		Name:        "ddtv",
@@ -334,6 +584,18 @@ func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
 						Name:        "remove",
 						Type:        discordgo.ApplicationCommandOptionSubCommand,
 						Description: "Remove current channel as ddtv webhook channel",
+					}, {
+						Name:        "threads",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Description: "Open a thread for every live session, archived when the live stops",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "enabled",
+								Required:    true,
+								Description: "Post notifications of a session in its thread",
+							},
+						},
 					},
 				},
 			},
@@ -349,10 +611,11 @@ func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
 						Description: "Add a streamer to current channel's featured list",
 						Options: []*discordgo.ApplicationCommandOption{
 							{
-								Type:        discordgo.ApplicationCommandOptionInteger,
-								Name:        "uid",
-								Required:    true,
-								Description: "The bilibili UID of the streamer (not RoomID!)",
+								Type:         discordgo.ApplicationCommandOptionInteger,
+								Name:         "uid",
+								Required:     true,
+								Description:  "The bilibili UID of the streamer (not RoomID!)",
+								Autocomplete: true,
 							},
 						},
 					}, {
@@ -365,7 +628,7 @@ func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
 								Type:        discordgo.ApplicationCommandOptionString,
 								Name:        "uids",
 								Required:    true,
-								Description: fmt.Sprintf("The bilibili UID of the streamer, separated by default separator (%s)", p.DiscordService.DiscordAccountConfig.Separator),
+								Description: fmt.Sprintf("The bilibili UID of the streamer, separated by default separator (%s)", separator),
 							},
 							{
 								Type:        discordgo.ApplicationCommandOptionBoolean,
@@ -401,10 +664,11 @@ func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
 						Description: "Add a webbhook type code to featured list",
 						Options: []*discordgo.ApplicationCommandOption{
 							{
-								Type:        discordgo.ApplicationCommandOptionInteger,
-								Name:        "webhook-code",
-								Required:    true,
-								Description: "The webhook type code of the DDTV Webhook.",
+								Type:         discordgo.ApplicationCommandOptionInteger,
+								Name:         "webhook-code",
+								Required:     true,
+								Description:  "The webhook type code of the DDTV Webhook.",
+								Autocomplete: true,
 							},
 						},
 					}, {
@@ -417,7 +681,7 @@ func (p *DDTVPlugin) Init(reg *core.ServiceRegistry) error {
 								Type:        discordgo.ApplicationCommandOptionString,
 								Name:        "webhook-codes",
 								Required:    true,
-								Description: fmt.Sprintf("TThe webhook type code of the DDTV Webhook, separated by default separator (%s)", p.DiscordService.DiscordAccountConfig.Separator),
+								Description: fmt.Sprintf("TThe webhook type code of the DDTV Webhook, separated by default separator (%s)", separator),
 							},
 							{
 								Type:        discordgo.ApplicationCommandOptionBoolean,
@@ -451,6 +715,9 @@ Dalian will send a message for every incoming DDTV webhook received in this chan
 	formattedHelpRemoveNotifChannel := `ddtv webhook-channel remove*: /ddtv webhook-channel remove
 Remove current channel from DDTV webhook notification channel list.
 Dalian will no longer send a message for every incoming DDTV webhook received in this channel`
+	formattedHelpThreadsNotifChannel := `*ddtv webhook-channel threads*: /ddtv webhook-channel threads [enabled]
+Open a thread on the StartLive notification of a streamer. Later notifications of the live session are posted into it,
+and the thread is archived after StopLive.`
 
 	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
 		PluginHelp: "HelperUtil support for Dalian.",
@@ -463,8 +730,15 @@ Dalian will no longer send a message for every incoming DDTV webhook received in
 				Name:          "ddtv webhook-channel remove",
 				FormattedHelp: formattedHelpRemoveNotifChannel,
 			},
+			{
+				Name:          "ddtv webhook-channel threads",
+				FormattedHelp: formattedHelpThreadsNotifChannel,
+			},
 		},
 	})
+	if p.DiscordService == nil {
+		return nil
+	}
 	return p.DiscordService.RegisterSlashCommand(p)
 }
 
@@ -495,83 +769,360 @@ func (p *DDTVPlugin) Trigger(trigger core.Trigger) {
 	case ddtv.TriggerTypeDDTV:
 		// do ddtv webhook thing
 		webhook := ddtv.UnboxEvent(trigger).WebHook
+		// every streamer seen is suggested when featuring streamers
+		if err := p.recordStreamer(webhook); err != nil {
+			core.Logger.Warnf("Error recording streamer: %v", err)
+		}
 		// if hooktype is channel, restrict non-record channel message to online.
 		// todo: add a config option to control this behavior
 		if webhook.UserInfo.UID != 0 && !webhook.RoomInfo.IsAutoRec && webhook.Type != ddtv.HookStartLive {
 			return
 		}
 		p.notifyDDTVWebhookToChannels(webhook)
+	case telegram.TriggerTypeTelegram:
+		m := telegram.UnboxEvent(trigger).Message
+		if p.TelegramService.IsMessageFromBotOrSelf(m) {
+			return
+		}
+		if err := p.DoTelegramMessage(m); err != nil {
+			core.Logger.Warnf("Error executing telegram command: %v", err)
+		}
+	case console.TriggerTypeConsole:
+		if err := p.DoConsoleMessage(console.UnboxEvent(trigger).Message); err != nil {
+			core.Logger.Warnf("Error executing console command: %v", err)
+		}
 	default:
 		core.Logger.Warnf(core.LogPromptUnknownTrigger, trigger.Type)
 	}
 }
 
+// notifyDDTVWebhookToChannels notify every accepting channel at once, a failing channel doesn't hold up the others.
 func (p *DDTVPlugin) notifyDDTVWebhookToChannels(webhook ddtv.WebHook) {
 	channels, err := p.fetchDDTVWebhookNotifyChannels()
 	if err != nil {
 		core.Logger.Warnf("Retrieve webhook channels failed!: %v", err)
 		return
 	}
+	forwardedGuilds := make(map[string]bool)
+	var wg sync.WaitGroup
 	for _, channel := range channels {
-		// check feature group only when it's not empty
-		if len(channel.FeaturedUIDs) != 0 {
-			// do NOT display if the user is not in the list
-			if !slices.Contains(channel.FeaturedUIDs, webhook.Uid) {
-				//SKIP this channel
-				continue
+		if !channel.accepts(webhook) {
+			//SKIP this channel
+			continue
+		}
+		// forward once per guild, no matter how many of its channels accept the webhook
+		if p.ForwardService != nil && channel.GuildID != "" && !forwardedGuilds[channel.GuildID] {
+			forwardedGuilds[channel.GuildID] = true
+			if err := p.ForwardService.Publish(channel.GuildID, forward.EventDDTVWebhook, webhook); err != nil {
+				core.Logger.Warnf("Error forwarding webhook: %v", err)
 			}
 		}
-		// check feature group only when it's not empty
-		if len(channel.FeaturedHookTypes) != 0 {
-			// do NOT display if the webhook type is incorrect
-			if !slices.Contains(channel.FeaturedHookTypes, webhook.Type.Value()) {
-				//SKIP this webhook type
-				continue
+		wg.Add(1)
+		go func(channel ddtvNotifyPo) {
+			defer wg.Done()
+			switch channel.Platform {
+			case platformTelegram:
+				p.notifyDDTVWebhookToTelegram(channel, webhook)
+			default:
+				if p.DiscordService != nil {
+					p.notifyDDTVWebhookToDiscord(channel, webhook)
+				}
 			}
+		}(channel)
+	}
+	wg.Wait()
+}
+
+func (p *DDTVPlugin) notifyDDTVWebhookToDiscord(channel ddtvNotifyPo, webhook ddtv.WebHook) {
+	channelID := channel.NotifyChannelID
+	var session *ddtvThreadPo
+	if channel.UseThreads {
+		var err error
+		if session, err = p.findActiveThread(channel.NotifyChannelID, webhook.Uid); err != nil {
+			core.Logger.Warnf("Error finding session thread, posting in the channel: %v", err)
+		} else if session != nil {
+			channelID = session.ThreadID
+		}
+	}
+	msg, err := p.DiscordService.ChannelMessageSendEmbed(channelID, webhook.DigestEmbed())
+	if err != nil {
+		core.Logger.Warnf("Embed sent to channel %s failed: %v", channelID, err)
+		b, _ := json.Marshal(webhook)
+		if _, reportErr := p.DiscordService.ChannelMessageSendCodeBlock(channel.NotifyChannelID, err.Error()+"\n"+string(b)); reportErr != nil {
+			core.Logger.Warnf("Error reporting the failed embed to channel %s: %v", channel.NotifyChannelID, reportErr)
+		}
+		return
+	}
+	if channel.UseThreads {
+		p.followSessionThread(channel, webhook, msg, session)
+	}
+}
+
+// guildLocation the timezone set for the guild, the one of the bot by default.
+func (p *DDTVPlugin) guildLocation(guildID string) *time.Location {
+	if p.SettingsService == nil {
+		return time.Local
+	}
+	return p.SettingsService.Location(guildID)
+}
+
+// followSessionThread open a thread on the StartLive notification, and archive the thread of the session on StopLive.
+// session is the active session the notification was posted in, if any.
+func (p *DDTVPlugin) followSessionThread(channel ddtvNotifyPo, webhook ddtv.WebHook, msg *discordgo.Message, session *ddtvThreadPo) {
+	if webhook.Uid == 0 {
+		return
+	}
+	switch webhook.Type {
+	case ddtv.HookStartLive:
+		if session != nil {
+			// StopLive never came, close the previous session first
+			p.archiveSessionThread(session)
+		}
+		name := webhook.RoomInfo.Uname
+		if name == "" {
+			name = webhook.UserInfo.Name
 		}
-		_, err := p.DiscordService.ChannelMessageSendEmbed(channel.NotifyChannelID, webhook.DigestEmbed())
+		thread, err := p.DiscordService.Session.MessageThreadStartComplex(msg.ChannelID, msg.ID, &discordgo.ThreadStart{
+			Name:                fmt.Sprintf("%s %s", name, time.Now().In(p.guildLocation(channel.GuildID)).Format("2006-01-02 15:04")),
+			AutoArchiveDuration: ddtvThreadAutoArchive,
+		})
 		if err != nil {
-			core.Logger.Warnf("Embed sent failed: %v", err)
-			b, _ := json.Marshal(webhook)
-			p.DiscordService.ChannelMessageSendCodeBlock(channel.NotifyChannelID, err.Error()+"\n"+string(b))
+			core.Logger.Warnf("Error starting session thread: %v", err)
 			return
 		}
+		if _, err := p.DataService.Collection(ddtvThreadCollection).InsertOne(context.Background(), ddtvThreadPo{
+			NotifyChannelID: channel.NotifyChannelID,
+			UID:             webhook.Uid,
+			ThreadID:        thread.ID,
+			StartedTime:     time.Now(),
+		}); err != nil {
+			core.Logger.Warnf("Error saving session thread: %v", err)
+		}
+	case ddtv.HookStopLive:
+		if session != nil {
+			p.archiveSessionThread(session)
+		}
 	}
+}
 
+// findActiveThread the session thread of the streamer in the channel not archived yet, or nil.
+func (p *DDTVPlugin) findActiveThread(channelID string, uid int64) (*ddtvThreadPo, error) {
+	var session ddtvThreadPo
+	err := p.DataService.Collection(ddtvThreadCollection).FindOne(context.Background(), &session,
+		data.Where(data.Eq("notify_channel_id", channelID), data.Eq("uid", uid), data.Eq("archived", false)))
+	if errors.Is(err, data.ErrNotFound) {
+		return nil, nil
+	}
+	return &session, err
+}
+
+// archiveSessionThread archive the thread on discord, the session record is kept.
+func (p *DDTVPlugin) archiveSessionThread(session *ddtvThreadPo) {
+	archived := true
+	if _, err := p.DiscordService.Session.ChannelEditComplex(session.ThreadID, &discordgo.ChannelEdit{Archived: &archived}); err != nil {
+		core.Logger.Warnf("Error archiving session thread: %v", err)
+	}
+	session.Archived = true
+	session.ArchivedTime = time.Now()
+	if _, err := p.DataService.Collection(ddtvThreadCollection).UpdateOne(context.Background(), data.ByID(session.BsonID), session, false); err != nil {
+		core.Logger.Warnf("Error saving archived session thread: %v", err)
+	}
 }
 
+func (p *DDTVPlugin) notifyDDTVWebhookToTelegram(channel ddtvNotifyPo, webhook ddtv.WebHook) {
+	if p.TelegramService == nil {
+		// telegram chat saved in a previous run, but telegram is not configured any more.
+		return
+	}
+	chatID, err := telegram.ParseChatID(channel.NotifyChannelID)
+	if err != nil {
+		core.Logger.Warnf("Malformed telegram chat id %s: %v", channel.NotifyChannelID, err)
+		return
+	}
+	if _, err := p.TelegramService.SendMessage(chatID, webhook.DigestText()); err != nil {
+		core.Logger.Warnf("Telegram message sent to chat %d failed: %v", chatID, err)
+	}
+}
+
+const (
+	platformDiscord  = "discord"
+	platformTelegram = "telegram"
+)
+
 type ddtvNotifyPo struct {
-	BsonID             primitive.ObjectID `bson:"_id,omitempty"`
-	AdminDiscordUserID string             `bson:"admin_dc_user_id"`
-	GuildID            string             `bson:"guild_id"`
-	NotifyChannelID    string             `bson:"notify_channel_id"`
-	FeaturedUIDs       []int64            `bson:"featured_uid_list"`
-	FeaturedHookTypes  []int              `bson:"featured_hook_types"`
+	BsonID            primitive.ObjectID `bson:"_id,omitempty"`
+	Platform          string             `bson:"platform"`
+	AdminUserID       string             `bson:"admin_user_id"` // user of the platform who set the channel up
+	GuildID           string             `bson:"guild_id"`      // discord only
+	NotifyChannelID   string             `bson:"notify_channel_id"`
+	FeaturedUIDs      []int64            `bson:"featured_uid_list"`
+	FeaturedHookTypes []int              `bson:"featured_hook_types"`
+	UseThreads        bool               `bson:"use_threads"` // discord only, see followSessionThread
+}
+
+// ddtvThreadPo the thread of a live session of a streamer, in a notify channel using threads.
+type ddtvThreadPo struct {
+	BsonID          primitive.ObjectID `bson:"_id,omitempty"`
+	NotifyChannelID string             `bson:"notify_channel_id"`
+	UID             int64              `bson:"uid"`
+	ThreadID        string             `bson:"thread_id"`
+	Archived        bool               `bson:"archived"`
+	StartedTime     time.Time          `bson:"started_time"`
+	ArchivedTime    time.Time          `bson:"archived_time,omitempty"`
 }
 
-func (p *DDTVPlugin) getCollection() *mongo.Collection {
-	return p.DataService.GetCollection("ddtv_notify_channels")
+// accepts check the webhook against the featured lists. An empty featured list accepts everything.
+func (po ddtvNotifyPo) accepts(webhook ddtv.WebHook) bool {
+	// do NOT display if the user is not in the list
+	if len(po.FeaturedUIDs) != 0 && !slices.Contains(po.FeaturedUIDs, webhook.Uid) {
+		return false
+	}
+	// do NOT display if the webhook type is incorrect
+	if len(po.FeaturedHookTypes) != 0 && !slices.Contains(po.FeaturedHookTypes, webhook.Type.Value()) {
+		return false
+	}
+	return true
+}
+
+// notifyChannelFilter channel ids of different platforms may collide.
+func notifyChannelFilter(platform, channelID string) data.Filter {
+	return data.Where(data.Eq("notify_channel_id", channelID), data.Eq("platform", platform))
+}
+
+const (
+	ddtvNotifyCollection   = "ddtv_notify_channels"
+	ddtvStreamerCollection = "ddtv_streamers"
+	ddtvThreadCollection   = "ddtv_threads"
+	// ddtvThreadAutoArchive minutes of inactivity before discord archives a session thread itself, a day.
+	ddtvThreadAutoArchive = 1440
+)
+
+// ddtvSchema every lookup of notify channels is by channel id and platform.
+var ddtvSchema = data.Schema{
+	Namespace: "ddtv",
+	Migrations: []data.Migration{{
+		Version:     1,
+		Description: "tag notify channels predating the platform field as discord, rename admin_dc_user_id to admin_user_id",
+		Up:          migrateDDTVNotifyPlatform,
+	}},
+	Indexes: []data.Index{{
+		Collection: ddtvNotifyCollection,
+		Name:       "notify_channel_platform",
+		Keys:       []data.SortField{{Field: "notify_channel_id"}, {Field: "platform"}},
+	}, {
+		Collection: ddtvThreadCollection,
+		Name:       "notify_channel_uid_archived",
+		Keys:       []data.SortField{{Field: "notify_channel_id"}, {Field: "uid"}, {Field: "archived"}},
+	}, {
+		Collection: ddtvStreamerCollection,
+		Name:       "uid",
+		Keys:       []data.SortField{{Field: "uid"}},
+		Unique:     true,
+	}},
+}
+
+// migrateDDTVNotifyPlatform see ddtvSchema. The old admin_dc_user_id field is left in place.
+func migrateDDTVNotifyPlatform(ctx context.Context, store data.Store) error {
+	collection := store.Collection(ddtvNotifyCollection)
+	var untagged []bson.M
+	if err := collection.Find(ctx, &untagged, data.Where(data.Exists("platform", false))); err != nil {
+		return err
+	}
+	for _, doc := range untagged {
+		if _, err := collection.UpdateOne(ctx, data.ByID(doc["_id"]), bson.M{"platform": platformDiscord}, false); err != nil {
+			return err
+		}
+	}
+	var unrenamed []bson.M
+	if err := collection.Find(ctx, &unrenamed, data.Where(data.Exists("admin_dc_user_id", true), data.Exists("admin_user_id", false))); err != nil {
+		return err
+	}
+	for _, doc := range unrenamed {
+		if _, err := collection.UpdateOne(ctx, data.ByID(doc["_id"]), bson.M{"admin_user_id": doc["admin_dc_user_id"]}, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ddtvStreamerPo a streamer seen in a webhook.
+type ddtvStreamerPo struct {
+	UID          int64     `bson:"uid"`
+	Uname        string    `bson:"uname"`
+	LastSeenTime time.Time `bson:"last_seen_time"`
+}
+
+// recordStreamer remember the streamer of the webhook and its latest name.
+func (p *DDTVPlugin) recordStreamer(webhook ddtv.WebHook) error {
+	if webhook.Uid == 0 {
+		// hooks about DDTV itself
+		return nil
+	}
+	uname := webhook.RoomInfo.Uname
+	if uname == "" {
+		uname = webhook.UserInfo.Name
+	}
+	_, err := p.DataService.Collection(ddtvStreamerCollection).UpdateOne(context.Background(), data.Where(data.Eq("uid", webhook.Uid)),
+		ddtvStreamerPo{UID: webhook.Uid, Uname: uname, LastSeenTime: time.Now()}, true)
+	return err
+}
+
+// DoAutocomplete suggest streamers seen in webhooks and webhook types by name.
+func (p *DDTVPlugin) DoAutocomplete(_ *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	switch focused.Name {
+	case "uid":
+		var streamers []*ddtvStreamerPo
+		if err := p.DataService.Collection(ddtvStreamerCollection).Find(context.Background(), &streamers, data.Where(),
+			data.FindOptions{Sort: []data.SortField{{Field: "last_seen_time", Descending: true}}}); err != nil {
+			return nil, err
+		}
+		for _, streamer := range streamers {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  fmt.Sprintf("%s (%d)", streamer.Uname, streamer.UID),
+				Value: streamer.UID,
+			})
+		}
+	case "webhook-code":
+		for _, hookType := range ddtv.HookTypes() {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  fmt.Sprintf("%d %s", hookType.Value(), hookType.Name()),
+				Value: hookType.Value(),
+			})
+		}
+	}
+	return discord.FilterChoices(discord.FocusedValue(focused), choices), nil
+}
+
+func (p *DDTVPlugin) getCollection() data.Collection {
+	return p.DataService.Collection(ddtvNotifyCollection)
 }
 
 func (p *DDTVPlugin) findOneWebhookNotifyChannelByChannelID(channelID string) (ddtvNotifyPo, error) {
+	return p.findOneWebhookNotifyChannelOnPlatform(platformDiscord, channelID)
+}
+
+func (p *DDTVPlugin) findOneWebhookNotifyChannelOnPlatform(platform, channelID string) (ddtvNotifyPo, error) {
 	var result ddtvNotifyPo
-	rawResult := p.DataService.FindOne(&result, p.getCollection(), context.Background(), bson.M{"notify_channel_id": channelID})
-	return result, rawResult.Err()
+	err := p.getCollection().FindOne(context.Background(), &result, notifyChannelFilter(platform, channelID))
+	return result, err
 }
 
-func (p *DDTVPlugin) upsertOneWebhookNotifyChannel(po ddtvNotifyPo) (*mongo.UpdateResult, error) {
-	rawResult := p.DataService.UpdateOne(bson.D{{"$set", data.ToBsonDocForce(po)}}, p.getCollection(), context.Background(), bson.M{"notify_channel_id": po.NotifyChannelID}, options.Update().SetUpsert(true))
-	return rawResult.UpdateResult(), rawResult.Err()
+func (p *DDTVPlugin) upsertOneWebhookNotifyChannel(po ddtvNotifyPo) (data.UpdateResult, error) {
+	return p.getCollection().UpdateOne(context.Background(), notifyChannelFilter(po.Platform, po.NotifyChannelID), po, true)
 }
 
-func (p *DDTVPlugin) deleteOneWebhookNotifyChannel(channelID string) (*mongo.DeleteResult, error) {
-	rawResult := p.DataService.DeleteOne(p.getCollection(), context.Background(), bson.M{"notify_channel_id": channelID})
-	return rawResult.DeleteResult(), rawResult.Err()
+func (p *DDTVPlugin) deleteOneWebhookNotifyChannel(channelID string) (int64, error) {
+	return p.deleteOneWebhookNotifyChannelOnPlatform(platformDiscord, channelID)
+}
+
+func (p *DDTVPlugin) deleteOneWebhookNotifyChannelOnPlatform(platform, channelID string) (int64, error) {
+	return p.getCollection().DeleteOne(context.Background(), notifyChannelFilter(platform, channelID))
 }
 
 func (p *DDTVPlugin) findDDTVWebhookNotifyChannelIDs() (channels []string, er error) {
-	var results []ddtvNotifyPo
-	if err := p.DataService.Find(&results, p.getCollection(), context.Background(), bson.M{}); err != nil {
+	results, err := p.fetchDDTVWebhookNotifyChannels()
+	if err != nil {
 		return nil, err
 	}
 	var channelIDs []string
@@ -583,7 +1134,7 @@ func (p *DDTVPlugin) findDDTVWebhookNotifyChannelIDs() (channels []string, er er
 }
 
 func (p *DDTVPlugin) fetchDDTVWebhookNotifyChannels() (channels []ddtvNotifyPo, er error) {
-	if err := p.DataService.Find(&channels, p.getCollection(), context.Background(), bson.M{}); err != nil {
+	if err := p.getCollection().Find(context.Background(), &channels, nil); err != nil {
 		return nil, err
 	}
 	return channels, nil
@@ -591,7 +1142,7 @@ func (p *DDTVPlugin) fetchDDTVWebhookNotifyChannels() (channels []ddtvNotifyPo,
 
 func NewDDTVPlugin(reg *core.ServiceRegistry) core.IPlugin {
 	var ddtvPlugin DDTVPlugin
-	if err := (&ddtvPlugin).Init(reg); err != nil && errors.As(err, &core.ErrServiceFetchUnknownService) {
+	if err := (&ddtvPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
 		core.Logger.Panicf("DDTV plugin MUST have all required service(s) injected!")
 		panic("DDTV plugin initialization failed.")
 	}
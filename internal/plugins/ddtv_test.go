@@ -0,0 +1,292 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/ddtv"
+	"dalian-bot/internal/services/discord/discordtest"
+	"dalian-bot/internal/services/telegram"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func ddtvCommand(group, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return discordtest.SlashCommand("ddtv", discordtest.SubCommandGroup(group, discordtest.SubCommand(sub, options...)))
+}
+
+func ddtvTrigger(webhook ddtv.WebHook) core.Trigger {
+	return core.Trigger{
+		Type:  ddtv.TriggerTypeDDTV,
+		Event: ddtv.Event{EventType: ddtv.EventTypeWebhook, WebHook: webhook},
+	}
+}
+
+func TestDDTVNotifyPoAccepts(t *testing.T) {
+	webhook := ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1}
+	for _, tc := range []struct {
+		name string
+		po   ddtvNotifyPo
+		want bool
+	}{
+		{"empty lists", ddtvNotifyPo{}, true},
+		{"featured uid", ddtvNotifyPo{FeaturedUIDs: []int64{1, 2}}, true},
+		{"other uid", ddtvNotifyPo{FeaturedUIDs: []int64{2}}, false},
+		{"featured hook type", ddtvNotifyPo{FeaturedHookTypes: []int{ddtv.HookStartLive.Value()}}, true},
+		{"other hook type", ddtvNotifyPo{FeaturedHookTypes: []int{ddtv.HookStartRec.Value()}}, false},
+	} {
+		if got := tc.po.accepts(webhook); got != tc.want {
+			t.Errorf("%s: accepts = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDDTVWebhookChannelNotify(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	h.RegisterPlugin(NewDDTVPlugin)
+
+	h.Interact(ddtvCommand("webhook-channel", "set"))
+	if got := h.LastResponse().Content; got != "webhook channel created!" {
+		t.Fatalf("unexpected response: %q", got)
+	}
+	h.Interact(ddtvCommand("webhook-channel", "set"))
+	if got := h.LastResponse().Content; got != "already a webhook channel!" {
+		t.Fatalf("unexpected response: %q", got)
+	}
+
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1}))
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 || len(sends[0].Embeds) != 1 || sends[0].ChannelID != discordtest.ChannelID {
+		t.Fatalf("want one embed sent to the webhook channel, got %+v", sends)
+	}
+
+	// only the featured streamer is notified once the list is not empty
+	h.Interact(ddtvCommand("streamers", "addone-by-uid", discordtest.IntOption("uid", 2)))
+	h.Session.Reset()
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1}))
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 2}))
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 {
+		t.Fatalf("want only the featured streamer notified, got %d sends", len(sends))
+	}
+
+	h.Interact(ddtvCommand("webhook-channel", "remove"))
+	if got := h.LastResponse().Content; got != "webhook channel removed!" {
+		t.Fatalf("unexpected response: %q", got)
+	}
+}
+
+func TestDDTVAutocomplete(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	h.RegisterPlugin(NewDDTVPlugin)
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1, RoomInfo: ddtv.RoomInfo{Uname: "alice"}}))
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 2, RoomInfo: ddtv.RoomInfo{Uname: "bob"}}))
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStopLive, Uid: 1, RoomInfo: ddtv.RoomInfo{Uname: "alice2"}}))
+
+	h.Interact(discordtest.Autocomplete("ddtv", discordtest.SubCommandGroup("streamers",
+		discordtest.SubCommand("addone-by-uid", discordtest.Focus(discordtest.StringOption("uid", "ALI"))))))
+	resp := h.LastResponse()
+	if resp.Response.Type != discordgo.InteractionApplicationCommandAutocompleteResult {
+		t.Fatalf("want an autocomplete result, got %+v", resp.Response)
+	}
+	if choices := resp.Response.Data.Choices; len(choices) != 1 || choices[0].Name != "alice2 (1)" || choices[0].Value != int64(1) {
+		t.Errorf("want the streamer with its latest name, got %+v", choices)
+	}
+
+	h.Interact(discordtest.Autocomplete("ddtv", discordtest.SubCommandGroup("webhooks",
+		discordtest.SubCommand("addone-by-code", discordtest.Focus(discordtest.StringOption("webhook-code", "rec"))))))
+	var names []string
+	for _, choice := range h.LastResponse().Response.Data.Choices {
+		names = append(names, choice.Name)
+	}
+	if fmt.Sprint(names) != "[2 StartRec 3 RecComplete 4 CancelRec]" {
+		t.Errorf("unexpected hook types %v", names)
+	}
+}
+
+func TestDDTVNotifyFailingChannelDoesNotStopOthers(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	dataService := registerTestDataService(t, h)
+	h.RegisterPlugin(NewDDTVPlugin)
+	h.Interact(ddtvCommand("webhook-channel", "set"))
+	if _, err := dataService.Collection(ddtvNotifyCollection).InsertOne(context.Background(),
+		ddtvNotifyPo{Platform: platformDiscord, GuildID: discordtest.GuildID, NotifyChannelID: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Session.FailNext("ChannelMessageSend", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden}})
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1}))
+	embeds, reports := map[string]int{}, 0
+	for _, send := range h.Session.CallsOf("ChannelMessageSend") {
+		if len(send.Embeds) == 1 {
+			embeds[send.ChannelID]++
+		} else {
+			reports++
+		}
+	}
+	// one of the embeds failed and was reported, the other channel was notified all the same
+	if embeds[discordtest.ChannelID] != 1 || embeds["other"] != 1 || reports != 1 {
+		t.Fatalf("want an embed attempt per channel and one failure report, got %v and %d reports", embeds, reports)
+	}
+}
+
+func TestDDTVSessionThreads(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	plugin := h.RegisterPlugin(NewDDTVPlugin).(*DDTVPlugin)
+	h.Interact(ddtvCommand("webhook-channel", "set"))
+	h.Interact(ddtvCommand("webhook-channel", "threads", discordtest.BoolOption("enabled", true)))
+	if got := h.LastResponse().Content; got != "Every live session now gets its own thread." {
+		t.Fatalf("unexpected response: %q", got)
+	}
+
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartLive, Uid: 1, RoomInfo: ddtv.RoomInfo{Uname: "alice"}}))
+	threads := h.Session.CallsOf("MessageThreadStart")
+	if len(threads) != 1 || threads[0].ChannelID != discordtest.ChannelID {
+		t.Fatalf("want a thread on the StartLive notification, got %+v", threads)
+	}
+	threadID := threads[0].Message.ID
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartRec, Uid: 1}))
+	// other streamers stay in the channel
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStartRec, Uid: 2}))
+	h.Trigger(ddtvTrigger(ddtv.WebHook{Type: ddtv.HookStopLive, Uid: 1}))
+
+	var channels []string
+	for _, send := range h.Session.CallsOf("ChannelMessageSend") {
+		channels = append(channels, send.ChannelID)
+	}
+	want := []string{discordtest.ChannelID, threadID, discordtest.ChannelID, threadID}
+	if fmt.Sprint(channels) != fmt.Sprint(want) {
+		t.Errorf("want notifications sent to %v, got %v", want, channels)
+	}
+	if edits := h.Session.CallsOf("ChannelEdit"); len(edits) != 1 || edits[0].ChannelID != threadID {
+		t.Errorf("want the thread archived, got %+v", edits)
+	}
+	if session, err := plugin.findActiveThread(discordtest.ChannelID, 1); err != nil || session != nil {
+		t.Errorf("want no active session left, got %+v (%v)", session, err)
+	}
+}
+
+// fakeTelegramAPI answers getChatMember and records sendMessage texts.
+type fakeTelegramAPI struct {
+	sync.Mutex
+	admins map[int64]bool
+	sent   []string
+}
+
+func (f *fakeTelegramAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&params)
+	f.Lock()
+	defer f.Unlock()
+	var result any
+	switch path.Base(r.URL.Path) {
+	case "getChatMember":
+		status := "member"
+		if f.admins[int64(params["user_id"].(float64))] {
+			status = "administrator"
+		}
+		result = telegram.ChatMember{Status: status}
+	case "sendMessage":
+		f.sent = append(f.sent, params["text"].(string))
+		result = telegram.Message{}
+	}
+	raw, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": json.RawMessage(raw)})
+}
+
+func (f *fakeTelegramAPI) lastSent() string {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.sent) == 0 {
+		return ""
+	}
+	return f.sent[len(f.sent)-1]
+}
+
+func TestDDTVTelegramCommands(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	fake := &fakeTelegramAPI{admins: map[int64]bool{1: true}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	telegramService := &telegram.Service{ServiceConfig: telegram.ServiceConfig{Token: "token", APIEndpoint: server.URL}}
+	if err := telegramService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatal(err)
+	}
+	telegramService.TelegramAccountConfig = core.MessengerConfig{Prefix: "$", Separator: "$"}
+	plugin := h.RegisterPlugin(NewDDTVPlugin).(*DDTVPlugin)
+
+	say := func(userID int64, text string) string {
+		t.Helper()
+		m := &telegram.Message{Chat: telegram.Chat{ID: -100, Type: telegram.ChatTypeSupergroup}, From: &telegram.User{ID: userID}, Text: text}
+		if err := plugin.DoTelegramMessage(m); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		return fake.lastSent()
+	}
+	if got := say(2, "$ddtv set"); got != "Only admins of this chat can do that." {
+		t.Fatalf("member set: %q", got)
+	}
+	if got := say(1, "$ddtv set"); got != "webhook chat created!" {
+		t.Fatalf("admin set: %q", got)
+	}
+	if got := say(1, "$ddtv streamers add 1$2$3"); !strings.Contains(got, "Featured streamers: [1 2 3]") {
+		t.Fatalf("streamers add: %q", got)
+	}
+	if got := say(1, "$ddtv streamers remove 2"); !strings.Contains(got, "Featured streamers: [1 3]") {
+		t.Fatalf("streamers remove: %q", got)
+	}
+	if got := say(1, "$ddtv webhooks set 0$1"); !strings.Contains(got, "Featured webhook types: [0 1]") {
+		t.Fatalf("webhooks set: %q", got)
+	}
+	if got := say(1, "$ddtv streamers add x"); got != `"x" is not a valid number!` {
+		t.Fatalf("invalid uid: %q", got)
+	}
+	// anyone can read the status
+	if got := say(2, "$ddtv status"); !strings.Contains(got, "Featured streamers: [1 3]") {
+		t.Fatalf("member status: %q", got)
+	}
+
+	// a discord channel with the same id is a different record
+	if _, err := plugin.findOneWebhookNotifyChannelByChannelID("-100"); !errors.Is(err, data.ErrNotFound) {
+		t.Errorf("want no discord record, got %v", err)
+	}
+}
+
+func TestDDTVNotifyPlatformMigration(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	dataService := registerTestDataService(t, h)
+	ctx := context.Background()
+	for _, doc := range []bson.M{
+		{"notify_channel_id": discordtest.ChannelID, "admin_dc_user_id": discordtest.UserID},
+		{"platform": platformTelegram, "notify_channel_id": "-100", "admin_dc_user_id": "9"},
+	} {
+		if _, err := dataService.Collection(ddtvNotifyCollection).InsertOne(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plugin := h.RegisterPlugin(NewDDTVPlugin).(*DDTVPlugin)
+	if err := dataService.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	discordPo, err := plugin.findOneWebhookNotifyChannelByChannelID(discordtest.ChannelID)
+	if err != nil || discordPo.AdminUserID != discordtest.UserID {
+		t.Errorf("discord record = %+v (%v)", discordPo, err)
+	}
+	telegramPo, err := plugin.findOneWebhookNotifyChannelOnPlatform(platformTelegram, "-100")
+	if err != nil || telegramPo.AdminUserID != "9" {
+		t.Errorf("telegram record = %+v (%v)", telegramPo, err)
+	}
+}
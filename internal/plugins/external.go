@@ -0,0 +1,95 @@
+package plugins
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
+	"dalian-bot/internal/services/ddtv"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/external"
+	"dalian-bot/internal/services/telegram"
+	"errors"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ExternalPlugin Bridge between the bot and the out-of-process plugins run by external.Service.
+// Discord: slash commands declared by the external plugins are registered on their behalf.
+type ExternalPlugin struct {
+	core.Plugin
+	DiscordService  *discord.Service
+	ExternalService *external.Service
+	discord.SlashCommandUtil
+	commandsLock sync.Mutex
+}
+
+// DoNamedInteraction interactions are routed with every other trigger in Trigger.
+func (p *ExternalPlugin) DoNamedInteraction(_ *core.Bot, _ *discordgo.InteractionCreate) error {
+	return nil
+}
+
+func (p *ExternalPlugin) Init(reg *core.ServiceRegistry) error {
+	// ExternalService is a MUST have. return error if not found.
+	if err := reg.FetchService(&p.ExternalService); err != nil {
+		return err
+	}
+	p.Plugin = core.Plugin{
+		Name: "external",
+		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord, console.TriggerTypeConsole,
+			telegram.TriggerTypeTelegram, ddtv.TriggerTypeDDTV},
+	}
+	p.AppCommandsMap = make(map[string]*discordgo.ApplicationCommand)
+	// discordService is optional, only needed for slash commands.
+	// A restarted plugin may declare other commands, they are synced after every handshake.
+	if err := reg.FetchService(&p.DiscordService); err == nil {
+		p.ExternalService.OnHandshake = p.syncCommands
+	}
+	// every service is online by now, the external plugins can be started.
+	p.ExternalService.Launch()
+	return nil
+}
+
+// syncCommands register the commands of the running plugins, and dispose the ones no plugin declares anymore.
+func (p *ExternalPlugin) syncCommands() {
+	p.commandsLock.Lock()
+	defer p.commandsLock.Unlock()
+	commands := make(discord.AppCommandsMap)
+	for _, cmd := range p.ExternalService.Commands() {
+		commands.RegisterCommand(cmd)
+	}
+	removed := make(discord.AppCommandsMap)
+	for name, cmd := range p.AppCommandsMap {
+		if _, ok := commands[name]; !ok {
+			removed[name] = cmd
+		}
+	}
+	if len(removed) > 0 {
+		p.AppCommandsMap = removed
+		if err := p.DiscordService.DisposeSlashCommand(p); err != nil {
+			core.Logger.Warnf("Error disposing commands of external plugins: %v", err)
+		}
+	}
+	p.AppCommandsMap = commands
+	if len(commands) == 0 {
+		return
+	}
+	if err := p.DiscordService.RegisterSlashCommand(p); err != nil {
+		core.Logger.Warnf("Error registering commands of external plugins: %v", err)
+	}
+}
+
+func (p *ExternalPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	p.ExternalService.Dispatch(trigger)
+}
+
+func NewExternalPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var externalPlugin ExternalPlugin
+	if err := (&externalPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("External plugin MUST have all required service(s) injected!")
+		panic("External plugin initialization failed.")
+	}
+	return &externalPlugin
+}
@@ -0,0 +1,250 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/forward"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ForwardPlugin Manage the endpoints events of a guild are forwarded to.
+// Discord: Related commands are stored in command group `forward`, restricted to guild managers.
+type ForwardPlugin struct {
+	core.Plugin
+	DiscordService *discord.Service
+	ForwardService *forward.Service
+	discord.SlashCommandUtil
+	discord.IDiscordHelper
+	core.ArgParseUtil
+}
+
+func (p *ForwardPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCreate) error {
+	if isMatched, cmdName := p.DefaultMatchCommand(i); !isMatched || cmdName != "forward" {
+		return nil
+	}
+	if i.GuildID == "" {
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Forwarding is configured per guild, run it in a guild channel.")
+	}
+	ctx := context.Background()
+	cmdOption := i.ApplicationCommandData().Options[0]
+	optionsMap := p.ParseOptionsMap(cmdOption.Options)
+	switch cmdOption.Name {
+	case "add":
+		destination := forward.Destination{
+			GuildID:   i.GuildID,
+			URL:       optionsMap["url"].StringValue(),
+			CreatedBy: i.Member.User.ID,
+		}
+		if events, ok := optionsMap["events"]; ok {
+			destination.Events = p.SeparateArgs(events.StringValue(), p.DiscordService.MessengerConfig(i.GuildID).Separator)
+		}
+		if secret, ok := optionsMap["secret"]; ok {
+			destination.Secret = secret.StringValue()
+		}
+		destination, err := p.ForwardService.AddDestination(ctx, destination)
+		if err != nil {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Destination not added: %v", err))
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Destination `%s` added for %s.\r"+
+			"Requests are signed in the `%s` header with HMAC-SHA256 of the body, using this secret: ||%s||",
+			destination.ID.Hex(), describeEvents(destination.Events), forward.HeaderSignature, destination.Secret))
+	case "list":
+		destinations, err := p.ForwardService.Destinations(ctx, i.GuildID)
+		if err != nil {
+			return err
+		}
+		if len(destinations) == 0 {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No destination yet! Add one with */forward add*?")
+		}
+		var lines []string
+		for _, d := range destinations {
+			lines = append(lines, fmt.Sprintf("`%s` %s (%s)", d.ID.Hex(), d.URL, describeEvents(d.Events)))
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, strings.Join(lines, "\r"))
+	case "remove", "test":
+		id, err := primitive.ObjectIDFromHex(optionsMap["id"].StringValue())
+		if err != nil {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Malformed destination id. Check */forward list*?")
+		}
+		if cmdOption.Name == "test" {
+			if err := p.ForwardService.SendPing(i.GuildID, id); err != nil {
+				if errors.Is(err, data.ErrNotFound) {
+					return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No such destination in this guild.")
+				}
+				return err
+			}
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Ping queued! Failures will show up in */forward dead-letters*.")
+		}
+		removed, err := p.ForwardService.RemoveDestination(ctx, i.GuildID, id)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No such destination in this guild.")
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Destination removed!")
+	case "dead-letters":
+		letters, err := p.ForwardService.DeadLetters(ctx, i.GuildID, 10)
+		if err != nil {
+			return err
+		}
+		if len(letters) == 0 {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "No dead letter, every event was delivered.")
+		}
+		var lines []string
+		for _, l := range letters {
+			lines = append(lines, fmt.Sprintf("%s `%s` to %s after %d attempt(s): %s",
+				l.CreatedTime.Format(time.RFC3339), l.Event, l.URL, l.Attempts, l.LastError))
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Latest dead letters:\r"+strings.Join(lines, "\r"))
+	case "redeliver":
+		queued, err := p.ForwardService.Redeliver(ctx, i.GuildID)
+		if err != nil {
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("%d dead letter(s) queued for redelivery.", queued))
+	}
+	return nil
+}
+
+func describeEvents(events []string) string {
+	if len(events) == 0 {
+		return "every event"
+	}
+	return strings.Join(events, ", ")
+}
+
+func (p *ForwardPlugin) Init(reg *core.ServiceRegistry) error {
+	// DiscordService is a MUST have. return error if not found.
+	if err := reg.FetchService(&p.DiscordService); err != nil {
+		return err
+	}
+	// ForwardService is also a MUST have. return error if not found.
+	if err := reg.FetchService(&p.ForwardService); err != nil {
+		return err
+	}
+	p.Plugin = core.Plugin{
+		Name:                 "forward",
+		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord},
+	}
+	p.ArgParseUtil = core.ArgParseUtil{}
+
+	manageGuild := int64(discordgo.PermissionManageServer)
+	dmPermission := false
+	idOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "id",
+		Description: "Destination id, as shown by /forward list.",
+		Required:    true,
+	}
+	p.SlashCommandUtil = discord.SlashCommandUtil{AppCommandsMap: map[string]*discordgo.ApplicationCommand{}}
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name:                     "forward",
+		Description:              "Forward guild events to external endpoints",
+		DefaultMemberPermissions: &manageGuild,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "add",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Forward events of this guild to an endpoint.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "url",
+						Description: "The http(s) endpoint receiving signed JSON POSTs.",
+						Required:    true,
+					},
+					{
+						Type: discordgo.ApplicationCommandOptionString,
+						Name: "events",
+						Description: fmt.Sprintf("Events to forward, separated by [%s]. All of them if absent: %s",
+							p.DiscordService.DiscordAccountConfig.Separator, strings.Join(forward.Events, ", ")),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "secret",
+						Description: "HMAC secret of the signature. Generated if absent.",
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "List the destinations of this guild.",
+			},
+			{
+				Name:        "remove",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Stop forwarding to a destination.",
+				Options:     []*discordgo.ApplicationCommandOption{idOption},
+			},
+			{
+				Name:        "test",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Send a ping event to a destination.",
+				Options:     []*discordgo.ApplicationCommandOption{idOption},
+			},
+			{
+				Name:        "dead-letters",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Show the latest events that could not be delivered.",
+			},
+			{
+				Name:        "redeliver",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Try delivering every dead letter again.",
+			},
+		},
+	})
+
+	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
+		PluginHelp: "Forward guild events to external endpoints as signed JSON.",
+		CommandHelps: []discord.CommandHelp{
+			{
+				Name: "forward add",
+				FormattedHelp: `*forward add*: /forward add url [events] [secret]
+POST the events of this guild to the url. Each request carries ` + "`" + forward.HeaderSignature + "`" + `: sha256=<hex HMAC-SHA256 of the body>.
+Failed deliveries are retried with backoff, then kept as dead letters.`,
+			},
+			{
+				Name: "forward dead-letters",
+				FormattedHelp: `*forward dead-letters*: /forward dead-letters
+Show the latest events given up on. Use */forward redeliver* to try them again.`,
+			},
+		},
+	})
+	return p.DiscordService.RegisterSlashCommand(p)
+}
+
+func (p *ForwardPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	discordEvent := discord.UnboxEvent(trigger)
+	if discordEvent.EventType != discord.EventTypeInteractionCreate ||
+		discordEvent.InteractionCreate.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	if err := p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate); err != nil {
+		core.Logger.Warnf("Error executing slash command: %v", err)
+		p.DiscordService.InteractionRespondEphemeral(discordEvent.InteractionCreate.Interaction, "Internal error! Please contact admin for help.")
+	}
+}
+
+func NewForwardPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var forwardPlugin ForwardPlugin
+	if err := (&forwardPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("Forward plugin MUST have all required service(s) injected!")
+		panic("Forward plugin initialization failed.")
+	}
+	return &forwardPlugin
+}
@@ -0,0 +1,52 @@
+package plugins
+
+import (
+	"context"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord/discordtest"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// envTestMongoURI run database flows against mongo instead of the file backend when set.
+const envTestMongoURI = "DALIAN_TEST_MONGO_URI"
+
+// registerTestDataService register and start a data.Service backed by a throwaway store.
+func registerTestDataService(t *testing.T, h *discordtest.Harness) *data.Service {
+	t.Helper()
+	dataService := &data.Service{ServiceConfig: data.ServiceConfig{
+		URI:      os.Getenv(envTestMongoURI),
+		Database: fmt.Sprintf("dalian_test_%d", time.Now().UnixNano()),
+		Path:     filepath.Join(t.TempDir(), "dalian.db"),
+	}}
+	if err := dataService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatalf("data service init failed: %v", err)
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	dataService.Start(wg)
+	t.Cleanup(func() {
+		if mongoStore, ok := dataService.Store.(*data.MongoStore); ok {
+			mongoStore.Database.Drop(context.Background())
+		}
+		wg.Add(1)
+		dataService.Stop(wg)
+	})
+	return dataService
+}
+
+// waitFor poll the condition until it's met or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
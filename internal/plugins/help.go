@@ -2,17 +2,22 @@ package plugins
 
 import (
 	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/discord"
 	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strings"
 )
 
 // HelpPlugin Plugin for collecting help info of registered commands.
 // Discord: can be triggered by `$help` or `/help`
+// Console: can be triggered by `$help`
 type HelpPlugin struct {
 	core.Plugin                               // basic plugin basetype
-	DiscordService           *discord.Service // currently support discord
+	DiscordService           *discord.Service // discord support
+	ConsoleService           *console.Service // console support
 	core.StartWithMatchUtil                   // plain message support
 	core.ArgParseUtil                         // command argument support
 	discord.SlashCommandUtil                  // discord slash command support
@@ -39,6 +44,20 @@ func (p *HelpPlugin) DoNamedInteraction(b *core.Bot, i *discordgo.InteractionCre
 	return nil
 }
 
+// DoAutocomplete suggest the commands with a help.
+func (p *HelpPlugin) DoAutocomplete(_ *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	var names []string
+	for _, helper := range p.DiscordService.Helpers() {
+		names = append(names, helper.DiscordCommandNames()...)
+	}
+	sort.Strings(names)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range names {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	return discord.FilterChoices(discord.FocusedValue(focused), choices), nil
+}
+
 // parseHelpText browse through all plugins registered with bot and match help texts available.
 func parseHelpText(b *core.Bot, commandName string) string {
 	helpText := ""
@@ -68,8 +87,9 @@ func parseHelpText(b *core.Bot, commandName string) string {
 
 // DoPlainMessage `$help [command-name]` support
 func (p *HelpPlugin) DoPlainMessage(b *core.Bot, m *discordgo.MessageCreate) (err error) {
-	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, p.DiscordService.DiscordAccountConfig); matched {
-		args := p.ArgParseUtil.SeparateArgs(m.Content, p.DiscordService.DiscordAccountConfig.Separator)
+	config := p.DiscordService.MessengerConfig(m.GuildID)
+	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, config); matched {
+		args := p.ArgParseUtil.SeparateArgs(m.Content, config.Separator)
 		if len(args) == 1 {
 			p.DiscordService.ChannelMessageSend(m.ChannelID, parseHelpText(b, ""))
 		} else {
@@ -79,14 +99,36 @@ func (p *HelpPlugin) DoPlainMessage(b *core.Bot, m *discordgo.MessageCreate) (er
 	return nil
 }
 
+// DoConsoleMessage `$help [command-name]` support for console
+func (p *HelpPlugin) DoConsoleMessage(b *core.Bot, m *console.Message) (err error) {
+	config := p.ConsoleService.ConsoleAccountConfig
+	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, config); matched {
+		args := p.ArgParseUtil.SeparateArgs(m.Content, config.Separator)
+		if len(args) == 1 {
+			return p.ConsoleService.Reply(m, parseHelpText(b, ""))
+		}
+		return p.ConsoleService.Reply(m, parseHelpText(b, strings.Join(args[1:], " ")))
+	}
+	return nil
+}
+
 func (p *HelpPlugin) Init(reg *core.ServiceRegistry) error {
-	//discordService is a MUST have. return error if not found.
-	if err := reg.FetchService(&p.DiscordService); err != nil {
-		return err
+	//discordService and consoleService are both optional, but at least one of them is required.
+	discordErr := reg.FetchService(&p.DiscordService)
+	if err := reg.FetchService(&p.ConsoleService); err != nil && discordErr != nil {
+		return discordErr
 	}
-	p.AcceptedTriggerTypes = []core.TriggerType{discord.TriggerTypeDiscord}
 	p.Name = "help"
 	p.Identifiers = []string{"help"}
+	prefix := "$"
+	if p.ConsoleService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, console.TriggerTypeConsole)
+		prefix = p.ConsoleService.ConsoleAccountConfig.Prefix
+	}
+	if p.DiscordService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, discord.TriggerTypeDiscord)
+		prefix = p.DiscordService.DiscordAccountConfig.Prefix
+	}
 	p.AppCommandsMap = make(map[string]*discordgo.ApplicationCommand)
 	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
 		Name:        "help",
@@ -96,8 +138,9 @@ func (p *HelpPlugin) Init(reg *core.ServiceRegistry) error {
 				Type: discordgo.ApplicationCommandOptionString,
 				Name: "command-name",
 				//late init, replace %s with separator
-				Description: "Name of the command.",
-				Required:    false,
+				Description:  "Name of the command.",
+				Required:     false,
+				Autocomplete: true,
 			},
 		},
 	})
@@ -108,7 +151,7 @@ func (p *HelpPlugin) Init(reg *core.ServiceRegistry) error {
 *Optional Argument*: [command-name]
 Display the help message.
 If command-name not provided, list the names of all available commands; Otherwise, provide detailed explaination of the specific command.`,
-		p.DiscordService.DiscordAccountConfig.Prefix)
+		prefix)
 	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
 		PluginHelp: "HelperUtil support for Dalian.",
 		CommandHelps: []discord.CommandHelp{
@@ -118,6 +161,9 @@ If command-name not provided, list the names of all available commands; Otherwis
 			},
 		},
 	})
+	if p.DiscordService == nil {
+		return nil
+	}
 	return p.DiscordService.RegisterSlashCommand(p)
 }
 
@@ -125,23 +171,36 @@ func (p *HelpPlugin) Trigger(trigger core.Trigger) {
 	if !p.AcceptTrigger(trigger.Type) {
 		return
 	}
-	discordEvent := discord.UnboxEvent(trigger)
-	switch discordEvent.EventType {
-	case discord.EventTypeMessageCreate:
-		if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+	switch trigger.Type {
+	case discord.TriggerTypeDiscord:
+		discordEvent := discord.UnboxEvent(trigger)
+		switch discordEvent.EventType {
+		case discord.EventTypeMessageCreate:
+			if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+				return
+			}
+			p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
+		case discord.EventTypeInteractionCreate:
+			p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate)
+		case discord.EventTypeMessageCommand:
+			// message commands of other plugins
+			return
+		case discord.EventTypeMessageReactionAdd:
+			// reactions page the text pagers of other plugins
 			return
+		default:
+			core.Logger.Warnf("This should NOT reach!")
 		}
-		p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
-	case discord.EventTypeInteractionCreate:
-		p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate)
+	case console.TriggerTypeConsole:
+		p.DoConsoleMessage(trigger.Bot, console.UnboxEvent(trigger).Message)
 	default:
-		core.Logger.Warnf("This should NOT reach!")
+		core.Logger.Warnf(core.LogPromptUnknownTrigger, trigger.Type)
 	}
 }
 
 func NewHelpPlugin(reg *core.ServiceRegistry) core.IPlugin {
 	var help HelpPlugin
-	if err := (&help).Init(reg); err != nil && errors.As(err, &core.ErrServiceFetchUnknownService) {
+	if err := (&help).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
 		core.Logger.Panicf("Help plugin MUST have all required service(s) injected!")
 		panic("Help plugin initialization failed.")
 	}
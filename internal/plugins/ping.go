@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/discord"
 	"errors"
 	"fmt"
@@ -10,9 +11,11 @@ import (
 
 // PingPlugin Basic ping support.
 // Discord: can be trigggered by `$ping`, `/ping`
+// Console: can be triggered by `$ping`
 type PingPlugin struct {
 	core.Plugin
 	DiscordService *discord.Service
+	ConsoleService *console.Service
 	core.StartWithMatchUtil
 	discord.SlashCommandUtil
 	discord.IDiscordHelper
@@ -38,28 +41,44 @@ func (p *PingPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCre
 }
 
 func (p *PingPlugin) DoPlainMessage(_ *core.Bot, m *discordgo.MessageCreate) error {
-	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, p.DiscordService.DiscordAccountConfig); matched {
+	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, p.DiscordService.MessengerConfig(m.GuildID)); matched {
 		p.DiscordService.ChannelMessageSend(m.ChannelID, "Pong!")
 	}
 	return nil
 }
 
+func (p *PingPlugin) DoConsoleMessage(_ *core.Bot, m *console.Message) error {
+	if matched, _ := p.StartWithMatchUtil.MatchText(m.Content, p.ConsoleService.ConsoleAccountConfig); matched {
+		return p.ConsoleService.Reply(m, "Pong!")
+	}
+	return nil
+}
+
 func (p *PingPlugin) Init(reg *core.ServiceRegistry) error {
-	//discordService is a MUST have. return error if not found.
-	if err := reg.FetchService(&p.DiscordService); err != nil {
-		return err
+	//discordService and consoleService are both optional, but at least one of them is required.
+	discordErr := reg.FetchService(&p.DiscordService)
+	if err := reg.FetchService(&p.ConsoleService); err != nil && discordErr != nil {
+		return discordErr
 	}
 
-	p.AcceptedTriggerTypes = []core.TriggerType{discord.TriggerTypeDiscord}
 	p.Name = "ping"
 	p.Identifiers = []string{"ping"}
+	prefix := "$"
+	if p.ConsoleService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, console.TriggerTypeConsole)
+		prefix = p.ConsoleService.ConsoleAccountConfig.Prefix
+	}
+	if p.DiscordService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, discord.TriggerTypeDiscord)
+		prefix = p.DiscordService.DiscordAccountConfig.Prefix
+	}
 	p.AppCommandsMap = make(map[string]*discordgo.ApplicationCommand)
 	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
 		Name:        "ping",
 		Description: "Ping command for Dalian",
 	})
 
-	formattedPingHelp := fmt.Sprintf("*Call*: /ping,%sping\rrespond a \"pong\"", p.DiscordService.DiscordAccountConfig.Prefix)
+	formattedPingHelp := fmt.Sprintf("*Call*: /ping,%sping\rrespond a \"pong\"", prefix)
 	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
 		PluginHelp: "Basic ping command for dalian over Discord.",
 		CommandHelps: []discord.CommandHelp{
@@ -70,6 +89,9 @@ func (p *PingPlugin) Init(reg *core.ServiceRegistry) error {
 		},
 	})
 
+	if p.DiscordService == nil {
+		return nil
+	}
 	return p.DiscordService.RegisterSlashCommand(p)
 }
 
@@ -77,23 +99,36 @@ func (p *PingPlugin) Trigger(trigger core.Trigger) {
 	if !p.AcceptTrigger(trigger.Type) {
 		return
 	}
-	discordEvent := discord.UnboxEvent(trigger) // not checking because only accept discord.
-	switch discordEvent.EventType {
-	case discord.EventTypeMessageCreate:
-		if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+	switch trigger.Type {
+	case discord.TriggerTypeDiscord:
+		discordEvent := discord.UnboxEvent(trigger)
+		switch discordEvent.EventType {
+		case discord.EventTypeMessageCreate:
+			if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+				return
+			}
+			p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
+		case discord.EventTypeInteractionCreate:
+			p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate)
+		case discord.EventTypeMessageCommand:
+			// message commands of other plugins
+			return
+		case discord.EventTypeMessageReactionAdd:
+			// reactions page the text pagers of other plugins
 			return
+		default:
+			core.Logger.Warnf("This should NOT reach!")
 		}
-		p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
-	case discord.EventTypeInteractionCreate:
-		p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate)
+	case console.TriggerTypeConsole:
+		p.DoConsoleMessage(trigger.Bot, console.UnboxEvent(trigger).Message)
 	default:
-		core.Logger.Warnf("This should NOT reach!")
+		core.Logger.Warnf(core.LogPromptUnknownTrigger, trigger.Type)
 	}
 }
 
 func NewPingPlugin(reg *core.ServiceRegistry) core.IPlugin {
 	var ping PingPlugin
-	if err := (&ping).Init(reg); err != nil && errors.As(err, &core.ErrServiceFetchUnknownService) {
+	if err := (&ping).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
 		core.Logger.Panicf("Ping plugin MUST have all required service(s) injected!")
 		panic("Ping plugin initialization failed.")
 	}
@@ -0,0 +1,8 @@
+package plugins
+
+import "dalian-bot/internal/services/data"
+
+// Schemas Storage schemas of every plugin, for managing the database without starting the plugins.
+func Schemas() []data.Schema {
+	return []data.Schema{archiveSchema, ddtvSchema, customCmdSchema}
+}
@@ -0,0 +1,220 @@
+package plugins
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/settings"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SettingsPlugin Guild settings: prefix, separator, locale, timezone and default notification channel.
+// Discord: Managed with command group `settings`, by guild managers.
+type SettingsPlugin struct {
+	core.Plugin
+	DiscordService  *discord.Service
+	SettingsService *settings.Service
+	discord.SlashCommandUtil
+	discord.IDiscordHelper
+}
+
+func (p *SettingsPlugin) DoNamedInteraction(_ *core.Bot, i *discordgo.InteractionCreate) error {
+	if isMatched, cmdName := p.DefaultMatchCommand(i); !isMatched || cmdName != "settings" {
+		return nil
+	}
+	if i.GuildID == "" {
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Settings belong to a guild, run it in a guild channel.")
+	}
+	gs, err := p.SettingsService.Get(i.GuildID)
+	if err != nil {
+		return err
+	}
+	cmdOption := i.ApplicationCommandData().Options[0]
+	optionsMap := p.ParseOptionsMap(cmdOption.Options)
+	switch cmdOption.Name {
+	case "show":
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, p.describe(gs))
+	case "set":
+		if len(optionsMap) == 0 {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Nothing to set! Pick at least one option.")
+		}
+		if prefix, ok := optionsMap["prefix"]; ok {
+			gs.Prefix = prefix.StringValue()
+		}
+		if separator, ok := optionsMap["separator"]; ok {
+			gs.Separator = separator.StringValue()
+		}
+		if locale, ok := optionsMap["locale"]; ok {
+			gs.Locale = locale.StringValue()
+		}
+		if timezone, ok := optionsMap["timezone"]; ok {
+			gs.Timezone = timezone.StringValue()
+		}
+		if channel, ok := optionsMap["notify-channel"]; ok {
+			gs.NotifyChannelID = channel.ChannelValue(nil).ID
+		}
+		gs.UpdatedBy = i.Member.User.ID
+		if err := gs.Validate(); err != nil {
+			return p.DiscordService.InteractionRespondEphemeral(i.Interaction, fmt.Sprintf("Settings not saved: %v.", err))
+		}
+		if err := p.SettingsService.Save(gs); err != nil {
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Settings saved!\r"+p.describe(gs))
+	case "reset":
+		if err := p.SettingsService.Reset(i.GuildID); err != nil {
+			return err
+		}
+		return p.DiscordService.InteractionRespondEphemeral(i.Interaction, "Settings are back to the defaults!\r"+p.describe(settings.GuildSettings{GuildID: i.GuildID}))
+	}
+	return nil
+}
+
+// describe the effective settings, defaults included.
+func (p *SettingsPlugin) describe(gs settings.GuildSettings) string {
+	config := p.DiscordService.MessengerConfig(gs.GuildID)
+	orDefault := func(value, fallback string) string {
+		if value == "" {
+			return fallback + " (default)"
+		}
+		return value
+	}
+	notifyChannel := "none"
+	if gs.NotifyChannelID != "" {
+		notifyChannel = fmt.Sprintf("<#%s>", gs.NotifyChannelID)
+	}
+	return strings.Join([]string{
+		fmt.Sprintf("**Prefix**: `%s`", config.Prefix),
+		fmt.Sprintf("**Separator**: `%s`", config.Separator),
+		fmt.Sprintf("**Locale**: %s", orDefault(gs.Locale, "en-US")),
+		fmt.Sprintf("**Timezone**: %s", orDefault(gs.Timezone, "the one of the bot")),
+		fmt.Sprintf("**Notification channel**: %s", notifyChannel),
+	}, "\r")
+}
+
+// DoAutocomplete suggest discord locales.
+func (p *SettingsPlugin) DoAutocomplete(_ *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if focused.Name != "locale" {
+		return nil, nil
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for locale, name := range discordgo.Locales {
+		if locale == discordgo.Unknown {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: fmt.Sprintf("%s %s", locale, name), Value: string(locale)})
+	}
+	sort.Slice(choices, func(i, j int) bool { return choices[i].Name < choices[j].Name })
+	return discord.FilterChoices(discord.FocusedValue(focused), choices), nil
+}
+
+func (p *SettingsPlugin) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&p.DiscordService); err != nil {
+		return err
+	}
+	if err := reg.FetchService(&p.SettingsService); err != nil {
+		return err
+	}
+	p.Plugin = core.Plugin{
+		Name:                 "settings",
+		AcceptedTriggerTypes: []core.TriggerType{discord.TriggerTypeDiscord},
+	}
+
+	manageGuild := int64(discordgo.PermissionManageServer)
+	dmPermission := false
+	p.SlashCommandUtil = discord.SlashCommandUtil{AppCommandsMap: map[string]*discordgo.ApplicationCommand{}}
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name:                     "settings",
+		Description:              "Manage the settings of this guild",
+		DefaultMemberPermissions: &manageGuild,
+		DMPermission:             &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "show",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Display the settings of this guild.",
+			},
+			{
+				Name:        "set",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Change some settings of this guild.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "prefix",
+						Description: fmt.Sprintf("Prefix of text commands, at most %d characters.", settings.PrefixLimit),
+						MaxLength:   settings.PrefixLimit,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "separator",
+						Description: fmt.Sprintf("Separator of values such as tags, at most %d characters.", settings.SeparatorLimit),
+						MaxLength:   settings.SeparatorLimit,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "locale",
+						Description:  "Language of the guild, e.g. en-US.",
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "Timezone of displayed times, e.g. Asia/Shanghai.",
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "notify-channel",
+						Description:  "Default channel for notifications of the bot.",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+				},
+			},
+			{
+				Name:        "reset",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Description: "Go back to the defaults of the bot.",
+			},
+		},
+	})
+
+	p.IDiscordHelper = discord.GenerateHelper(discord.HelperConfig{
+		PluginHelp: "Settings of the guild, editable by guild managers.",
+		CommandHelps: []discord.CommandHelp{
+			{
+				Name: "settings",
+				FormattedHelp: "*settings show*: /settings show\rdisplay the settings of this guild\r" +
+					"*settings set*: /settings set [prefix] [separator] [locale] [timezone] [notify-channel]\r" +
+					"change the prefix and separator of text commands, the locale, the timezone of displayed times and the default notification channel\r" +
+					"*settings reset*: /settings reset\rgo back to the defaults of the bot",
+			},
+		},
+	})
+	return p.DiscordService.RegisterSlashCommand(p)
+}
+
+func (p *SettingsPlugin) Trigger(trigger core.Trigger) {
+	if !p.AcceptTrigger(trigger.Type) {
+		return
+	}
+	discordEvent := discord.UnboxEvent(trigger)
+	if discordEvent.EventType != discord.EventTypeInteractionCreate ||
+		discordEvent.InteractionCreate.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	if err := p.DoNamedInteraction(trigger.Bot, discordEvent.InteractionCreate); err != nil {
+		core.Logger.Warnf("Error executing settings command: %v", err)
+	}
+}
+
+func NewSettingsPlugin(reg *core.ServiceRegistry) core.IPlugin {
+	var settingsPlugin SettingsPlugin
+	if err := (&settingsPlugin).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
+		core.Logger.Panicf("Settings plugin MUST have all required service(s) injected!")
+	}
+	return &settingsPlugin
+}
@@ -0,0 +1,49 @@
+package plugins
+
+import (
+	"dalian-bot/internal/services/discord/discordtest"
+	"dalian-bot/internal/services/settings"
+	"strings"
+	"testing"
+)
+
+func TestSettingsOverrideGuildPrefix(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	registerTestDataService(t, h)
+	settingsService := &settings.Service{}
+	if err := settingsService.Init(h.Bot.ServiceRegistry); err != nil {
+		t.Fatalf("settings service init failed: %v", err)
+	}
+	h.RegisterPlugin(NewSettingsPlugin)
+	h.RegisterPlugin(NewPingPlugin)
+
+	h.Interact(discordtest.SlashCommand("settings", discordtest.SubCommand("set",
+		discordtest.StringOption("prefix", "!"), discordtest.StringOption("timezone", "Asia/Shanghai"))))
+	if got := h.LastResponse().Response.Data.Content; !strings.Contains(got, "**Prefix**: `!`") || !strings.Contains(got, "Asia/Shanghai") {
+		t.Fatalf("unexpected response: %q", got)
+	}
+	if loc := settingsService.Location(discordtest.GuildID); loc.String() != "Asia/Shanghai" {
+		t.Fatalf("unexpected location: %v", loc)
+	}
+
+	h.Session.Reset()
+	h.Say(discordtest.ChannelID, "$ping")
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("default prefix still answered: %v", sends)
+	}
+	h.Say(discordtest.ChannelID, "!ping")
+	if got := lastSend(t, h); got != "Pong!" {
+		t.Fatalf("guild prefix not answered: %q", got)
+	}
+
+	h.Interact(discordtest.SlashCommand("settings", discordtest.SubCommand("set", discordtest.StringOption("timezone", "Mars/Olympus"))))
+	if got := h.LastResponse().Response.Data.Content; got != "Settings not saved: unknown timezone Mars/Olympus." {
+		t.Fatalf("unexpected response: %q", got)
+	}
+
+	h.Interact(discordtest.SlashCommand("settings", discordtest.SubCommand("reset")))
+	h.Say(discordtest.ChannelID, "$ping")
+	if got := lastSend(t, h); got != "Pong!" {
+		t.Fatalf("default prefix not restored: %q", got)
+	}
+}
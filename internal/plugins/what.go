@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
 	"dalian-bot/internal/services/discord"
 	"errors"
 	"fmt"
@@ -11,9 +12,11 @@ import (
 
 // WhatPlugin A for-fun function that will repeat the last message.
 // Discord: Can be triggered by `what`
+// Console: Can be triggered by `what`
 type WhatPlugin struct {
 	core.Plugin
 	DiscordService *discord.Service
+	ConsoleService *console.Service
 	core.RegexMatchUtil
 }
 
@@ -30,7 +33,7 @@ func (p *WhatPlugin) DoPlainMessage(_ *core.Bot, m *discordgo.MessageCreate) (er
 			}
 			msg, foundNonBot := discord.FindFirstNonBotMsg(msgs)
 			if foundNonBot {
-				p.DiscordService.Session.ChannelMessageSend(msg.ChannelID, fmt.Sprintf("**%s**", msg.Content))
+				p.DiscordService.ChannelMessageSend(msg.ChannelID, fmt.Sprintf("**%s**", msg.Content))
 				return nil
 			}
 			step *= 2
@@ -39,12 +42,39 @@ func (p *WhatPlugin) DoPlainMessage(_ *core.Bot, m *discordgo.MessageCreate) (er
 	return nil
 }
 
+func (p *WhatPlugin) DoConsoleMessage(_ *core.Bot, m *console.Message) (err error) {
+	if matchStatus, _ := p.RegMatchMessage(m.Content); !matchStatus {
+		return nil
+	}
+	step := 2
+	for {
+		msgs := p.ConsoleService.ChannelMessages(m.ChannelID, step, m.ID)
+		for _, msg := range msgs {
+			if !msg.Bot {
+				return p.ConsoleService.Reply(m, fmt.Sprintf("**%s**", msg.Content))
+			}
+		}
+		if len(msgs) < step {
+			// reached the beginning of history
+			return nil
+		}
+		step *= 2
+	}
+}
+
 func (p *WhatPlugin) Init(reg *core.ServiceRegistry) error {
-	if err := reg.FetchService(&p.DiscordService); err != nil {
-		return err
+	//discordService and consoleService are both optional, but at least one of them is required.
+	discordErr := reg.FetchService(&p.DiscordService)
+	if err := reg.FetchService(&p.ConsoleService); err != nil && discordErr != nil {
+		return discordErr
 	}
 
-	p.AcceptedTriggerTypes = []core.TriggerType{discord.TriggerTypeDiscord}
+	if p.DiscordService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, discord.TriggerTypeDiscord)
+	}
+	if p.ConsoleService != nil {
+		p.AcceptedTriggerTypes = append(p.AcceptedTriggerTypes, console.TriggerTypeConsole)
+	}
 	p.Name = "what"
 	p.RegexExpressions = []*regexp.Regexp{regexp.MustCompile("^what$")}
 	return nil
@@ -54,24 +84,29 @@ func (p *WhatPlugin) Trigger(trigger core.Trigger) {
 	if !p.AcceptTrigger(trigger.Type) {
 		return
 	}
-	// only accept discord so far, so not using switch.
-	// example of accepting other triggers can be found at:
-	discordEvent := discord.UnboxEvent(trigger)
-	switch discordEvent.EventType {
-	case discord.EventTypeMessageCreate:
-		if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+	switch trigger.Type {
+	case discord.TriggerTypeDiscord:
+		discordEvent := discord.UnboxEvent(trigger)
+		switch discordEvent.EventType {
+		case discord.EventTypeMessageCreate:
+			if p.DiscordService.IsGuildMessageFromBotOrSelf(discordEvent.MessageCreate.Message) {
+				return
+			}
+			p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
+		default:
+			//not handling any other type of discordEvent.
 			return
 		}
-		p.DoPlainMessage(trigger.Bot, discordEvent.MessageCreate)
+	case console.TriggerTypeConsole:
+		p.DoConsoleMessage(trigger.Bot, console.UnboxEvent(trigger).Message)
 	default:
-		//not handling any other type of discordEvent.
-		return
+		core.Logger.Warnf(core.LogPromptUnknownTrigger, trigger.Type)
 	}
 }
 
 func NewWhatPlugin(reg *core.ServiceRegistry) core.IPlugin {
 	var what WhatPlugin
-	if err := (&what).Init(reg); err != nil && errors.As(err, &core.ErrServiceFetchUnknownService) {
+	if err := (&what).Init(reg); err != nil && errors.Is(err, core.ErrServiceFetchUnknownService) {
 		core.Logger.Panicf("What plugin MUST have all required service(s) injected!")
 	}
 	return &what
@@ -0,0 +1,14 @@
+package console
+
+import "dalian-bot/internal/core"
+
+const TriggerTypeConsole core.TriggerType = "console"
+
+const (
+	// ChannelStdin channel id of messages read from Input.
+	ChannelStdin = "stdin"
+	// AuthorBot author of replies written by Dalian.
+	AuthorBot = "dalian"
+	// historyLimit max number of messages kept per channel.
+	historyLimit = 200
+)
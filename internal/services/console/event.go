@@ -0,0 +1,21 @@
+package console
+
+import (
+	"dalian-bot/internal/core"
+)
+
+type EventType string
+
+const (
+	EventTypeMessage EventType = "message"
+)
+
+type Event struct {
+	EventType EventType
+	Message   *Message
+}
+
+func UnboxEvent(t core.Trigger) Event {
+	var e = t.Event.(Event)
+	return e
+}
@@ -0,0 +1,208 @@
+package console
+
+import (
+	"bufio"
+	"dalian-bot/internal/core"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Service Local trigger source for running plugins without Discord.
+// Every line read from Input, or from a connection of the unix socket at SocketPath, becomes a message
+// trigger. Replies of plugins are written back to where the line came from.
+type Service struct {
+	ServiceConfig
+	core.TriggerableEmbedUtil
+	ConsoleAccountConfig core.MessengerConfig
+	listener             net.Listener
+	lastID               int64
+	historyLock          sync.RWMutex
+	history              map[string][]*Message // channelID : messages, oldest first
+	outputs              map[string]io.Writer  // channelID : where replies go
+	running              atomic.Bool
+}
+
+type ServiceConfig struct {
+	// SocketPath listen on a unix socket instead of reading Input.
+	SocketPath string
+	// Input defaults to os.Stdin
+	Input io.Reader
+	// Output defaults to os.Stdout
+	Output io.Writer
+}
+
+// Message A single line, either sent to or by Dalian.
+type Message struct {
+	ID        int64
+	ChannelID string
+	Author    string
+	Content   string
+	Bot       bool
+}
+
+func (s *Service) Name() string {
+	return "console"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if s.Input == nil {
+		s.Input = os.Stdin
+	}
+	if s.Output == nil {
+		s.Output = os.Stdout
+	}
+	s.history = make(map[string][]*Message)
+	s.outputs = make(map[string]io.Writer)
+	s.ConsoleAccountConfig = core.MessengerConfig{
+		Prefix:    "$",
+		Separator: " ",
+		BotID:     AuthorBot,
+	}
+	return reg.RegisterService(s)
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	defer wg.Done()
+	if s.SocketPath != "" {
+		// remove the stale socket left by an unclean shutdown.
+		_ = os.Remove(s.SocketPath)
+		listener, err := net.Listen("unix", s.SocketPath)
+		if err != nil {
+			core.Logger.Errorf("error listening console socket [%s]: %v", s.SocketPath, err)
+			return
+		}
+		s.listener = listener
+		go s.acceptLoop()
+	} else {
+		s.outputs[ChannelStdin] = s.Output
+		go s.readLoop(ChannelStdin, s.Input)
+	}
+	s.running.Store(true)
+	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
+}
+
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	s.running.Store(false)
+	if s.listener != nil {
+		s.listener.Close()
+		_ = os.Remove(s.SocketPath)
+	}
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	wg.Done()
+	return nil
+}
+
+func (s *Service) Status() error {
+	if !s.running.Load() {
+		return errors.New("console is not running")
+	}
+	return nil
+}
+
+func (s *Service) acceptLoop() {
+	connCount := 0
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.running.Load() {
+				core.Logger.Warnf("console socket accept failed: %v", err)
+			}
+			return
+		}
+		connCount++
+		channelID := fmt.Sprintf("socket-%d", connCount)
+		s.historyLock.Lock()
+		s.outputs[channelID] = conn
+		s.historyLock.Unlock()
+		go func() {
+			defer conn.Close()
+			s.readLoop(channelID, conn)
+			s.historyLock.Lock()
+			delete(s.outputs, channelID)
+			s.historyLock.Unlock()
+		}()
+	}
+}
+
+// readLoop turn every non-empty line into a Trigger until r is exhausted.
+func (s *Service) readLoop(channelID string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m := s.record(channelID, "console", line, false)
+		s.SendTrigger(core.Trigger{
+			Type: TriggerTypeConsole,
+			Event: Event{
+				EventType: EventTypeMessage,
+				Message:   m,
+			},
+		})
+	}
+}
+
+// record append a message to the history of the channel.
+func (s *Service) record(channelID, author, content string, bot bool) *Message {
+	m := &Message{
+		ID:        atomic.AddInt64(&s.lastID, 1),
+		ChannelID: channelID,
+		Author:    author,
+		Content:   content,
+		Bot:       bot,
+	}
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+	history := append(s.history[channelID], m)
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	s.history[channelID] = history
+	return m
+}
+
+// Reply print the content to the origin of m.
+func (s *Service) Reply(m *Message, content string) error {
+	return s.ChannelMessageSend(m.ChannelID, content)
+}
+
+// ChannelMessageSend print the content to the given console channel.
+func (s *Service) ChannelMessageSend(channelID, content string) error {
+	s.historyLock.RLock()
+	w, ok := s.outputs[channelID]
+	s.historyLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("console channel %s is closed", channelID)
+	}
+	s.record(channelID, AuthorBot, content, true)
+	_, err := fmt.Fprintln(w, content)
+	return err
+}
+
+// ChannelMessages Return at most limit messages sent before beforeID in the channel, latest first,
+// the same order as discordgo.Session.ChannelMessages.
+func (s *Service) ChannelMessages(channelID string, limit int, beforeID int64) []*Message {
+	s.historyLock.RLock()
+	defer s.historyLock.RUnlock()
+	var messages []*Message
+	history := s.history[channelID]
+	for i := len(history) - 1; i >= 0 && len(messages) < limit; i-- {
+		if history[i].ID < beforeID {
+			messages = append(messages, history[i])
+		}
+	}
+	return messages
+}
+
+// IsMessageFromBotOrSelf Return true if the message is written by Dalian.
+func (s *Service) IsMessageFromBotOrSelf(m *Message) bool {
+	return m.Bot
+}
@@ -0,0 +1,581 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FileStore Store kept in a single local bbolt file, for small deployments and tests without a mongo server.
+// Every collection is a bucket of BSON documents in insertion order, with an index of their `_id`.
+//
+// Limits: filters are evaluated by scanning the collection, only `_id` lookups use the index, so it suits
+// collections of some thousand documents; use mongo beyond that. Writes are serialized, reads run alongside them.
+// Only one process may open the file, so the bot and `migrate` can't run on it at once.
+type FileStore struct {
+	db *bolt.DB
+}
+
+// errStoreInUse another process holds the lock of the file.
+var errStoreInUse = errors.New("data: the store is in use by another process")
+
+// openTimeout how long OpenFileStore waits for another process to release the file.
+const openTimeout = 100 * time.Millisecond
+
+var (
+	docsBucket = []byte("docs") // sequence : document
+	idsBucket  = []byte("ids")  // `_id` : sequence
+)
+
+// OpenFileStore open the store at path, creating it and its directory if the file doesn't exist yet.
+// It fails with errStoreInUse if another process has the store opened.
+func OpenFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", errStoreInUse, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{db: db}, nil
+}
+
+func (s *FileStore) Collection(name string) Collection {
+	return fileCollection{store: s, name: []byte(name)}
+}
+
+func (s *FileStore) Ping(_ context.Context) error {
+	return nil
+}
+
+// Close release the file, every write is already on disk.
+func (s *FileStore) Close(_ context.Context) error {
+	return s.db.Close()
+}
+
+type fileCollection struct {
+	store *FileStore
+	name  []byte
+}
+
+// storedDoc a document and its key in the docs bucket.
+type storedDoc struct {
+	key []byte
+	raw bson.Raw
+}
+
+// buckets Return the docs and ids buckets of the collection, creating them in a writable transaction.
+// Both are nil if a read-only transaction finds no collection.
+func (c fileCollection) buckets(tx *bolt.Tx) (docs, ids *bolt.Bucket, err error) {
+	collection := tx.Bucket(c.name)
+	if collection == nil {
+		if !tx.Writable() {
+			return nil, nil, nil
+		}
+		if collection, err = tx.CreateBucket(c.name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if docs = collection.Bucket(docsBucket); docs == nil && tx.Writable() {
+		if docs, err = collection.CreateBucket(docsBucket); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ids = collection.Bucket(idsBucket); ids == nil && tx.Writable() {
+		if ids, err = collection.CreateBucket(idsBucket); err != nil {
+			return nil, nil, err
+		}
+	}
+	return docs, ids, nil
+}
+
+// idKey the key of an `_id` in the ids bucket, its BSON type and value.
+func idKey(id any) ([]byte, error) {
+	t, raw, err := bson.MarshalValue(id)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{byte(t)}, raw...), nil
+}
+
+// match Return the documents matching filter, in insertion order. A filter on `_id` alone uses the index.
+// The documents are copies, valid after the transaction.
+func (c fileCollection) match(tx *bolt.Tx, filter Filter) ([]storedDoc, error) {
+	docs, ids, err := c.buckets(tx)
+	if err != nil || docs == nil {
+		return nil, err
+	}
+	if len(filter) == 1 && filter[0].Field == "_id" && filter[0].Operator == OpEq {
+		key, err := idKey(filter[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		seq := ids.Get(key)
+		if seq == nil {
+			return nil, nil
+		}
+		raw := docs.Get(seq)
+		return []storedDoc{{key: append([]byte{}, seq...), raw: append(bson.Raw{}, raw...)}}, nil
+	}
+	var matched []storedDoc
+	err = docs.ForEach(func(k, v []byte) error {
+		ok, err := filter.matches(v)
+		if ok {
+			matched = append(matched, storedDoc{key: append([]byte{}, k...), raw: append(bson.Raw{}, v...)})
+		}
+		return err
+	})
+	return matched, err
+}
+
+// put store the document under key, indexing its `_id`.
+func (c fileCollection) put(tx *bolt.Tx, key []byte, doc bson.D) error {
+	docs, ids, err := c.buckets(tx)
+	if err != nil {
+		return err
+	}
+	id, doc := ensureID(doc)
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	idKey, err := idKey(id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		if ids.Get(idKey) != nil {
+			return fmt.Errorf("data: duplicate _id %v in %s", id, c.name)
+		}
+		seq, err := docs.NextSequence()
+		if err != nil {
+			return err
+		}
+		key = binary.BigEndian.AppendUint64(nil, seq)
+	}
+	if err := ids.Put(idKey, key); err != nil {
+		return err
+	}
+	return docs.Put(key, raw)
+}
+
+func (c fileCollection) Find(_ context.Context, receiver any, filter Filter, opts ...FindOptions) error {
+	rv := reflect.ValueOf(receiver)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("receiver is not a pointer to a slice")
+	}
+	var docs []storedDoc
+	if err := c.store.db.View(func(tx *bolt.Tx) (err error) {
+		docs, err = c.match(tx, filter)
+		return err
+	}); err != nil {
+		return err
+	}
+	o := mergeFindOptions(opts)
+	if len(o.Sort) > 0 {
+		sort.SliceStable(docs, func(i, j int) bool {
+			for _, f := range o.Sort {
+				cmp := compareValues(lookupOrNull(docs[i].raw, f.Field), lookupOrNull(docs[j].raw, f.Field))
+				if cmp == 0 {
+					continue
+				}
+				return (cmp < 0) != f.Descending
+			}
+			return false
+		})
+	}
+	if o.Skip >= int64(len(docs)) {
+		docs = nil
+	} else {
+		docs = docs[o.Skip:]
+	}
+	if o.Limit > 0 && o.Limit < int64(len(docs)) {
+		docs = docs[:o.Limit]
+	}
+
+	sliceType := rv.Elem().Type()
+	elemType := sliceType.Elem()
+	results := reflect.MakeSlice(sliceType, 0, len(docs))
+	for _, doc := range docs {
+		if elemType.Kind() == reflect.Ptr {
+			elem := reflect.New(elemType.Elem())
+			if err := bson.Unmarshal(doc.raw, elem.Interface()); err != nil {
+				return err
+			}
+			results = reflect.Append(results, elem)
+		} else {
+			elem := reflect.New(elemType)
+			if err := bson.Unmarshal(doc.raw, elem.Interface()); err != nil {
+				return err
+			}
+			results = reflect.Append(results, elem.Elem())
+		}
+	}
+	rv.Elem().Set(results)
+	return nil
+}
+
+func (c fileCollection) FindOne(_ context.Context, receiver any, filter Filter) error {
+	if reflect.TypeOf(receiver).Kind() != reflect.Ptr {
+		return errors.New("receiver is not a pointer")
+	}
+	var docs []storedDoc
+	if err := c.store.db.View(func(tx *bolt.Tx) (err error) {
+		docs, err = c.match(tx, filter)
+		return err
+	}); err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return ErrNotFound
+	}
+	return bson.Unmarshal(docs[0].raw, receiver)
+}
+
+func (c fileCollection) Count(_ context.Context, filter Filter) (int64, error) {
+	var count int64
+	err := c.store.db.View(func(tx *bolt.Tx) error {
+		docs, err := c.match(tx, filter)
+		count = int64(len(docs))
+		return err
+	})
+	return count, err
+}
+
+func (c fileCollection) InsertOne(_ context.Context, doc any) (any, error) {
+	d, err := ToBsonDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	id, d := ensureID(d)
+	err = c.store.db.Update(func(tx *bolt.Tx) error {
+		return c.put(tx, nil, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// UpdateOne `_id` in doc is ignored, the id of a stored document never changes.
+func (c fileCollection) UpdateOne(_ context.Context, filter Filter, doc any, upsert bool) (UpdateResult, error) {
+	fields, err := ToBsonDoc(doc)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	var result UpdateResult
+	err = c.store.db.Update(func(tx *bolt.Tx) error {
+		matched, err := c.match(tx, filter)
+		if err != nil {
+			return err
+		}
+		if len(matched) == 0 {
+			if !upsert {
+				return nil
+			}
+			// seed the new document with the equality conditions, like mongo does.
+			seed := bson.D{}
+			for _, cond := range filter {
+				if cond.Operator == OpEq && !strings.Contains(cond.Field, ".") {
+					seed = append(seed, bson.E{Key: cond.Field, Value: cond.Value})
+				}
+			}
+			id, inserting := ensureID(setFields(seed, fields, true))
+			result = UpdateResult{UpsertedCount: 1, UpsertedID: id}
+			return c.put(tx, nil, inserting)
+		}
+
+		result.MatchedCount = 1
+		var current bson.D
+		if err := bson.Unmarshal(matched[0].raw, &current); err != nil {
+			return err
+		}
+		updated := setFields(current, fields, false)
+		if raw, err := bson.Marshal(updated); err != nil || bytes.Equal(raw, matched[0].raw) {
+			return err
+		}
+		result.ModifiedCount = 1
+		return c.put(tx, matched[0].key, updated)
+	})
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return result, nil
+}
+
+func (c fileCollection) UnsetOne(_ context.Context, filter Filter, fields ...string) (UpdateResult, error) {
+	removed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		removed[field] = field != "_id"
+	}
+	var result UpdateResult
+	err := c.store.db.Update(func(tx *bolt.Tx) error {
+		matched, err := c.match(tx, filter)
+		if err != nil || len(matched) == 0 {
+			return err
+		}
+		result.MatchedCount = 1
+		var current bson.D
+		if err := bson.Unmarshal(matched[0].raw, &current); err != nil {
+			return err
+		}
+		kept := bson.D{}
+		for _, e := range current {
+			if !removed[e.Key] {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == len(current) {
+			return nil
+		}
+		result.ModifiedCount = 1
+		return c.put(tx, matched[0].key, kept)
+	})
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return result, nil
+}
+
+func (c fileCollection) DeleteOne(_ context.Context, filter Filter) (int64, error) {
+	var deleted int64
+	err := c.store.db.Update(func(tx *bolt.Tx) error {
+		matched, err := c.match(tx, filter)
+		if err != nil || len(matched) == 0 {
+			return err
+		}
+		docs, ids, err := c.buckets(tx)
+		if err != nil {
+			return err
+		}
+		idKey, err := idKey(matched[0].raw.Lookup("_id"))
+		if err != nil {
+			return err
+		}
+		if err := ids.Delete(idKey); err != nil {
+			return err
+		}
+		deleted = 1
+		return docs.Delete(matched[0].key)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// ensureID Return the `_id` of the document, prepending a new ObjectID if there is none.
+func ensureID(d bson.D) (any, bson.D) {
+	for _, e := range d {
+		if e.Key == "_id" {
+			return e.Value, d
+		}
+	}
+	id := primitive.NewObjectID()
+	return id, append(bson.D{{Key: "_id", Value: id}}, d...)
+}
+
+// setFields `$set` the fields on the document. `_id` is only set when withID is true.
+func setFields(d bson.D, fields bson.D, withID bool) bson.D {
+	for _, f := range fields {
+		if f.Key == "_id" && !withID {
+			continue
+		}
+		replaced := false
+		for i := range d {
+			if d[i].Key == f.Key {
+				d[i].Value = f.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			d = append(d, f)
+		}
+	}
+	return d
+}
+
+func (f Filter) matches(doc bson.Raw) (bool, error) {
+	for _, c := range f {
+		ok, err := c.matches(doc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (c Condition) matches(doc bson.Raw) (bool, error) {
+	value, err := doc.LookupErr(strings.Split(c.Field, ".")...)
+	found := err == nil
+	if c.Operator == OpExists {
+		want, _ := c.Value.(bool)
+		return found == want, nil
+	}
+	target, err := toRawValue(c.Value)
+	if err != nil {
+		return false, fmt.Errorf("data: condition on %s: %w", c.Field, err)
+	}
+	switch c.Operator {
+	case OpEq:
+		if !found {
+			// mongo matches missing fields against null
+			return target.Type == bson.TypeNull, nil
+		}
+		return containsValue(value, target), nil
+	case OpAll:
+		if target.Type != bson.TypeArray {
+			return false, fmt.Errorf("data: All condition on %s needs a slice", c.Field)
+		}
+		elements, err := target.Array().Values()
+		if err != nil {
+			return false, err
+		}
+		if !found || len(elements) == 0 {
+			return false, nil
+		}
+		for _, element := range elements {
+			if !containsValue(value, element) {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("data: unknown operator %d", c.Operator)
+	}
+}
+
+func toRawValue(v any) (bson.RawValue, error) {
+	if v == nil {
+		return bson.RawValue{Type: bson.TypeNull}, nil
+	}
+	t, raw, err := bson.MarshalValue(v)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return bson.RawValue{Type: t, Value: raw}, nil
+}
+
+func lookupOrNull(doc bson.Raw, field string) bson.RawValue {
+	value, err := doc.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return bson.RawValue{Type: bson.TypeNull}
+	}
+	return value
+}
+
+// containsValue value equals target, or value is an array with an element equal to target.
+func containsValue(value, target bson.RawValue) bool {
+	if compareValues(value, target) == 0 {
+		return true
+	}
+	if value.Type != bson.TypeArray || target.Type == bson.TypeArray {
+		return false
+	}
+	elements, err := value.Array().Values()
+	if err != nil {
+		return false
+	}
+	for _, element := range elements {
+		if compareValues(element, target) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compareValues order values the way mongo sorts them: by type first, then by value.
+// Numbers of different types compare by value.
+func compareValues(a, b bson.RawValue) int {
+	if ra, rb := typeRank(a.Type), typeRank(b.Type); ra != rb {
+		return ra - rb
+	}
+	switch a.Type {
+	case bson.TypeInt32, bson.TypeInt64, bson.TypeDouble:
+		ai, aInt := a.AsInt64OK()
+		bi, bInt := b.AsInt64OK()
+		if aInt && bInt && a.Type != bson.TypeDouble && b.Type != bson.TypeDouble {
+			return compareOrdered(ai, bi)
+		}
+		return compareOrdered(asFloat64(a), asFloat64(b))
+	case bson.TypeString:
+		return strings.Compare(a.StringValue(), b.StringValue())
+	case bson.TypeDateTime:
+		return compareOrdered(a.DateTime(), b.DateTime())
+	case bson.TypeBoolean:
+		return compareOrdered(boolRank(a.Boolean()), boolRank(b.Boolean()))
+	default:
+		return bytes.Compare(a.Value, b.Value)
+	}
+}
+
+func typeRank(t bsontype.Type) int {
+	switch t {
+	case bson.TypeNull, bson.TypeUndefined:
+		return 1
+	case bson.TypeInt32, bson.TypeInt64, bson.TypeDouble:
+		return 2
+	case bson.TypeString, bson.TypeSymbol:
+		return 3
+	case bson.TypeEmbeddedDocument:
+		return 4
+	case bson.TypeArray:
+		return 5
+	case bson.TypeBinary:
+		return 6
+	case bson.TypeObjectID:
+		return 7
+	case bson.TypeBoolean:
+		return 8
+	case bson.TypeDateTime:
+		return 9
+	default:
+		return 10 + int(t)
+	}
+}
+
+func asFloat64(v bson.RawValue) float64 {
+	switch v.Type {
+	case bson.TypeInt32:
+		return float64(v.Int32())
+	case bson.TypeInt64:
+		return float64(v.Int64())
+	default:
+		return v.Double()
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered[T int | int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
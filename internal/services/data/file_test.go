@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testDoc struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Name  string             `bson:"name"`
+	Tags  []string           `bson:"tags"`
+	Score int64              `bson:"score"`
+	Note  string             `bson:"note,omitempty"`
+	Inner struct {
+		Level int `bson:"level"`
+	} `bson:"inner"`
+}
+
+func openTestStore(t *testing.T) (*FileStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nested", "dalian.db")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close(context.Background()) })
+	return store, path
+}
+
+func seed(t *testing.T, c Collection) {
+	t.Helper()
+	docs := []testDoc{
+		{Name: "a", Tags: []string{"go", "bot"}, Score: 3},
+		{Name: "b", Tags: []string{"go"}, Score: 1, Note: "noted"},
+		{Name: "c", Tags: []string{"bot"}, Score: 2},
+	}
+	docs[2].Inner.Level = 7
+	for _, d := range docs {
+		if _, err := c.InsertOne(context.Background(), d); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+}
+
+func names(docs []testDoc) []string {
+	var result []string
+	for _, d := range docs {
+		result = append(result, d.Name)
+	}
+	return result
+}
+
+func TestFileStoreFilters(t *testing.T) {
+	store, _ := openTestStore(t)
+	c := store.Collection("docs")
+	seed(t, c)
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   int64
+	}{
+		{"everything", nil, 3},
+		{"eq", Where(Eq("name", "a")), 1},
+		{"eq array element", Where(Eq("tags", "bot")), 2},
+		{"eq int32 against int64", Where(Eq("score", 3)), 1},
+		{"all", Where(All("tags", []string{"go", "bot"})), 1},
+		{"all empty", Where(All("tags", []string{})), 0},
+		{"exists", Where(Exists("note", true)), 1},
+		{"not exists", Where(Exists("note", false)), 2},
+		{"eq nil matches missing", Where(Eq("note", nil)), 2},
+		{"nested", Where(Eq("inner.level", 7)), 1},
+		{"and", Where(Eq("tags", "go"), Eq("tags", "bot")), 1},
+	} {
+		count, err := c.Count(context.Background(), tc.filter)
+		if err != nil || count != tc.want {
+			t.Errorf("%s: count = %d (%v), want %d", tc.name, count, err, tc.want)
+		}
+	}
+}
+
+func TestFileStoreFindOptions(t *testing.T) {
+	store, _ := openTestStore(t)
+	c := store.Collection("docs")
+	seed(t, c)
+	var docs []testDoc
+	if err := c.Find(context.Background(), &docs, nil, FindOptions{Sort: []SortField{{Field: "score"}}}); err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if got := names(docs); len(got) != 3 || got[0] != "b" || got[2] != "a" {
+		t.Errorf("ascending = %v", got)
+	}
+	var pointers []*testDoc
+	err := c.Find(context.Background(), &pointers, nil, FindOptions{Sort: []SortField{{Field: "score", Descending: true}}, Skip: 1, Limit: 1})
+	if err != nil || len(pointers) != 1 || pointers[0].Name != "c" {
+		t.Errorf("skip/limit = %+v (%v)", pointers, err)
+	}
+}
+
+func TestFileStoreUpdateAndDelete(t *testing.T) {
+	store, path := openTestStore(t)
+	c := store.Collection("docs")
+	seed(t, c)
+	ctx := context.Background()
+
+	var a testDoc
+	if err := c.FindOne(ctx, &a, Where(Eq("name", "a"))); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	a.Note = "changed"
+	if result, err := c.UpdateOne(ctx, ByID(a.ID), a, false); err != nil || result.ModifiedCount != 1 {
+		t.Fatalf("UpdateOne = %+v (%v)", result, err)
+	}
+	if result, _ := c.UpdateOne(ctx, ByID(a.ID), a, false); result.MatchedCount != 1 || result.ModifiedCount != 0 {
+		t.Errorf("unchanged update = %+v", result)
+	}
+	if result, err := c.UnsetOne(ctx, ByID(a.ID), "note"); err != nil || result.ModifiedCount != 1 {
+		t.Fatalf("UnsetOne = %+v (%v)", result, err)
+	}
+	if n, _ := c.Count(ctx, Where(Eq("name", "a"), Exists("note", false))); n != 1 {
+		t.Errorf("note of a is still set")
+	}
+	// upsert seeds the new document with the equality conditions
+	result, err := c.UpdateOne(ctx, Where(Eq("name", "d")), bson.M{"score": 9}, true)
+	if err != nil || result.UpsertedCount != 1 || result.UpsertedID == nil {
+		t.Fatalf("upsert = %+v (%v)", result, err)
+	}
+	if _, err := c.InsertOne(ctx, bson.M{"_id": a.ID}); err == nil {
+		t.Errorf("inserting a duplicate _id should fail")
+	}
+	if deleted, err := c.DeleteOne(ctx, Where(Eq("name", "b"))); err != nil || deleted != 1 {
+		t.Fatalf("DeleteOne = %d (%v)", deleted, err)
+	}
+	if deleted, _ := c.DeleteOne(ctx, Where(Eq("name", "b"))); deleted != 0 {
+		t.Errorf("second DeleteOne = %d", deleted)
+	}
+
+	// the store can't be opened twice
+	if _, err := OpenFileStore(path); !errors.Is(err, errStoreInUse) {
+		t.Fatalf("want errStoreInUse while opened, got %v", err)
+	}
+
+	// everything survives a reopen
+	if err := store.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close(ctx)
+	var docs []testDoc
+	reopened.Collection("docs").Find(ctx, &docs, nil, FindOptions{Sort: []SortField{{Field: "name"}}})
+	if got := names(docs); len(got) != 3 || got[0] != "a" || got[2] != "d" || docs[0].Note != "" || docs[2].Score != 9 {
+		t.Errorf("after reopen = %+v", docs)
+	}
+	if err := reopened.Collection("docs").FindOne(ctx, &a, Where(Eq("name", "b"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestFilterToBson(t *testing.T) {
+	if got := Where(Eq("a", 1), All("b", []int{2})).ToBson(); len(got) != 2 || got[0].Key != "a" {
+		t.Errorf("unexpected query: %v", got)
+	}
+	if got := Where(Eq("a", 1), Eq("a", 2)).ToBson(); len(got) != 1 || got[0].Key != "$and" {
+		t.Errorf("duplicate fields must be joined with $and, got %v", got)
+	}
+}
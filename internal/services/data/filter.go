@@ -0,0 +1,85 @@
+package data
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Filter Conditions a document must all meet. An empty Filter matches every document.
+type Filter []Condition
+
+type Operator int
+
+const (
+	// OpEq the field equals the value. When the field is an array, one of its elements equals the value.
+	OpEq Operator = iota
+	// OpAll the field is an array containing every element of the value.
+	OpAll
+	// OpExists the field presence equals the boolean value.
+	OpExists
+)
+
+// Condition Field can address nested documents with dots, e.g. `user_info.uid`.
+type Condition struct {
+	Field    string
+	Operator Operator
+	Value    any
+}
+
+func Where(conditions ...Condition) Filter {
+	return conditions
+}
+
+// ByID Filter matching the document with the given `_id`.
+func ByID(id any) Filter {
+	return Where(Eq("_id", id))
+}
+
+func Eq(field string, value any) Condition {
+	return Condition{Field: field, Operator: OpEq, Value: value}
+}
+
+// All values must be a slice.
+func All(field string, values any) Condition {
+	return Condition{Field: field, Operator: OpAll, Value: values}
+}
+
+func Exists(field string, exists bool) Condition {
+	return Condition{Field: field, Operator: OpExists, Value: exists}
+}
+
+// And Return a new Filter with the conditions appended.
+func (f Filter) And(conditions ...Condition) Filter {
+	return append(append(Filter{}, f...), conditions...)
+}
+
+// ToBson translate the filter into a mongo query document.
+func (f Filter) ToBson() bson.D {
+	query := bson.D{}
+	seen := make(map[string]bool)
+	duplicated := false
+	for _, c := range f {
+		duplicated = duplicated || seen[c.Field]
+		seen[c.Field] = true
+		query = append(query, c.toBson())
+	}
+	if !duplicated {
+		return query
+	}
+	// several conditions on the same field can't share a document
+	clauses := bson.A{}
+	for _, e := range query {
+		clauses = append(clauses, bson.D{e})
+	}
+	return bson.D{{Key: "$and", Value: clauses}}
+}
+
+func (c Condition) toBson() bson.E {
+	switch c.Operator {
+	case OpAll:
+		return bson.E{Key: c.Field, Value: bson.M{"$all": c.Value}}
+	case OpExists:
+		return bson.E{Key: c.Field, Value: bson.M{"$exists": c.Value}}
+	default:
+		return bson.E{Key: c.Field, Value: c.Value}
+	}
+}
@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// schemaVersionCollection holds one document per namespace: `{_id: <namespace>, version: <int>}`.
+const schemaVersionCollection = "schema_versions"
+
+// Schema Collections owned by a namespace, usually a plugin, and how to bring them to the latest version.
+type Schema struct {
+	Namespace string
+	// Indexes are created at every startup if missing.
+	Indexes []Index
+	// Migrations versions must be 1, 2, 3..., each one is applied once and recorded.
+	Migrations []Migration
+}
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, store Store) error
+}
+
+// Index Keys are in index order, Descending marks a descending key.
+type Index struct {
+	Collection string
+	Name       string
+	Keys       []SortField
+	Unique     bool
+}
+
+// IndexedStore Store supporting secondary indexes. Indexes are skipped on other stores.
+type IndexedStore interface {
+	Store
+	IndexNames(ctx context.Context, collection string) ([]string, error)
+	CreateIndex(ctx context.Context, index Index) error
+}
+
+// SchemaStatus Where a namespace stands compared with its registered Schema.
+type SchemaStatus struct {
+	Namespace      string
+	Version        int // recorded in the store
+	LatestVersion  int
+	Pending        []Migration
+	MissingIndexes []Index
+	IndexSupported bool
+}
+
+func (st SchemaStatus) UpToDate() bool {
+	return len(st.Pending) == 0 && len(st.MissingIndexes) == 0 && st.Version <= st.LatestVersion
+}
+
+type schemaVersionPo struct {
+	Namespace   string    `bson:"_id"`
+	Version     int       `bson:"version"`
+	UpdatedTime time.Time `bson:"updated_time"`
+}
+
+func (s Schema) latestVersion() int {
+	return len(s.Migrations)
+}
+
+func (s Schema) validate() error {
+	if s.Namespace == "" {
+		return errors.New("schema without namespace")
+	}
+	for i, m := range s.Migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("schema %s: migration #%d has version %d, want %d", s.Namespace, i+1, m.Version, i+1)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("schema %s: migration %d has nothing to do", s.Namespace, m.Version)
+		}
+	}
+	for _, index := range s.Indexes {
+		if index.Collection == "" || index.Name == "" || len(index.Keys) == 0 {
+			return fmt.Errorf("schema %s: incomplete index %+v", s.Namespace, index)
+		}
+	}
+	return nil
+}
+
+// RegisterSchema Register or replace the schema of a namespace. Call Migrate to apply it.
+func (s *Service) RegisterSchema(schema Schema) error {
+	if err := schema.validate(); err != nil {
+		return err
+	}
+	s.schemaLock.Lock()
+	defer s.schemaLock.Unlock()
+	for i, registered := range s.schemas {
+		if registered.Namespace == schema.Namespace {
+			s.schemas[i] = schema
+			return nil
+		}
+	}
+	s.schemas = append(s.schemas, schema)
+	return nil
+}
+
+// SchemaStatus Compare every registered schema with the store, in registration order.
+func (s *Service) SchemaStatus(ctx context.Context) ([]SchemaStatus, error) {
+	s.schemaLock.Lock()
+	schemas := append([]Schema{}, s.schemas...)
+	s.schemaLock.Unlock()
+	var statuses []SchemaStatus
+	for _, schema := range schemas {
+		status, err := s.schemaStatus(ctx, schema)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *Service) schemaStatus(ctx context.Context, schema Schema) (SchemaStatus, error) {
+	status := SchemaStatus{Namespace: schema.Namespace, LatestVersion: schema.latestVersion()}
+	var record schemaVersionPo
+	err := s.Collection(schemaVersionCollection).FindOne(ctx, &record, ByID(schema.Namespace))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return status, err
+	}
+	status.Version = record.Version
+	if status.Version < status.LatestVersion {
+		status.Pending = schema.Migrations[status.Version:]
+	}
+	indexedStore, ok := s.Store.(IndexedStore)
+	status.IndexSupported = ok
+	if !ok {
+		return status, nil
+	}
+	existing := make(map[string]map[string]bool)
+	for _, index := range schema.Indexes {
+		if existing[index.Collection] == nil {
+			names, err := indexedStore.IndexNames(ctx, index.Collection)
+			if err != nil {
+				return status, err
+			}
+			existing[index.Collection] = make(map[string]bool)
+			for _, name := range names {
+				existing[index.Collection][name] = true
+			}
+		}
+		if !existing[index.Collection][index.Name] {
+			status.MissingIndexes = append(status.MissingIndexes, index)
+		}
+	}
+	return status, nil
+}
+
+// Migrate Create missing indexes and apply pending migrations of every registered schema.
+// The version is recorded after each migration, so a failed run resumes from the failing migration.
+func (s *Service) Migrate(ctx context.Context) error {
+	statuses, err := s.SchemaStatus(ctx)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.Version > status.LatestVersion {
+			return fmt.Errorf("schema %s is at version %d, newer than the latest known version %d",
+				status.Namespace, status.Version, status.LatestVersion)
+		}
+		for _, index := range status.MissingIndexes {
+			if err := s.Store.(IndexedStore).CreateIndex(ctx, index); err != nil {
+				return fmt.Errorf("schema %s: creating index %s: %w", status.Namespace, index.Name, err)
+			}
+		}
+		for _, migration := range status.Pending {
+			if err := migration.Up(ctx, s.Store); err != nil {
+				return fmt.Errorf("schema %s: migration %d (%s): %w", status.Namespace, migration.Version, migration.Description, err)
+			}
+			if _, err := s.Collection(schemaVersionCollection).UpdateOne(ctx, ByID(status.Namespace),
+				bson.M{"version": migration.Version, "updated_time": time.Now()}, true); err != nil {
+				return fmt.Errorf("schema %s: recording version %d: %w", status.Namespace, migration.Version, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newMigratingService(t *testing.T) *Service {
+	t.Helper()
+	store, _ := openTestStore(t)
+	return &Service{Store: store}
+}
+
+func TestMigrateAppliesPendingOnce(t *testing.T) {
+	s := newMigratingService(t)
+	ctx := context.Background()
+	applied := 0
+	fail := errors.New("boom")
+	shouldFail := true
+	schema := Schema{Namespace: "test", Migrations: []Migration{
+		{Version: 1, Description: "seed", Up: func(ctx context.Context, store Store) error {
+			applied++
+			_, err := store.Collection("docs").InsertOne(ctx, bson.M{"name": "seeded"})
+			return err
+		}},
+		{Version: 2, Description: "flaky", Up: func(ctx context.Context, store Store) error {
+			applied++
+			if shouldFail {
+				return fail
+			}
+			return nil
+		}},
+	}}
+	if err := s.RegisterSchema(schema); err != nil {
+		t.Fatalf("RegisterSchema: %v", err)
+	}
+
+	// the failing migration stops the run, the successful one is recorded
+	if err := s.Migrate(ctx); !errors.Is(err, fail) {
+		t.Fatalf("want migration error, got %v", err)
+	}
+	statuses, _ := s.SchemaStatus(ctx)
+	if st := statuses[0]; st.Version != 1 || len(st.Pending) != 1 || st.UpToDate() {
+		t.Fatalf("after failure: %+v", st)
+	}
+
+	shouldFail = false
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("migrations ran %d times, want 3", applied)
+	}
+	statuses, _ = s.SchemaStatus(ctx)
+	if st := statuses[0]; st.Version != 2 || !st.UpToDate() || st.IndexSupported {
+		t.Errorf("after success: %+v", st)
+	}
+	if count, _ := s.Collection("docs").Count(ctx, Where(Eq("name", "seeded"))); count != 1 {
+		t.Errorf("seeded %d documents, want 1", count)
+	}
+
+	// a database migrated by a newer build is refused
+	schema.Migrations = schema.Migrations[:1]
+	s.RegisterSchema(schema)
+	if err := s.Migrate(ctx); err == nil {
+		t.Error("want an error for a schema newer than the build")
+	}
+}
+
+func TestRegisterSchemaValidation(t *testing.T) {
+	s := newMigratingService(t)
+	noop := func(context.Context, Store) error { return nil }
+	for name, schema := range map[string]Schema{
+		"no namespace":   {Migrations: []Migration{{Version: 1, Up: noop}}},
+		"version gap":    {Namespace: "a", Migrations: []Migration{{Version: 1, Up: noop}, {Version: 3, Up: noop}}},
+		"no up":          {Namespace: "a", Migrations: []Migration{{Version: 1}}},
+		"index w/o keys": {Namespace: "a", Indexes: []Index{{Collection: "c", Name: "i"}}},
+	} {
+		if err := s.RegisterSchema(schema); err == nil {
+			t.Errorf("%s: want validation error", name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// MongoStore Store backed by a mongo database.
+type MongoStore struct {
+	Client   *mongo.Client
+	Database *mongo.Database
+}
+
+func OpenMongoStore(ctx context.Context, uri, database string) (*MongoStore, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	return &MongoStore{Client: client, Database: client.Database(database)}, nil
+}
+
+func (s *MongoStore) Collection(name string) Collection {
+	return mongoCollection{s.Database.Collection(name)}
+}
+
+func (s *MongoStore) Ping(ctx context.Context) error {
+	return s.Client.Ping(ctx, readpref.Primary())
+}
+
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.Client.Disconnect(ctx)
+}
+
+func (s *MongoStore) IndexNames(ctx context.Context, collection string) ([]string, error) {
+	specs, err := s.Database.Collection(collection).Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	return names, nil
+}
+
+func (s *MongoStore) CreateIndex(ctx context.Context, index Index) error {
+	_, err := s.Database.Collection(index.Collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    orderDoc(index.Keys),
+		Options: options.Index().SetName(index.Name).SetUnique(index.Unique),
+	})
+	return err
+}
+
+type mongoCollection struct {
+	*mongo.Collection
+}
+
+func (c mongoCollection) Find(ctx context.Context, receiver any, filter Filter, opts ...FindOptions) error {
+	if reflect.TypeOf(receiver).Kind() != reflect.Ptr {
+		return errors.New("receiver is not a pointer")
+	}
+	o := mergeFindOptions(opts)
+	findOptions := options.Find().SetSkip(o.Skip).SetLimit(o.Limit)
+	if len(o.Sort) > 0 {
+		findOptions.SetSort(orderDoc(o.Sort))
+	}
+	cursor, err := c.Collection.Find(ctx, filter.ToBson(), findOptions)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, receiver)
+}
+
+func (c mongoCollection) FindOne(ctx context.Context, receiver any, filter Filter) error {
+	if reflect.TypeOf(receiver).Kind() != reflect.Ptr {
+		return errors.New("receiver is not a pointer")
+	}
+	err := c.Collection.FindOne(ctx, filter.ToBson()).Decode(receiver)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (c mongoCollection) Count(ctx context.Context, filter Filter) (int64, error) {
+	return c.Collection.CountDocuments(ctx, filter.ToBson())
+}
+
+func (c mongoCollection) InsertOne(ctx context.Context, doc any) (any, error) {
+	result, err := c.Collection.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return result.InsertedID, nil
+}
+
+func (c mongoCollection) UpdateOne(ctx context.Context, filter Filter, doc any, upsert bool) (UpdateResult, error) {
+	fields, err := ToBsonDoc(doc)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	result, err := c.Collection.UpdateOne(ctx, filter.ToBson(), bson.D{{Key: "$set", Value: fields}}, options.Update().SetUpsert(upsert))
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedID:    result.UpsertedID,
+	}, nil
+}
+
+func (c mongoCollection) UnsetOne(ctx context.Context, filter Filter, fields ...string) (UpdateResult, error) {
+	unset := bson.D{}
+	for _, field := range fields {
+		unset = append(unset, bson.E{Key: field, Value: ""})
+	}
+	result, err := c.Collection.UpdateOne(ctx, filter.ToBson(), bson.D{{Key: "$unset", Value: unset}})
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return UpdateResult{MatchedCount: result.MatchedCount, ModifiedCount: result.ModifiedCount}, nil
+}
+
+func (c mongoCollection) DeleteOne(ctx context.Context, filter Filter) (int64, error) {
+	result, err := c.Collection.DeleteOne(ctx, filter.ToBson())
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// orderDoc translate fields into a mongo sort or index key document.
+func orderDoc(fields []SortField) bson.D {
+	keys := bson.D{}
+	for _, f := range fields {
+		order := 1
+		if f.Descending {
+			order = -1
+		}
+		keys = append(keys, bson.E{Key: f.Field, Value: order})
+	}
+	return keys
+}
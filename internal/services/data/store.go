@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound returned by Collection.FindOne when no document matches the filter.
+var ErrNotFound = errors.New("data: no document found")
+
+// Store A document database made of named collections. Plugins never talk to a backend directly;
+// they get a Collection from Service and describe queries with Filter.
+type Store interface {
+	Collection(name string) Collection
+	// Ping check the backend is reachable.
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+// Collection A set of documents. Documents are marshaled with bson struct tags, and every document
+// gets an ObjectID `_id` on insert if it doesn't have one.
+type Collection interface {
+	// Find decode every matching document into receiver, which must be a pointer to a slice.
+	Find(ctx context.Context, receiver any, filter Filter, opts ...FindOptions) error
+	// FindOne decode the first matching document into receiver, or return ErrNotFound.
+	FindOne(ctx context.Context, receiver any, filter Filter) error
+	Count(ctx context.Context, filter Filter) (int64, error)
+	// InsertOne insert the document and return its `_id`.
+	InsertOne(ctx context.Context, doc any) (any, error)
+	// UpdateOne set the fields of doc on the first matching document.
+	// With upsert, a document made of the Eq conditions of filter and doc is inserted when nothing matches.
+	UpdateOne(ctx context.Context, filter Filter, doc any, upsert bool) (UpdateResult, error)
+	// UnsetOne remove the fields from the first matching document.
+	UnsetOne(ctx context.Context, filter Filter, fields ...string) (UpdateResult, error)
+	// DeleteOne delete the first matching document and return the number of deleted documents.
+	DeleteOne(ctx context.Context, filter Filter) (int64, error)
+}
+
+// FindOptions Sort is applied before Skip and Limit. Limit 0 means no limit.
+type FindOptions struct {
+	Sort  []SortField
+	Skip  int64
+	Limit int64
+}
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+type UpdateResult struct {
+	MatchedCount  int64
+	ModifiedCount int64
+	UpsertedCount int64
+	UpsertedID    any
+}
+
+// mergeFindOptions the last non-zero value of each option wins.
+func mergeFindOptions(opts []FindOptions) FindOptions {
+	var merged FindOptions
+	for _, o := range opts {
+		if o.Sort != nil {
+			merged.Sort = o.Sort
+		}
+		if o.Skip != 0 {
+			merged.Skip = o.Skip
+		}
+		if o.Limit != 0 {
+			merged.Limit = o.Limit
+		}
+	}
+	return merged
+}
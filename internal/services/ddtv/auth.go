@@ -0,0 +1,113 @@
+package ddtv
+
+import (
+	"dalian-bot/internal/services/web"
+	"fmt"
+	"net"
+	"sync"
+)
+
+const (
+	// HeaderToken carries the secret of the sender. The `token` query parameter is accepted as well,
+	// since DDTV only lets you configure the webhook url.
+	HeaderToken = "X-Dalian-Token"
+	QueryToken  = "token"
+	// HeaderSignature sha256=<hex HMAC-SHA256 of the body, keyed with the secret of the sender>
+	HeaderSignature = "X-Dalian-Signature"
+)
+
+// Reasons of rejected webhook calls, see AuthStats.
+const (
+	RejectIP        = "ip"
+	RejectToken     = "token"
+	RejectSignature = "signature"
+)
+
+// Sender A DDTV instance allowed to post webhooks.
+type Sender struct {
+	Name   string
+	Secret string // sent as HeaderToken or QueryToken, or used to sign the body
+	// RequireSignature reject calls of the sender without a valid HeaderSignature, even with the right token.
+	RequireSignature bool
+	// AllowedIPs IPs or CIDRs the sender calls from, any when empty.
+	AllowedIPs  []string
+	allowedNets []*net.IPNet
+}
+
+func (s *Sender) parseAllowedIPs() error {
+	nets, err := web.ParseIPNets(s.AllowedIPs)
+	if err != nil {
+		return fmt.Errorf("ddtv sender %s: %w", s.Name, err)
+	}
+	s.allowedNets = nets
+	return nil
+}
+
+// authenticate Return the sender of the call, or why it's rejected.
+// The sender is identified by its token or signature, then its IP and signature requirements are checked.
+func authenticate(senders []Sender, ip net.IP, token, signature string, body []byte) (*Sender, string) {
+	reason := RejectToken
+	if signature != "" {
+		reason = RejectSignature
+	}
+	for i := range senders {
+		sender := &senders[i]
+		signed := signature != "" && web.ValidSignature(sender.Secret, signature, body)
+		if !signed && !web.ValidToken(sender.Secret, token) {
+			continue
+		}
+		if !web.AllowsIP(sender.allowedNets, ip) {
+			reason = RejectIP
+			continue
+		}
+		if sender.RequireSignature && !signed {
+			reason = RejectSignature
+			continue
+		}
+		return sender, ""
+	}
+	return nil, reason
+}
+
+// AuthStats Webhook calls since the service started.
+type AuthStats struct {
+	Accepted map[string]uint64 // by sender name
+	Rejected map[string]uint64 // by reason
+}
+
+type authCounter struct {
+	lock     sync.Mutex
+	accepted map[string]uint64
+	rejected map[string]uint64
+}
+
+func (a *authCounter) accept(sender string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.accepted == nil {
+		a.accepted = make(map[string]uint64)
+	}
+	a.accepted[sender]++
+}
+
+func (a *authCounter) reject(reason string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.rejected == nil {
+		a.rejected = make(map[string]uint64)
+	}
+	a.rejected[reason]++
+}
+
+func (a *authCounter) snapshot() AuthStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	stats := AuthStats{Accepted: make(map[string]uint64), Rejected: make(map[string]uint64)}
+	for k, v := range a.accepted {
+		stats.Accepted[k] = v
+	}
+	for k, v := range a.rejected {
+		stats.Rejected[k] = v
+	}
+	return stats
+}
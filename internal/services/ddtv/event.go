@@ -14,6 +14,7 @@ type Event struct {
 	EventType EventType
 	//will only use one of them
 	WebHook WebHook
+	Sender  string // name of the Sender of the webhook, "anonymous" without authentication
 }
 
 func UnboxEvent(t core.Trigger) Event {
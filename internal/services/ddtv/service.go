@@ -3,8 +3,12 @@ package ddtv
 import (
 	"dalian-bot/internal/core"
 	"dalian-bot/internal/services/web"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
+	"net"
 	"net/http"
 	"reflect"
 	"sync"
@@ -12,10 +16,20 @@ import (
 )
 
 type Service struct {
+	ServiceConfig
 	WebService *web.Service
 	core.TriggerableEmbedUtil
+	auth authCounter
 }
 
+type ServiceConfig struct {
+	// Senders DDTV instances allowed to post webhooks. Any call is accepted when empty.
+	Senders []Sender
+}
+
+// maxWebhookSize bodies are a few KB, anything bigger is not DDTV.
+const maxWebhookSize = 1 << 20
+
 func (s *Service) Name() string {
 	return "ddtv"
 }
@@ -26,8 +40,15 @@ func (s *Service) Init(reg *core.ServiceRegistry) error {
 		return err
 	}
 	s.WebService = webSrv
-	// todo: load the path from config
-	s.WebService.GinEngine.POST("/ddtv/webhook", s.handleWebhook)
+	for i := range s.Senders {
+		if err := s.Senders[i].parseAllowedIPs(); err != nil {
+			return err
+		}
+	}
+	if len(s.Senders) == 0 {
+		core.Logger.Warnf("No DDTV sender configured, webhooks are accepted from anyone.")
+	}
+	s.WebService.Group("/ddtv").POST("/webhook", s.handleWebhook)
 	return reg.RegisterService(s)
 }
 
@@ -42,25 +63,51 @@ func (s *Service) Stop(wg *sync.WaitGroup) error {
 	return nil
 }
 
+// Status webhooks are received through the web service.
 func (s *Service) Status() error {
-	//TODO implement me
-	panic("implement me")
+	if s.WebService == nil {
+		return errors.New("ddtv service is not initialized")
+	}
+	return s.WebService.Status()
+}
+
+// AuthStats Return the webhook calls accepted and rejected so far.
+func (s *Service) AuthStats() AuthStats {
+	return s.auth.snapshot()
 }
 
 // WOW, you can attach a struct!
 func (s *Service) handleWebhook(c *gin.Context) {
 	var hook WebHook
 
-	//debug
-	fmt.Println("hook found")
-	if err := c.BindJSON(&hook); err != nil {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		core.Logger.Warnf("Error reading DDTV webhook from %s: %v", c.ClientIP(), err)
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	sender := "anonymous"
+	if len(s.Senders) > 0 {
+		token := c.GetHeader(HeaderToken)
+		if token == "" {
+			token = c.Query(QueryToken)
+		}
+		authenticated, reason := authenticate(s.Senders, net.ParseIP(c.ClientIP()), token, c.GetHeader(HeaderSignature), body)
+		if authenticated == nil {
+			s.auth.reject(reason)
+			core.Logger.Warnf("Rejected DDTV webhook from %s: invalid %s.", c.ClientIP(), reason)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		sender = authenticated.Name
+	}
+	if err := json.Unmarshal(body, &hook); err != nil {
 		core.Logger.Warnf("Error: %v\r\n", err)
 		//failed. malformed.
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
-	//fmt.Println(hook)
-	fmt.Printf("Hook is: %d\r\n", hook.Type)
+	s.auth.accept(sender)
 	switch hook.Type {
 	case HookStartRec:
 		//debug
@@ -78,6 +125,7 @@ func (s *Service) handleWebhook(c *gin.Context) {
 		Event: Event{
 			EventType: EventTypeWebhook,
 			WebHook:   hook,
+			Sender:    sender,
 		},
 	}
 	s.TriggerChan <- t
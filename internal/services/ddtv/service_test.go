@@ -0,0 +1,101 @@
+package ddtv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"dalian-bot/internal/core"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newTestService(t *testing.T, senders ...Sender) (*Service, chan core.Trigger) {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	gin.SetMode(gin.TestMode)
+	s := &Service{ServiceConfig: ServiceConfig{Senders: senders}}
+	for i := range s.Senders {
+		if err := s.Senders[i].parseAllowedIPs(); err != nil {
+			t.Fatalf("sender %s: %v", s.Senders[i].Name, err)
+		}
+	}
+	triggers := make(chan core.Trigger, 10)
+	s.InstallTriggerChan(triggers)
+	return s, triggers
+}
+
+func post(s *Service, target, remoteAddr string, headers map[string]string, body string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	s.handleWebhook(c)
+	return w.Code
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookAuthentication(t *testing.T) {
+	s, triggers := newTestService(t,
+		Sender{Name: "home", Secret: "home-secret", AllowedIPs: []string{"10.0.0.0/8"}},
+		Sender{Name: "cloud", Secret: "cloud-secret", RequireSignature: true},
+	)
+	body := `{"type":0,"uid":1}`
+
+	for _, tc := range []struct {
+		name       string
+		target     string
+		remoteAddr string
+		headers    map[string]string
+		status     int
+	}{
+		{"query token", "/ddtv/webhook?token=home-secret", "10.1.2.3:1234", nil, http.StatusOK},
+		{"header token", "/ddtv/webhook", "10.1.2.3:1234", map[string]string{HeaderToken: "home-secret"}, http.StatusOK},
+		{"not allowed ip", "/ddtv/webhook?token=home-secret", "192.0.2.1:1234", nil, http.StatusUnauthorized},
+		{"wrong token", "/ddtv/webhook?token=nope", "10.1.2.3:1234", nil, http.StatusUnauthorized},
+		{"token without required signature", "/ddtv/webhook?token=cloud-secret", "192.0.2.1:1234", nil, http.StatusUnauthorized},
+		{"signed", "/ddtv/webhook", "192.0.2.1:1234", map[string]string{HeaderSignature: sign("cloud-secret", body)}, http.StatusOK},
+		{"bad signature", "/ddtv/webhook", "192.0.2.1:1234", map[string]string{HeaderSignature: sign("cloud-secret", "tampered")}, http.StatusUnauthorized},
+	} {
+		if status := post(s, tc.target, tc.remoteAddr, tc.headers, body); status != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, status, tc.status)
+		}
+	}
+
+	if len(triggers) != 3 {
+		t.Fatalf("want 3 triggers, got %d", len(triggers))
+	}
+	if e := UnboxEvent(<-triggers); e.Sender != "home" {
+		t.Errorf("first webhook sender = %s, want home", e.Sender)
+	}
+	stats := s.AuthStats()
+	if stats.Accepted["home"] != 2 || stats.Accepted["cloud"] != 1 {
+		t.Errorf("unexpected accepted counts: %v", stats.Accepted)
+	}
+	if stats.Rejected[RejectIP] != 1 || stats.Rejected[RejectToken] != 1 || stats.Rejected[RejectSignature] != 2 {
+		t.Errorf("unexpected rejected counts: %v", stats.Rejected)
+	}
+}
+
+func TestWebhookWithoutSenders(t *testing.T) {
+	s, triggers := newTestService(t)
+	if status := post(s, "/ddtv/webhook", "192.0.2.1:1234", nil, `{"type":1}`); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if e := UnboxEvent(<-triggers); e.Sender != "anonymous" || e.WebHook.Type != HookStopLive {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
@@ -11,62 +11,38 @@ import (
 func (wh WebHook) DigestEmbed() *discordgo.MessageEmbed {
 	switch wh.Type {
 	case HookSpaceIsInsufficientWarn:
-		return &discordgo.MessageEmbed{
-			Title:       "DDTV Insufficient Disk Storage WARNING",
-			Description: wh.Type.MessagePrompt("", 0),
-			Timestamp:   time.Now().Format(time.RFC3339),
-			Color:       discord.EmbedColorDanger,
-		}
-	case HookLoginFailure:
-		fallthrough
-	case HookLoginWillExpireSoon:
-		return &discordgo.MessageEmbed{
-			Title:       "DDTV Login Status WARNING",
-			Description: wh.Type.MessagePrompt("", 0),
-			Timestamp:   time.Now().Format(time.RFC3339),
-			Color:       discord.EmbedColorDanger,
-		}
+		return discord.DangerEmbed("DDTV Insufficient Disk Storage WARNING").Description(wh.Type.MessagePrompt("", 0)).Build()
+	case HookLoginFailure, HookLoginWillExpireSoon:
+		return discord.DangerEmbed("DDTV Login Status WARNING").Description(wh.Type.MessagePrompt("", 0)).Build()
 	case HookUpdateAvailable:
-		return &discordgo.MessageEmbed{
-			Title:       "DDTV Update available",
-			Description: wh.Type.MessagePrompt("", 0),
-			Timestamp:   time.Now().Format(time.RFC3339),
-			Color:       discord.EmbedColorQuestion,
-		}
+		return discord.QuestionEmbed("DDTV Update available").Description(wh.Type.MessagePrompt("", 0)).Build()
 	}
-	embed := &discordgo.MessageEmbed{
-		Title:       "DDTV Webhook Update",
-		Description: wh.Type.MessagePrompt(wh.RoomInfo.Uname, wh.RoomInfo.RoomID),
-		Author: &discordgo.MessageEmbedAuthor{
-			URL:     fmt.Sprintf("https://space.bilibili.com/%d", wh.UserInfo.UID),
-			Name:    fmt.Sprintf("%s [%d]", wh.UserInfo.Name, wh.UserInfo.UID),
-			IconURL: wh.RoomInfo.Face,
-		},
-		Provider: &discordgo.MessageEmbedProvider{
-			URL:  "https://ddtv.pro",
-			Name: "DDTV",
-		},
-		// can be empty
-		//Image: &discordgo.MessageEmbedImage{
-		//	URL: wh.RoomInfo.CoverFromUser,
-		//},
-		Timestamp: time.Now().Format(time.RFC3339),
-		Color:     discord.EmbedColorNormal,
-		URL:       fmt.Sprintf("https://live.bilibili.com/%d", wh.RoomInfo.RoomID),
-		Fields: []*discordgo.MessageEmbedField{{
-			Name:   wh.RoomInfo.Title,
-			Value:  fmt.Sprintf("Code:%d", wh.Type),
-			Inline: false,
-		}},
-	}
-
+	embed := discord.InfoEmbed("DDTV Webhook Update").
+		Description(wh.Type.MessagePrompt(wh.RoomInfo.Uname, wh.RoomInfo.RoomID)).
+		Author(fmt.Sprintf("%s [%d]", wh.UserInfo.Name, wh.UserInfo.UID),
+			fmt.Sprintf("https://space.bilibili.com/%d", wh.UserInfo.UID), wh.RoomInfo.Face).
+		Provider("DDTV", "https://ddtv.pro").
+		URL(fmt.Sprintf("https://live.bilibili.com/%d", wh.RoomInfo.RoomID)).
+		Field(wh.RoomInfo.Title, fmt.Sprintf("Code:%d", wh.Type))
+	// can be empty
 	if wh.RoomInfo.CoverFromUser != "" {
-		embed.Image = &discordgo.MessageEmbedImage{
-			URL: wh.RoomInfo.CoverFromUser,
-		}
+		embed.Image(wh.RoomInfo.CoverFromUser)
 	}
+	return embed.Build()
+}
 
-	return embed
+// DigestText plain-text counterpart of DigestEmbed, for messengers without embeds.
+func (wh WebHook) DigestText() string {
+	switch wh.Type {
+	case HookSpaceIsInsufficientWarn, HookLoginFailure, HookLoginWillExpireSoon, HookUpdateAvailable:
+		return "[DDTV] " + wh.Type.MessagePrompt("", 0)
+	}
+	text := fmt.Sprintf("[DDTV] %s\n%s [%d]", wh.Type.MessagePrompt(wh.RoomInfo.Uname, wh.RoomInfo.RoomID),
+		wh.UserInfo.Name, wh.UserInfo.UID)
+	if wh.RoomInfo.Title != "" {
+		text += "\n" + wh.RoomInfo.Title
+	}
+	return text + fmt.Sprintf("\nhttps://live.bilibili.com/%d", wh.RoomInfo.RoomID)
 }
 
 type WebHook struct {
@@ -213,6 +189,25 @@ var hookCollection []HookType = []HookType{HookStartLive, HookStopLive, HookStar
 	HookSaveGiftComplete, HookSaveGuardComplete, HookRunShellComplete, HookDownloadEndMissionSuccess, HookSpaceIsInsufficientWarn, HookLoginFailure, HookLoginWillExpireSoon, HookUpdateAvailable,
 	HookShellExecutionComplete}
 
+// hookNames names of the hook types, indexed by code.
+var hookNames = []string{"StartLive", "StopLive", "StartRec", "RecComplete", "CancelRec", "TranscodingComplete",
+	"SaveDanmuComplete", "SaveSCComplete", "SaveGiftComplete", "SaveGuardComplete", "RunShellComplete",
+	"DownloadEndMissionSuccess", "SpaceIsInsufficientWarn", "LoginFailure", "LoginWillExpireSoon", "UpdateAvailable",
+	"ShellExecutionComplete"}
+
+// Name the DDTV name of the hook type.
+func (h HookType) Name() string {
+	if h < 0 || int(h) >= len(hookNames) {
+		return fmt.Sprintf("Unknown(%d)", int(h))
+	}
+	return hookNames[h]
+}
+
+// HookTypes every known hook type, by code.
+func HookTypes() []HookType {
+	return append([]HookType(nil), hookCollection...)
+}
+
 func parseHook(code int) (HookType, error) {
 	if code < 0 || code >= len(hookCollection) {
 		return -1, fmt.Errorf("invalid webhook code")
@@ -0,0 +1,92 @@
+package discord
+
+import (
+	"dalian-bot/internal/core"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// AutocompleteChoiceLimit choices discord shows for an option.
+	AutocompleteChoiceLimit = 25
+	// choiceNameLimit characters of the name of a choice.
+	choiceNameLimit = 100
+)
+
+// IAutocompleteCommand Plugins suggesting values of the options they registered with Autocomplete.
+// Autocomplete interactions are routed by Service.HandleAutocomplete to the plugin owning the command only,
+// they never reach Trigger.
+type IAutocompleteCommand interface {
+	// DoAutocomplete return the choices for the focused option. At most AutocompleteChoiceLimit are shown.
+	DoAutocomplete(i *discordgo.InteractionCreate, focused *discordgo.ApplicationCommandInteractionDataOption) ([]*discordgo.ApplicationCommandOptionChoice, error)
+}
+
+// HandleAutocomplete answer an autocomplete interaction with the choices of the plugin owning the command.
+// Return false if the interaction is not an autocomplete one.
+func (s *Service) HandleAutocomplete(i *discordgo.InteractionCreate) bool {
+	if i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+		return false
+	}
+	commandData := i.ApplicationCommandData()
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	if completer, ok := s.commandOwner(commandData.Name).(IAutocompleteCommand); ok {
+		if focused := FocusedOption(commandData.Options); focused != nil {
+			var err error
+			if choices, err = completer.DoAutocomplete(i, focused); err != nil {
+				core.Logger.Warnf("Error autocompleting command %s: %v", commandData.Name, err)
+			}
+		}
+	}
+	if len(choices) > AutocompleteChoiceLimit {
+		choices = choices[:AutocompleteChoiceLimit]
+	}
+	for _, choice := range choices {
+		choice.Name = truncateText(choice.Name, choiceNameLimit)
+	}
+	// an empty answer still tells discord there's nothing to suggest
+	if err := s.InteractionRespondComplex(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}); err != nil {
+		core.Logger.Warnf("Error answering autocomplete: %v", err)
+	}
+	return true
+}
+
+// FocusedOption the option being typed, looked up through subcommands.
+func FocusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+		if focused := FocusedOption(opt.Options); focused != nil {
+			return focused
+		}
+	}
+	return nil
+}
+
+// FocusedValue what has been typed so far. Values of number options are typed text as well.
+func FocusedValue(focused *discordgo.ApplicationCommandInteractionDataOption) string {
+	if focused.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(focused.Value)
+}
+
+// FilterChoices the choices whose name contains query, ignoring case, up to AutocompleteChoiceLimit.
+func FilterChoices(query string, choices []*discordgo.ApplicationCommandOptionChoice) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var filtered []*discordgo.ApplicationCommandOptionChoice
+	for _, choice := range choices {
+		if len(filtered) == AutocompleteChoiceLimit {
+			break
+		}
+		if strings.Contains(strings.ToLower(choice.Name), query) {
+			filtered = append(filtered, choice)
+		}
+	}
+	return filtered
+}
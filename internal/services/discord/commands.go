@@ -0,0 +1,297 @@
+package discord
+
+import (
+	"dalian-bot/internal/core"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// globalScope scope of commands available everywhere.
+const globalScope = ""
+
+// commandRegistry Application commands the bot should have, per scope: a guild id, or globalScope.
+// Nothing is sent to discord until SyncCommands, which overwrites a scope only when it differs.
+type commandRegistry struct {
+	lock    sync.Mutex
+	desired map[string]map[string]*discordgo.ApplicationCommand // scope : command key : command
+	plugin  map[string]core.IPlugin                             // command name : plugin owning it
+	synced  bool                                                // registrations apply immediately once true
+}
+
+func commandKey(cmd *discordgo.ApplicationCommand) string {
+	return fmt.Sprintf("%d/%s", commandType(cmd), cmd.Name)
+}
+
+func commandType(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommandType {
+	if cmd.Type == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return cmd.Type
+}
+
+// pluginScopes Scopes plugin commands are registered in.
+func (s *Service) pluginScopes() []string {
+	if len(s.CommandGuildIDs) > 0 {
+		return s.CommandGuildIDs
+	}
+	return []string{globalScope}
+}
+
+// setCommand add or remove (cmd == nil) a desired command. Must be called with the lock held.
+func (s *Service) setCommand(scope, key string, cmd *discordgo.ApplicationCommand) {
+	if s.commands.desired == nil {
+		s.commands.desired = make(map[string]map[string]*discordgo.ApplicationCommand)
+		s.commands.plugin = make(map[string]core.IPlugin)
+	}
+	if s.commands.desired[scope] == nil {
+		s.commands.desired[scope] = make(map[string]*discordgo.ApplicationCommand)
+	}
+	if cmd == nil {
+		delete(s.commands.desired[scope], key)
+	} else {
+		s.commands.desired[scope][key] = cmd
+	}
+}
+
+// RegisterSlashCommand Add the commands of the plugin, globally or in the guilds of ServiceConfig.CommandGuildIDs.
+func (s *Service) RegisterSlashCommand(plugin core.IPlugin) error {
+	slash, ok := plugin.(ISlashCommand)
+	if !ok {
+		core.Logger.Errorf("NOT A SLASH CMD")
+		return nil
+	}
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	for _, cmd := range slash.GetAppCommandsMap() {
+		for _, scope := range s.pluginScopes() {
+			s.setCommand(scope, commandKey(cmd), cmd)
+		}
+		s.commands.plugin[cmd.Name] = plugin
+	}
+	core.Logger.Debugf("Registered slash command for plugin:%s", plugin.GetName())
+	return s.syncIfStarted(s.pluginScopes()...)
+}
+
+// DisposeSlashCommand Remove the commands of the plugin.
+func (s *Service) DisposeSlashCommand(plugin core.IPlugin) error {
+	slash, ok := plugin.(ISlashCommand)
+	if !ok {
+		return nil
+	}
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	for _, cmd := range slash.GetAppCommandsMap() {
+		for _, scope := range s.pluginScopes() {
+			s.setCommand(scope, commandKey(cmd), nil)
+		}
+		delete(s.commands.plugin, cmd.Name)
+	}
+	return s.syncIfStarted(s.pluginScopes()...)
+}
+
+// RegisterGuildCommand Add or replace a command only available in the guild.
+func (s *Service) RegisterGuildCommand(guildID string, cmd *discordgo.ApplicationCommand) error {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	s.setCommand(guildID, commandKey(cmd), cmd)
+	return s.syncIfStarted(guildID)
+}
+
+// DisposeGuildCommand Remove a chat input command added by RegisterGuildCommand.
+func (s *Service) DisposeGuildCommand(guildID, name string) error {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	s.setCommand(guildID, commandKey(&discordgo.ApplicationCommand{Name: name}), nil)
+	return s.syncIfStarted(guildID)
+}
+
+// IsPluginCommand Return true if a plugin registered a command of the name.
+func (s *Service) IsPluginCommand(name string) bool {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	return s.commands.plugin[name] != nil
+}
+
+// commandOwner the plugin that registered the command of the name, if any.
+func (s *Service) commandOwner(name string) core.IPlugin {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	return s.commands.plugin[name]
+}
+
+// Helpers Return the help of every plugin with commands, see IDiscordHelper.
+func (s *Service) Helpers() []IDiscordHelper {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	var helpers []IDiscordHelper
+	seen := make(map[core.IPlugin]bool)
+	for _, plugin := range s.commands.plugin {
+		if helper, ok := plugin.(IDiscordHelper); ok && !seen[plugin] {
+			seen[plugin] = true
+			helpers = append(helpers, helper)
+		}
+	}
+	return helpers
+}
+
+// SyncCommands Bring every scope in line with the registered commands, then apply later registrations immediately.
+// Call it once every plugin is registered. The global scope is always checked, so commands left over by a
+// previous scope configuration are removed.
+func (s *Service) SyncCommands() error {
+	s.commands.lock.Lock()
+	defer s.commands.lock.Unlock()
+	s.commands.synced = true
+	scopes := []string{globalScope}
+	for scope := range s.commands.desired {
+		if scope != globalScope {
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, scope := range s.pluginScopes() {
+		if _, ok := s.commands.desired[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	return s.syncScopes(scopes...)
+}
+
+// syncIfStarted must be called with the lock held.
+func (s *Service) syncIfStarted(scopes ...string) error {
+	if !s.commands.synced {
+		return nil
+	}
+	return s.syncScopes(scopes...)
+}
+
+// syncScopes must be called with the lock held.
+func (s *Service) syncScopes(scopes ...string) error {
+	for _, scope := range scopes {
+		if err := s.syncScope(scope); err != nil {
+			return fmt.Errorf("syncing commands of scope [%s]: %w", scope, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) syncScope(scope string) error {
+	var desired []*discordgo.ApplicationCommand
+	for _, cmd := range s.commands.desired[scope] {
+		desired = append(desired, cmd)
+	}
+	sort.Slice(desired, func(i, j int) bool { return commandKey(desired[i]) < commandKey(desired[j]) })
+	existing, err := s.Session.ApplicationCommands(s.DiscordAccountConfig.BotID, scope)
+	if err != nil {
+		return err
+	}
+	if sameCommands(scope, existing, desired) {
+		core.Logger.Debugf("Commands of scope [%s] are up to date.", scope)
+		return nil
+	}
+	if desired == nil {
+		// discord expects an array
+		desired = []*discordgo.ApplicationCommand{}
+	}
+	if _, err := s.Session.ApplicationCommandBulkOverwrite(s.DiscordAccountConfig.BotID, scope, desired); err != nil {
+		return err
+	}
+	core.Logger.Infof("Overwrote %d command(s) of scope [%s].", len(desired), scope)
+	return nil
+}
+
+// commandSpec The part of a command that matters for comparison, with discord defaults filled in.
+type commandSpec struct {
+	Type                     discordgo.ApplicationCommandType
+	Name                     string
+	Description              string
+	DefaultMemberPermissions int64 // -1 when unset
+	DMPermission             bool
+	NSFW                     bool
+	Options                  []optionSpec
+}
+
+type optionSpec struct {
+	Type         discordgo.ApplicationCommandOptionType
+	Name         string
+	Description  string
+	ChannelTypes []discordgo.ChannelType
+	Required     bool
+	Autocomplete bool
+	Options      []optionSpec
+	Choices      []string
+	MinValue     *float64
+	MaxValue     float64
+	MinLength    *int
+	MaxLength    int
+}
+
+func newCommandSpec(scope string, cmd *discordgo.ApplicationCommand) commandSpec {
+	spec := commandSpec{
+		Type:                     commandType(cmd),
+		Name:                     cmd.Name,
+		Description:              cmd.Description,
+		DefaultMemberPermissions: -1,
+		DMPermission:             true,
+		Options:                  newOptionSpecs(cmd.Options),
+	}
+	if cmd.DefaultMemberPermissions != nil {
+		spec.DefaultMemberPermissions = *cmd.DefaultMemberPermissions
+	}
+	// only meaningful for global commands
+	if cmd.DMPermission != nil && scope == globalScope {
+		spec.DMPermission = *cmd.DMPermission
+	}
+	if cmd.NSFW != nil {
+		spec.NSFW = *cmd.NSFW
+	}
+	return spec
+}
+
+// newOptionSpecs empty lists become nil, choice values are compared as text since numbers come back as float64.
+func newOptionSpecs(options []*discordgo.ApplicationCommandOption) []optionSpec {
+	var specs []optionSpec
+	for _, option := range options {
+		spec := optionSpec{
+			Type:         option.Type,
+			Name:         option.Name,
+			Description:  option.Description,
+			ChannelTypes: append([]discordgo.ChannelType(nil), option.ChannelTypes...),
+			Required:     option.Required,
+			Autocomplete: option.Autocomplete,
+			Options:      newOptionSpecs(option.Options),
+			MinValue:     option.MinValue,
+			MaxValue:     option.MaxValue,
+			MinLength:    option.MinLength,
+			MaxLength:    option.MaxLength,
+		}
+		if len(spec.ChannelTypes) == 0 {
+			spec.ChannelTypes = nil
+		}
+		for _, choice := range option.Choices {
+			spec.Choices = append(spec.Choices, fmt.Sprintf("%s=%v", choice.Name, choice.Value))
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// sameCommands Return true if existing holds exactly the desired commands, in any order.
+func sameCommands(scope string, existing, desired []*discordgo.ApplicationCommand) bool {
+	if len(existing) != len(desired) {
+		return false
+	}
+	existingSpecs := make(map[string]commandSpec)
+	for _, cmd := range existing {
+		existingSpecs[commandKey(cmd)] = newCommandSpec(scope, cmd)
+	}
+	for _, cmd := range desired {
+		spec, ok := existingSpecs[commandKey(cmd)]
+		if !ok || !reflect.DeepEqual(spec, newCommandSpec(scope, cmd)) {
+			return false
+		}
+	}
+	return true
+}
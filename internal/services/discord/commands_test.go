@@ -0,0 +1,99 @@
+package discord_test
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/discord/discordtest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+)
+
+type commandPlugin struct {
+	core.Plugin
+	discord.SlashCommandUtil
+}
+
+func (p *commandPlugin) DoNamedInteraction(*core.Bot, *discordgo.InteractionCreate) error { return nil }
+func (p *commandPlugin) Init(*core.ServiceRegistry) error                                 { return nil }
+func (p *commandPlugin) Trigger(core.Trigger)                                             {}
+
+func newCommandPlugin(description string) *commandPlugin {
+	p := &commandPlugin{Plugin: core.Plugin{Name: "test"}}
+	p.AppCommandsMap = discord.AppCommandsMap{}
+	p.AppCommandsMap.RegisterCommand(&discordgo.ApplicationCommand{
+		Name:        "test",
+		Description: description,
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "count",
+			Description: "count",
+			Choices:     []*discordgo.ApplicationCommandOptionChoice{{Name: "one", Value: 1}},
+		}},
+	})
+	return p
+}
+
+// boot simulate a start of the bot: plugins register, then commands are synced.
+func boot(t *testing.T, session *discordtest.FakeSession, config discord.ServiceConfig, plugin core.IPlugin) *discord.Service {
+	t.Helper()
+	s := &discord.Service{ServiceConfig: config}
+	s.Attach(session, discordtest.BotUserID)
+	if err := s.RegisterSlashCommand(plugin); err != nil {
+		t.Fatalf("RegisterSlashCommand: %v", err)
+	}
+	if calls := session.CallsOf("ApplicationCommandBulkOverwrite"); len(calls) != 0 {
+		t.Fatalf("nothing should be sent before SyncCommands, got %+v", calls)
+	}
+	if err := s.SyncCommands(); err != nil {
+		t.Fatalf("SyncCommands: %v", err)
+	}
+	return s
+}
+
+func TestSyncCommandsOnlyOverwritesChanges(t *testing.T) {
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	session := discordtest.NewFakeSession()
+
+	boot(t, session, discord.ServiceConfig{}, newCommandPlugin("first"))
+	if calls := session.CallsOf("ApplicationCommandBulkOverwrite"); len(calls) != 1 || len(calls[0].Commands) != 1 {
+		t.Fatalf("want one overwrite on first boot, got %+v", calls)
+	}
+	id := session.Commands("")[0].ID
+
+	// a restart with the same commands leaves them alone, even though choice values come back as float64
+	session.Reset()
+	for _, cmd := range session.Commands("") {
+		cmd.Options[0].Choices[0].Value = float64(1)
+	}
+	boot(t, session, discord.ServiceConfig{}, newCommandPlugin("first"))
+	if calls := session.CallsOf("ApplicationCommandBulkOverwrite"); len(calls) != 0 {
+		t.Fatalf("want no overwrite for unchanged commands, got %+v", calls)
+	}
+
+	session.Reset()
+	boot(t, session, discord.ServiceConfig{}, newCommandPlugin("second"))
+	if commands := session.Commands(""); len(commands) != 1 || commands[0].Description != "second" || commands[0].ID != id {
+		t.Fatalf("want the command updated in place, got %+v", commands)
+	}
+
+	// moving to guild scope clears the global commands
+	session.Reset()
+	s := boot(t, session, discord.ServiceConfig{CommandGuildIDs: []string{discordtest.GuildID}}, newCommandPlugin("second"))
+	if len(session.Commands("")) != 0 || len(session.Commands(discordtest.GuildID)) != 1 {
+		t.Fatalf("want commands moved to the guild, got global %+v", session.Commands(""))
+	}
+
+	// after the sync, guild commands apply immediately and share the scope with plugin commands
+	if err := s.RegisterGuildCommand(discordtest.GuildID, &discordgo.ApplicationCommand{Name: "faq", Description: "faq"}); err != nil {
+		t.Fatalf("RegisterGuildCommand: %v", err)
+	}
+	if len(session.Commands(discordtest.GuildID)) != 2 {
+		t.Fatalf("want both commands in the guild, got %+v", session.Commands(discordtest.GuildID))
+	}
+	s.DisposeGuildCommand(discordtest.GuildID, "faq")
+	if commands := session.Commands(discordtest.GuildID); len(commands) != 1 || commands[0].Name != "test" {
+		t.Fatalf("want only the plugin command left, got %+v", commands)
+	}
+}
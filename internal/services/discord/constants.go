@@ -12,6 +12,8 @@ const (
 const (
 	EmojiLeftArrow  = "\u2B05"
 	EmojiRightArrow = "\u27A1"
+	EmojiFirstPage  = "\u23EE"
+	EmojiLastPage   = "\u23ED"
 )
 
 const TriggerTypeDiscord core.TriggerType = "discord"
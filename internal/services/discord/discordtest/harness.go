@@ -0,0 +1,231 @@
+package discordtest
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/discord"
+	"github.com/bwmarrin/discordgo"
+	"go.uber.org/zap"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// Default identities used by the builders.
+const (
+	GuildID   = "900000000000000001"
+	ChannelID = "900000000000000002"
+	UserID    = "900000000000000003"
+	BotUserID = "900000000000000004"
+)
+
+// Harness A bot wired with a discord.Service talking to a FakeSession.
+// Plugins receive triggers synchronously, so their direct responses can be asserted right after dispatching.
+// Work they hand to goroutines of their own, e.g. pager stages or DDTV notifications, completes later and must be polled.
+type Harness struct {
+	T              *testing.T
+	Bot            *core.Bot
+	Session        *FakeSession
+	DiscordService *discord.Service
+}
+
+// NewHarness prepare a bot with a fake-backed discord.Service registered.
+// Register other services needed by the plugins to Bot.ServiceRegistry before calling RegisterPlugin.
+func NewHarness(t *testing.T) *Harness {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	bot := core.NewBot()
+	session := NewFakeSession()
+	discordService := &discord.Service{}
+	if err := discordService.Init(bot.ServiceRegistry); err != nil {
+		t.Fatalf("discord service init failed: %v", err)
+	}
+	discordService.Attach(session, BotUserID)
+	// apply commands as soon as plugins register them
+	if err := discordService.SyncCommands(); err != nil {
+		t.Fatalf("command sync failed: %v", err)
+	}
+	return &Harness{T: t, Bot: bot, Session: session, DiscordService: discordService}
+}
+
+// RegisterPlugin initialize the plugin with the harness registry and register it to the bot.
+func (h *Harness) RegisterPlugin(f func(reg *core.ServiceRegistry) core.IPlugin) core.IPlugin {
+	h.T.Helper()
+	plugin := f(h.Bot.ServiceRegistry)
+	if err := h.Bot.PluginRegistry.RegisterPlugin(plugin); err != nil {
+		h.T.Fatalf("plugin registration failed: %v", err)
+	}
+	return plugin
+}
+
+// Trigger deliver the trigger to every enabled plugin, one after another.
+func (h *Harness) Trigger(t core.Trigger) {
+	t.Bot = h.Bot
+	for _, plugin := range h.Bot.PluginRegistry.EnabledPlugins() {
+		plugin.Trigger(t)
+	}
+}
+
+// Interact dispatch an InteractionCreate event, or a message command event, as the service does.
+// Autocomplete interactions are answered by the plugin owning the command.
+func (h *Harness) Interact(i *discordgo.InteractionCreate) {
+	if h.DiscordService.HandleAutocomplete(i) {
+		return
+	}
+	h.Trigger(core.Trigger{
+		Type:  discord.TriggerTypeDiscord,
+		Event: discord.InteractionEvent(i),
+	})
+}
+
+// Say put a message from UserID into the channel history and dispatch its MessageCreate event.
+func (h *Harness) Say(channelID, content string) *discordgo.Message {
+	m := h.Session.AddMessage(&discordgo.Message{
+		ChannelID: channelID,
+		GuildID:   GuildID,
+		Content:   content,
+		Author:    &discordgo.User{ID: UserID, Username: "tester"},
+	})
+	h.Trigger(core.Trigger{
+		Type: discord.TriggerTypeDiscord,
+		Event: discord.Event{
+			EventType:     discord.EventTypeMessageCreate,
+			MessageCreate: &discordgo.MessageCreate{Message: m},
+		},
+	})
+	return m
+}
+
+// React dispatch a MessageReactionAdd event of the user on the message.
+func (h *Harness) React(m *discordgo.Message, emoji, userID string) {
+	h.Trigger(core.Trigger{
+		Type: discord.TriggerTypeDiscord,
+		Event: discord.Event{
+			EventType: discord.EventTypeMessageReactionAdd,
+			MessageReactionAdd: &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+				UserID:    userID,
+				MessageID: m.ID,
+				ChannelID: m.ChannelID,
+				GuildID:   m.GuildID,
+				Emoji:     discordgo.Emoji{Name: emoji},
+			}},
+		},
+	})
+}
+
+// LastResponse Return the latest interaction response, failing the test if there is none.
+func (h *Harness) LastResponse() Call {
+	h.T.Helper()
+	calls := h.Session.CallsOf("InteractionRespond")
+	if len(calls) == 0 {
+		h.T.Fatalf("no interaction response recorded")
+	}
+	return calls[len(calls)-1]
+}
+
+var interactionCount int64
+
+func newInteraction(t discordgo.InteractionType, data discordgo.InteractionData) *discordgo.InteractionCreate {
+	id := strconv.FormatInt(atomic.AddInt64(&interactionCount, 1), 10)
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        "interaction-" + id,
+		Type:      t,
+		Data:      data,
+		GuildID:   GuildID,
+		ChannelID: ChannelID,
+		Member:    &discordgo.Member{User: &discordgo.User{ID: UserID, Username: "tester"}},
+		Token:     "token-" + id,
+	}}
+}
+
+// SlashCommand build a chat-input command interaction.
+func SlashCommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionApplicationCommand, discordgo.ApplicationCommandInteractionData{
+		Name:    name,
+		Options: options,
+	})
+}
+
+// Autocomplete build an autocomplete interaction of a chat-input command. Mark the option typed with Focus.
+func Autocomplete(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionApplicationCommandAutocomplete, discordgo.ApplicationCommandInteractionData{
+		Name:    name,
+		Options: options,
+	})
+}
+
+// Focus mark the option as the one being typed.
+func Focus(option *discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	option.Focused = true
+	return option
+}
+
+// MessageCommand build a message context-menu command interaction on the given message.
+func MessageCommand(name string, target *discordgo.Message) *discordgo.InteractionCreate {
+	i := newInteraction(discordgo.InteractionApplicationCommand, discordgo.ApplicationCommandInteractionData{
+		Name:     name,
+		TargetID: target.ID,
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Messages: map[string]*discordgo.Message{target.ID: target},
+		},
+	})
+	i.ChannelID = target.ChannelID
+	return i
+}
+
+// ModalSubmit build the submission of a modal, values by text input custom id, decoded as discordgo does.
+func ModalSubmit(customID string, values map[string]string) *discordgo.InteractionCreate {
+	var rows []discordgo.MessageComponent
+	for inputID, value := range values {
+		rows = append(rows, &discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.TextInput{CustomID: inputID, Value: value},
+		}})
+	}
+	return newInteraction(discordgo.InteractionModalSubmit, discordgo.ModalSubmitInteractionData{
+		CustomID:   customID,
+		Components: rows,
+	})
+}
+
+// ButtonClick build a component interaction on the given message.
+func ButtonClick(m *discordgo.Message, customID string) *discordgo.InteractionCreate {
+	i := newInteraction(discordgo.InteractionMessageComponent, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.ButtonComponent,
+	})
+	i.ChannelID = m.ChannelID
+	i.Message = m
+	return i
+}
+
+// SelectMenuChoice build a select menu interaction on the given message.
+func SelectMenuChoice(m *discordgo.Message, customID string, values ...string) *discordgo.InteractionCreate {
+	i := newInteraction(discordgo.InteractionMessageComponent, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.SelectMenuComponent,
+		Values:        values,
+	})
+	i.ChannelID = m.ChannelID
+	i.Message = m
+	return i
+}
+
+func SubCommandGroup(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommandGroup, Options: options}
+}
+
+func SubCommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+}
+
+func StringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+// IntOption integers arrive as float64 in decoded interactions.
+func IntOption(name string, value int64) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+}
+
+func BoolOption(name string, value bool) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionBoolean, Value: value}
+}
@@ -0,0 +1,477 @@
+// Package discordtest
+// In-process fakes for driving plugins through discord.Service without a Discord connection.
+package discordtest
+
+import (
+	"dalian-bot/internal/services/discord"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Call A recorded outgoing API call. Only fields relevant to the Method are filled.
+type Call struct {
+	Method      string
+	ChannelID   string
+	GuildID     string
+	Content     string
+	Embeds      []*discordgo.MessageEmbed
+	Components  []discordgo.MessageComponent
+	Interaction *discordgo.Interaction
+	Response    *discordgo.InteractionResponse
+	Commands    []*discordgo.ApplicationCommand
+	Message     *discordgo.Message // the message created or edited by this call, if any
+	// AllowedMentions of sent messages.
+	AllowedMentions *discordgo.MessageAllowedMentions
+	Emoji           string
+	UserID          string
+}
+
+// FakeSession Records every outgoing call and keeps just enough state (channel history,
+// interaction responses, application commands) to answer follow-up queries consistently.
+type FakeSession struct {
+	mu        sync.Mutex
+	calls     []Call
+	lastID    int64
+	channels  map[string]*discordgo.Channel                       // channelID : channel
+	messages  map[string][]*discordgo.Message                     // channelID : messages, oldest first
+	responses map[string]*discordgo.Message                       // interactionID : original response
+	commands  map[string]map[string]*discordgo.ApplicationCommand // guildID ("" for global) : commandID : command
+	failures  map[string]error                                    // method : error returned by its next call
+}
+
+var _ discord.Session = (*FakeSession)(nil)
+
+func NewFakeSession() *FakeSession {
+	return &FakeSession{
+		channels:  make(map[string]*discordgo.Channel),
+		messages:  make(map[string][]*discordgo.Message),
+		responses: make(map[string]*discordgo.Message),
+		commands:  make(map[string]map[string]*discordgo.ApplicationCommand),
+		failures:  make(map[string]error),
+	}
+}
+
+// FailNext make the next call of method return err.
+func (f *FakeSession) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = err
+}
+
+// Calls Return a copy of every recorded call, oldest first.
+func (f *FakeSession) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsOf Return recorded calls of the given method, oldest first.
+func (f *FakeSession) CallsOf(method string) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// LastCall Return the latest recorded call, false if nothing was called.
+func (f *FakeSession) LastCall() (Call, bool) {
+	calls := f.Calls()
+	if len(calls) == 0 {
+		return Call{}, false
+	}
+	return calls[len(calls)-1], true
+}
+
+// Reset forget recorded calls, keeping the state.
+func (f *FakeSession) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// AddMessage put a message into the channel history as if it was sent by someone else.
+func (f *FakeSession) AddMessage(m *discordgo.Message) *discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m.ID == "" {
+		m.ID = f.nextID()
+	}
+	f.messages[m.ChannelID] = append(f.messages[m.ChannelID], m)
+	return m
+}
+
+// Commands Return registered application commands of the guild ("" for global ones).
+func (f *FakeSession) Commands(guildID string) []*discordgo.ApplicationCommand {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var cmds []*discordgo.ApplicationCommand
+	for _, cmd := range f.commands[guildID] {
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// record must be called with lock held. Return the pending failure of the method, if any.
+func (f *FakeSession) record(c Call) error {
+	f.calls = append(f.calls, c)
+	if err, ok := f.failures[c.Method]; ok {
+		delete(f.failures, c.Method)
+		return err
+	}
+	return nil
+}
+
+func (f *FakeSession) nextID() string {
+	f.lastID++
+	return strconv.FormatInt(f.lastID, 10)
+}
+
+// newBotMessage must be called with lock held.
+func (f *FakeSession) newBotMessage(channelID, content string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) *discordgo.Message {
+	m := &discordgo.Message{
+		ID:         f.nextID(),
+		ChannelID:  channelID,
+		Content:    content,
+		Embeds:     embeds,
+		Components: components,
+		Author:     &discordgo.User{ID: BotUserID, Bot: true},
+	}
+	f.messages[channelID] = append(f.messages[channelID], m)
+	return m
+}
+
+func (f *FakeSession) findMessage(channelID, messageID string) *discordgo.Message {
+	for _, m := range f.messages[channelID] {
+		if m.ID == messageID {
+			return m
+		}
+	}
+	return nil
+}
+
+// AddChannel make the channel known to Channel.
+func (f *FakeSession) AddChannel(c *discordgo.Channel) *discordgo.Channel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channels[c.ID] = c
+	return c
+}
+
+func (f *FakeSession) Channel(channelID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(Call{Method: "Channel", ChannelID: channelID}); err != nil {
+		return nil, err
+	}
+	c, ok := f.channels[channelID]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %s", channelID)
+	}
+	return c, nil
+}
+
+// UserChannelCreate Return the DM channel of the user, `dm-<user id>`, creating it on first use.
+func (f *FakeSession) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(Call{Method: "UserChannelCreate", UserID: recipientID}); err != nil {
+		return nil, err
+	}
+	id := "dm-" + recipientID
+	if _, ok := f.channels[id]; !ok {
+		f.channels[id] = &discordgo.Channel{ID: id, Type: discordgo.ChannelTypeDM}
+	}
+	return f.channels[id], nil
+}
+
+func (f *FakeSession) ChannelEditComplex(channelID string, data *discordgo.ChannelEdit, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(Call{Method: "ChannelEdit", ChannelID: channelID}); err != nil {
+		return nil, err
+	}
+	c, ok := f.channels[channelID]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %s", channelID)
+	}
+	if data.Name != "" {
+		c.Name = data.Name
+	}
+	if c.ThreadMetadata == nil && (data.Archived != nil || data.Locked != nil) {
+		c.ThreadMetadata = &discordgo.ThreadMetadata{}
+	}
+	if data.Archived != nil {
+		c.ThreadMetadata.Archived = *data.Archived
+	}
+	if data.Locked != nil {
+		c.ThreadMetadata.Locked = *data.Locked
+	}
+	return c, nil
+}
+
+// MessageThreadStartComplex start a thread known to Channel, with the id of the message as discord does.
+func (f *FakeSession) MessageThreadStartComplex(channelID, messageID string, data *discordgo.ThreadStart, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.findMessage(channelID, messageID)
+	if err := f.record(Call{Method: "MessageThreadStart", ChannelID: channelID, Content: data.Name, Message: m}); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("unknown message %s in channel %s", messageID, channelID)
+	}
+	thread := &discordgo.Channel{
+		ID:             messageID,
+		ParentID:       channelID,
+		Name:           data.Name,
+		Type:           discordgo.ChannelTypeGuildPublicThread,
+		ThreadMetadata: &discordgo.ThreadMetadata{AutoArchiveDuration: data.AutoArchiveDuration},
+	}
+	f.channels[thread.ID] = thread
+	return thread, nil
+}
+
+func (f *FakeSession) ChannelMessages(channelID string, limit int, beforeID, _, _ string, _ ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(Call{Method: "ChannelMessages", ChannelID: channelID}); err != nil {
+		return nil, err
+	}
+	before, _ := strconv.ParseInt(beforeID, 10, 64)
+	var result []*discordgo.Message
+	history := f.messages[channelID]
+	for i := len(history) - 1; i >= 0 && len(result) < limit; i-- {
+		id, _ := strconv.ParseInt(history[i].ID, 10, 64)
+		if beforeID == "" || id < before {
+			result = append(result, history[i])
+		}
+	}
+	return result, nil
+}
+
+func (f *FakeSession) ChannelMessageSend(channelID string, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content})
+}
+
+func (f *FakeSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	embeds := data.Embeds
+	if data.Embed != nil {
+		embeds = append(embeds, data.Embed)
+	}
+	m := f.newBotMessage(channelID, data.Content, embeds, data.Components)
+	if err := f.record(Call{Method: "ChannelMessageSend", ChannelID: channelID, Content: data.Content, Embeds: embeds, Components: data.Components, Message: m, AllowedMentions: data.AllowedMentions}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *FakeSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}})
+}
+
+func (f *FakeSession) ChannelMessageEditComplex(edit *discordgo.MessageEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.findMessage(edit.Channel, edit.ID)
+	call := Call{Method: "ChannelMessageEdit", ChannelID: edit.Channel, Embeds: edit.Embeds, Components: edit.Components, Message: m}
+	if edit.Content != nil {
+		call.Content = *edit.Content
+	}
+	if err := f.record(call); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("unknown message %s in channel %s", edit.ID, edit.Channel)
+	}
+	if edit.Content != nil {
+		m.Content = *edit.Content
+	}
+	m.Embeds = edit.Embeds
+	m.Components = edit.Components
+	return m, nil
+}
+
+func (f *FakeSession) ChannelFileSend(channelID, name string, r io.Reader, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.newBotMessage(channelID, "", nil, nil)
+	m.Attachments = []*discordgo.MessageAttachment{{Filename: name, Size: len(content)}}
+	if err := f.record(Call{Method: "ChannelFileSend", ChannelID: channelID, Content: string(content), Message: m}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *FakeSession) MessageReactionAdd(channelID, messageID, emojiID string, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record(Call{Method: "MessageReactionAdd", ChannelID: channelID, Message: f.findMessage(channelID, messageID), Emoji: emojiID})
+}
+
+func (f *FakeSession) MessageReactionRemove(channelID, messageID, emojiID, userID string, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record(Call{Method: "MessageReactionRemove", ChannelID: channelID, Message: f.findMessage(channelID, messageID), Emoji: emojiID, UserID: userID})
+}
+
+func (f *FakeSession) MessageReactionsRemoveAll(channelID, messageID string, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record(Call{Method: "MessageReactionsRemoveAll", ChannelID: channelID, Message: f.findMessage(channelID, messageID)})
+}
+
+func (f *FakeSession) InteractionRespond(i *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := Call{Method: "InteractionRespond", ChannelID: i.ChannelID, GuildID: i.GuildID, Interaction: i, Response: resp}
+	var data discordgo.InteractionResponseData
+	if resp.Data != nil {
+		data = *resp.Data
+	}
+	call.Content, call.Embeds, call.Components = data.Content, data.Embeds, data.Components
+	switch resp.Type {
+	case discordgo.InteractionResponseUpdateMessage:
+		// edit the message the component is attached to
+		if i.Message != nil {
+			if m := f.findMessage(i.ChannelID, i.Message.ID); m != nil {
+				m.Content, m.Embeds, m.Components = data.Content, data.Embeds, data.Components
+				call.Message = m
+			}
+		}
+	case discordgo.InteractionResponseChannelMessageWithSource, discordgo.InteractionResponseDeferredChannelMessageWithSource:
+		m := f.newBotMessage(i.ChannelID, data.Content, data.Embeds, data.Components)
+		m.GuildID = i.GuildID
+		m.Flags = data.Flags
+		f.responses[i.ID] = m
+		call.Message = m
+	}
+	return f.record(call)
+}
+
+func (f *FakeSession) InteractionResponse(i *discordgo.Interaction, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.responses[i.ID]
+	if err := f.record(Call{Method: "InteractionResponse", ChannelID: i.ChannelID, Interaction: i, Message: m}); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("interaction %s has not been responded", i.ID)
+	}
+	return m, nil
+}
+
+func (f *FakeSession) InteractionResponseEdit(i *discordgo.Interaction, edit *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.responses[i.ID]
+	call := Call{Method: "InteractionResponseEdit", ChannelID: i.ChannelID, Interaction: i, Message: m}
+	if edit.Content != nil {
+		call.Content = *edit.Content
+	}
+	if edit.Embeds != nil {
+		call.Embeds = *edit.Embeds
+	}
+	if edit.Components != nil {
+		call.Components = *edit.Components
+	}
+	if err := f.record(call); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("interaction %s has not been responded", i.ID)
+	}
+	if edit.Content != nil {
+		m.Content = *edit.Content
+	}
+	if edit.Embeds != nil {
+		m.Embeds = *edit.Embeds
+	}
+	if edit.Components != nil {
+		m.Components = *edit.Components
+	}
+	return m, nil
+}
+
+func (f *FakeSession) FollowupMessageCreate(i *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	content := data.Content
+	for _, file := range data.Files {
+		raw, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		content += string(raw)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.newBotMessage(i.ChannelID, data.Content, data.Embeds, data.Components)
+	m.Flags = data.Flags
+	if err := f.record(Call{Method: "FollowupMessageCreate", ChannelID: i.ChannelID, Content: content, Embeds: data.Embeds,
+		Components: data.Components, Interaction: i, Message: m}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *FakeSession) WebhookExecute(webhookID, _ string, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.newBotMessage("webhook-"+webhookID, data.Content, data.Embeds, data.Components)
+	if err := f.record(Call{Method: "WebhookExecute", ChannelID: m.ChannelID, Content: data.Content, Embeds: data.Embeds, Message: m}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *FakeSession) ApplicationCommands(_, guildID string, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	f.mu.Lock()
+	if err := f.record(Call{Method: "ApplicationCommands", GuildID: guildID}); err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	f.mu.Unlock()
+	return f.Commands(guildID), nil
+}
+
+func (f *FakeSession) ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(Call{Method: "ApplicationCommandBulkOverwrite", GuildID: guildID, Commands: commands}); err != nil {
+		return nil, err
+	}
+	// commands keep their id when overwritten with the same name
+	existingIDs := make(map[string]string)
+	for id, existing := range f.commands[guildID] {
+		existingIDs[fmt.Sprintf("%d/%s", existing.Type, existing.Name)] = id
+	}
+	f.commands[guildID] = make(map[string]*discordgo.ApplicationCommand)
+	var created []*discordgo.ApplicationCommand
+	for _, cmd := range commands {
+		c := *cmd
+		c.ApplicationID = appID
+		c.GuildID = guildID
+		if c.Type == 0 {
+			c.Type = discordgo.ChatApplicationCommand
+		}
+		if id, ok := existingIDs[fmt.Sprintf("%d/%s", c.Type, c.Name)]; ok {
+			c.ID = id
+		} else {
+			c.ID = f.nextID()
+		}
+		f.commands[guildID][c.ID] = &c
+		created = append(created, &c)
+	}
+	return created, nil
+}
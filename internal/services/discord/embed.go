@@ -0,0 +1,209 @@
+package discord
+
+import (
+	"dalian-bot/internal/core"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Embed limits of discord, in characters unless stated otherwise.
+const (
+	EmbedTitleLimit       = 256
+	EmbedDescriptionLimit = 4096
+	EmbedFieldCountLimit  = 25 // fields per embed
+	EmbedFieldNameLimit   = 256
+	EmbedFieldValueLimit  = 1024
+	EmbedFooterLimit      = 2048
+	EmbedAuthorNameLimit  = 256
+	EmbedTotalLimit       = 6000 // title, description, field names and values, footer and author name combined
+)
+
+// emptyFieldText discord rejects fields with an empty name or value.
+const emptyFieldText = "\u200b"
+
+// EmbedBuilder Assemble a MessageEmbed fluently. Limits are enforced by Build, truncating rather than failing.
+type EmbedBuilder struct {
+	embed *discordgo.MessageEmbed
+}
+
+// NewEmbed Start an embed of the color, stamped with the current time.
+func NewEmbed(title string, color int) *EmbedBuilder {
+	return &EmbedBuilder{embed: &discordgo.MessageEmbed{
+		Title:     title,
+		Color:     color,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}}
+}
+
+// InfoEmbed an embed for plain results.
+func InfoEmbed(title string) *EmbedBuilder {
+	return NewEmbed(title, EmbedColorNormal)
+}
+
+// SuccessEmbed an embed for completed actions.
+func SuccessEmbed(title string) *EmbedBuilder {
+	return NewEmbed(title, EmbedColorSuccess)
+}
+
+// QuestionEmbed an embed for confirmations and warnings.
+func QuestionEmbed(title string) *EmbedBuilder {
+	return NewEmbed(title, EmbedColorQuestion)
+}
+
+// DangerEmbed an embed for errors and destructive actions.
+func DangerEmbed(title string) *EmbedBuilder {
+	return NewEmbed(title, EmbedColorDanger)
+}
+
+func (b *EmbedBuilder) Description(description string) *EmbedBuilder {
+	b.embed.Description = description
+	return b
+}
+
+func (b *EmbedBuilder) Descriptionf(format string, a ...any) *EmbedBuilder {
+	return b.Description(fmt.Sprintf(format, a...))
+}
+
+func (b *EmbedBuilder) URL(url string) *EmbedBuilder {
+	b.embed.URL = url
+	return b
+}
+
+// Color override the color of the preset.
+func (b *EmbedBuilder) Color(color int) *EmbedBuilder {
+	b.embed.Color = color
+	return b
+}
+
+// Field add a field on its own line.
+func (b *EmbedBuilder) Field(name, value string) *EmbedBuilder {
+	b.embed.Fields = append(b.embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: value})
+	return b
+}
+
+// InlineField add a field sharing its line with adjacent inline fields.
+func (b *EmbedBuilder) InlineField(name, value string) *EmbedBuilder {
+	b.embed.Fields = append(b.embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: true})
+	return b
+}
+
+// Fields add prepared fields, e.g. from IPagerItem.ToMessageEmbedField.
+func (b *EmbedBuilder) Fields(fields ...*discordgo.MessageEmbedField) *EmbedBuilder {
+	b.embed.Fields = append(b.embed.Fields, fields...)
+	return b
+}
+
+func (b *EmbedBuilder) Author(name, url, iconURL string) *EmbedBuilder {
+	b.embed.Author = &discordgo.MessageEmbedAuthor{Name: name, URL: url, IconURL: iconURL}
+	return b
+}
+
+func (b *EmbedBuilder) Provider(name, url string) *EmbedBuilder {
+	b.embed.Provider = &discordgo.MessageEmbedProvider{Name: name, URL: url}
+	return b
+}
+
+func (b *EmbedBuilder) Footer(text string) *EmbedBuilder {
+	b.embed.Footer = &discordgo.MessageEmbedFooter{Text: text}
+	return b
+}
+
+func (b *EmbedBuilder) Thumbnail(url string) *EmbedBuilder {
+	b.embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: url}
+	return b
+}
+
+func (b *EmbedBuilder) Image(url string) *EmbedBuilder {
+	b.embed.Image = &discordgo.MessageEmbedImage{URL: url}
+	return b
+}
+
+// Build Return a copy of the embed within discord limits: long texts are cut, extra fields dropped,
+// and fields are dropped from the end, then the description cut, until the total fits.
+func (b *EmbedBuilder) Build() *discordgo.MessageEmbed {
+	embed := *b.embed
+	embed.Title = truncateText(embed.Title, EmbedTitleLimit)
+	embed.Description = truncateText(embed.Description, EmbedDescriptionLimit)
+	if embed.Author != nil {
+		author := *embed.Author
+		author.Name = truncateText(author.Name, EmbedAuthorNameLimit)
+		embed.Author = &author
+	}
+	if embed.Footer != nil {
+		footer := *embed.Footer
+		footer.Text = truncateText(footer.Text, EmbedFooterLimit)
+		embed.Footer = &footer
+	}
+	if len(embed.Fields) > EmbedFieldCountLimit {
+		core.Logger.Debugf("Embed [%s] has %d fields, dropping the extra ones.", embed.Title, len(embed.Fields))
+	}
+	embed.Fields = nil
+	for i, field := range b.embed.Fields {
+		if i == EmbedFieldCountLimit {
+			break
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   nonEmpty(truncateText(field.Name, EmbedFieldNameLimit)),
+			Value:  nonEmpty(truncateText(field.Value, EmbedFieldValueLimit)),
+			Inline: field.Inline,
+		})
+	}
+	for over := embedLength(&embed) - EmbedTotalLimit; over > 0; over = embedLength(&embed) - EmbedTotalLimit {
+		if len(embed.Fields) > 0 {
+			embed.Fields = embed.Fields[:len(embed.Fields)-1]
+			continue
+		}
+		embed.Description = truncateText(embed.Description, utf8.RuneCountInString(embed.Description)-over)
+		break
+	}
+	return &embed
+}
+
+// Respond reply to the interaction with the embed.
+func (b *EmbedBuilder) Respond(s *Service, i *discordgo.Interaction, components ...discordgo.MessageComponent) error {
+	return s.InteractionRespondEmbed(i, b.Build(), components)
+}
+
+// Send the embed to the channel through the queue of the channel.
+func (b *EmbedBuilder) Send(s *Service, channelID string, components ...discordgo.MessageComponent) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{b.Build()},
+		Components: components,
+	})
+}
+
+// embedLength characters counted against EmbedTotalLimit.
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Footer != nil {
+		length += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += utf8.RuneCountInString(embed.Author.Name)
+	}
+	return length
+}
+
+// truncateText cut text to at most limit characters, marking the cut with an ellipsis.
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+func nonEmpty(text string) string {
+	if text == "" {
+		return emptyFieldText
+	}
+	return text
+}
@@ -0,0 +1,57 @@
+package discord_test
+
+import (
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/discord/discordtest"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEmbedBuilderLimits(t *testing.T) {
+	b := discord.DangerEmbed(strings.Repeat("t", 300)).Description(strings.Repeat("d", 5000))
+	for i := 0; i < 30; i++ {
+		b.Field(fmt.Sprint(i), strings.Repeat("v", 2000))
+	}
+	b.Field("", "")
+	embed := b.Build()
+
+	if embed.Color != discord.EmbedColorDanger || embed.Timestamp == "" {
+		t.Errorf("want the danger preset stamped, got color %x at %q", embed.Color, embed.Timestamp)
+	}
+	if n := utf8.RuneCountInString(embed.Title); n != discord.EmbedTitleLimit || !strings.HasSuffix(embed.Title, "…") {
+		t.Errorf("want the title cut to %d, got %d", discord.EmbedTitleLimit, n)
+	}
+	total := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		if utf8.RuneCountInString(field.Value) > discord.EmbedFieldValueLimit {
+			t.Errorf("field %s too long", field.Name)
+		}
+		total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if total > discord.EmbedTotalLimit {
+		t.Errorf("want at most %d characters in total, got %d", discord.EmbedTotalLimit, total)
+	}
+	if len(embed.Fields) == 0 || embed.Fields[0].Name != "0" {
+		t.Errorf("want the first fields kept, got %d", len(embed.Fields))
+	}
+
+	// discord rejects empty field texts
+	embed = discord.InfoEmbed("ok").Field("", "").Build()
+	if embed.Fields[0].Name == "" || embed.Fields[0].Value == "" {
+		t.Error("want empty field texts replaced")
+	}
+}
+
+func TestEmbedBuilderRespond(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	i := discordtest.SlashCommand("anything")
+	if err := discord.SuccessEmbed("Done").Field("a", "b").Respond(h.DiscordService, i.Interaction); err != nil {
+		t.Fatal(err)
+	}
+	embeds := h.LastResponse().Embeds
+	if len(embeds) != 1 || embeds[0].Title != "Done" || embeds[0].Color != discord.EmbedColorSuccess {
+		t.Fatalf("unexpected response %+v", embeds)
+	}
+}
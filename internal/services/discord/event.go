@@ -10,13 +10,30 @@ type EventType string
 const (
 	EventTypeMessageCreate     EventType = "message-create"
 	EventTypeInteractionCreate EventType = "interaction-create"
+	// EventTypeMessageReactionAdd a reaction added to a guild message, including the bot's own.
+	EventTypeMessageReactionAdd EventType = "message-reaction-add"
+	// EventTypeMessageCommand a message context-menu command, carried in InteractionCreate.
+	// Kept apart from EventTypeInteractionCreate so chat-input handlers never see it.
+	EventTypeMessageCommand EventType = "message-command"
 )
 
 type Event struct {
 	EventType EventType
 	//will only use one of them
-	MessageCreate     *discordgo.MessageCreate
-	InteractionCreate *discordgo.InteractionCreate
+	MessageCreate      *discordgo.MessageCreate
+	InteractionCreate  *discordgo.InteractionCreate
+	MessageReactionAdd *discordgo.MessageReactionAdd
+}
+
+// InteractionEvent the event of an interaction, telling message commands apart.
+// discordgo doesn't decode the command type, message commands are those targeting a resolved message.
+func InteractionEvent(i *discordgo.InteractionCreate) Event {
+	if i.Type == discordgo.InteractionApplicationCommand {
+		if _, ok := TargetMessage(i); ok {
+			return Event{EventType: EventTypeMessageCommand, InteractionCreate: i}
+		}
+	}
+	return Event{EventType: EventTypeInteractionCreate, InteractionCreate: i}
 }
 
 func UnboxEvent(t core.Trigger) Event {
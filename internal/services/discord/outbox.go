@@ -0,0 +1,230 @@
+package discord
+
+import (
+	"bytes"
+	"dalian-bot/internal/core"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// MessageLengthLimit max characters of a message content.
+	MessageLengthLimit = 2000
+	// maxSplitMessages content needing more messages than this is sent as a file instead.
+	maxSplitMessages = 5
+	// longContentFileName name of the attachment carrying content too long to be split.
+	longContentFileName = "message.txt"
+	// codeBlockOverhead length of the fences wrapped around a code block.
+	codeBlockOverhead = len("```\n\n```")
+	// outboxIdleTimeout the worker of a channel exits after being idle for this long.
+	outboxIdleTimeout = time.Minute
+	outboxQueueSize   = 64
+
+	defaultSendMaxAttempts = 5
+	defaultSendBaseBackoff = time.Second
+	defaultSendMaxBackoff  = 30 * time.Second
+)
+
+// outbox Per-channel queues of outgoing messages. Sends to a channel happen one at a time and in order,
+// matching the per-channel rate limit buckets of discord, while channels don't wait for each other.
+type outbox struct {
+	lock    sync.Mutex
+	queues  map[string]*channelQueue
+	stopped chan struct{} // closed on Stop, so retries don't hold up the shutdown
+}
+
+// stopChan the channel closed on Stop, created on first use.
+func (o *outbox) stopChan() chan struct{} {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.stopped == nil {
+		o.stopped = make(chan struct{})
+	}
+	return o.stopped
+}
+
+// stop give up pending retries, sends in progress still complete.
+func (o *outbox) stop() {
+	stopped := o.stopChan()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	select {
+	case <-stopped:
+	default:
+		close(stopped)
+	}
+}
+
+type channelQueue struct {
+	jobs    chan sendJob
+	pending int // jobs enqueued and not done yet, guarded by outbox.lock
+}
+
+type sendJob struct {
+	send func() (*discordgo.Message, error)
+	done chan sendResult
+}
+
+type sendResult struct {
+	message *discordgo.Message
+	err     error
+}
+
+// queueSend run send in the queue of the channel, and wait for its result.
+func (s *Service) queueSend(channelID string, send func() (*discordgo.Message, error)) (*discordgo.Message, error) {
+	job := sendJob{send: send, done: make(chan sendResult, 1)}
+	s.outbox.lock.Lock()
+	if s.outbox.queues == nil {
+		s.outbox.queues = make(map[string]*channelQueue)
+	}
+	q, ok := s.outbox.queues[channelID]
+	if !ok {
+		q = &channelQueue{jobs: make(chan sendJob, outboxQueueSize)}
+		s.outbox.queues[channelID] = q
+		go s.runQueue(channelID, q)
+	}
+	q.pending++
+	s.outbox.lock.Unlock()
+	q.jobs <- job
+	result := <-job.done
+	return result.message, result.err
+}
+
+func (s *Service) runQueue(channelID string, q *channelQueue) {
+	for {
+		select {
+		case job := <-q.jobs:
+			message, err := job.send()
+			job.done <- sendResult{message: message, err: err}
+			s.outbox.lock.Lock()
+			q.pending--
+			s.outbox.lock.Unlock()
+		case <-time.After(outboxIdleTimeout):
+			s.outbox.lock.Lock()
+			if q.pending == 0 {
+				delete(s.outbox.queues, channelID)
+				s.outbox.lock.Unlock()
+				return
+			}
+			s.outbox.lock.Unlock()
+		}
+	}
+}
+
+// withRetry call send until it succeeds, fails for good, SendMaxAttempts is reached or the service stops.
+func (s *Service) withRetry(send func() (*discordgo.Message, error)) (*discordgo.Message, error) {
+	backoff := s.SendBaseBackoff
+	for attempt := 1; ; attempt++ {
+		message, err := send()
+		if err == nil || attempt >= s.SendMaxAttempts || !isRetryable(err) {
+			return message, err
+		}
+		wait := backoff
+		var rateLimitErr *discordgo.RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RateLimit != nil && rateLimitErr.TooManyRequests != nil &&
+			rateLimitErr.RetryAfter > wait {
+			wait = rateLimitErr.RetryAfter
+		}
+		core.Logger.Debugf("Discord send failed (attempt %d/%d), retrying in %s: %v", attempt, s.SendMaxAttempts, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-s.outbox.stopChan():
+			return message, err
+		}
+		if backoff *= 2; backoff > s.SendMaxBackoff {
+			backoff = s.SendMaxBackoff
+		}
+	}
+}
+
+// isRetryable rate limits, server errors and network errors are worth another try.
+func isRetryable(err error) bool {
+	var rateLimitErr *discordgo.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return true
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) {
+		return restErr.Response != nil &&
+			(restErr.Response.StatusCode == http.StatusTooManyRequests || restErr.Response.StatusCode >= 500)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sendText send content split into as many messages as needed, or as a file when it takes more than maxSplitMessages.
+// wrap is applied to every chunk and adds overhead characters. Return the last message sent.
+func (s *Service) sendText(channelID, content string, overhead int, wrap func(string) string) (*discordgo.Message, error) {
+	chunks := splitMessage(content, MessageLengthLimit-overhead)
+	if len(chunks) > maxSplitMessages {
+		return s.sendFile(channelID, longContentFileName, []byte(content))
+	}
+	return s.queueSend(channelID, func() (*discordgo.Message, error) {
+		var last *discordgo.Message
+		for _, chunk := range chunks {
+			message, err := s.withRetry(func() (*discordgo.Message, error) {
+				return s.Session.ChannelMessageSend(channelID, wrap(chunk))
+			})
+			if err != nil {
+				return last, err
+			}
+			last = message
+		}
+		return last, nil
+	})
+}
+
+func (s *Service) sendFile(channelID, name string, content []byte) (*discordgo.Message, error) {
+	return s.queueSend(channelID, func() (*discordgo.Message, error) {
+		return s.withRetry(func() (*discordgo.Message, error) {
+			// a fresh reader for every attempt
+			return s.Session.ChannelFileSend(channelID, name, bytes.NewReader(content))
+		})
+	})
+}
+
+// splitMessage cut content into chunks of at most limit characters, on line boundaries when possible.
+func splitMessage(content string, limit int) []string {
+	if utf8.RuneCountInString(content) <= limit {
+		return []string{content}
+	}
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+	flush := func() {
+		if currentLen > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLen = 0
+		}
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineLen := utf8.RuneCountInString(line)
+		if currentLen+lineLen > limit {
+			flush()
+		}
+		// a single line too long for a message is cut anywhere
+		for lineLen > limit {
+			runes := []rune(line)
+			chunks = append(chunks, string(runes[:limit]))
+			line, lineLen = string(runes[limit:]), lineLen-limit
+		}
+		current.WriteString(line)
+		currentLen += lineLen
+	}
+	flush()
+	// the line break ending a chunk is implied by the next message
+	for i, chunk := range chunks {
+		if trimmed := strings.TrimSuffix(chunk, "\n"); trimmed != "" {
+			chunks[i] = trimmed
+		}
+	}
+	return chunks
+}
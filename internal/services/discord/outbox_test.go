@@ -0,0 +1,107 @@
+package discord_test
+
+import (
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/discord/discordtest"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSendRetriesRateLimited(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	h.DiscordService.SendBaseBackoff = time.Millisecond
+	h.Session.FailNext("ChannelMessageSend", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusTooManyRequests}})
+
+	if _, err := h.DiscordService.ChannelMessageSend(discordtest.ChannelID, "hello"); err != nil {
+		t.Fatalf("want the send retried, got %v", err)
+	}
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 2 || sends[1].Content != "hello" {
+		t.Fatalf("want a failed and a successful attempt, got %+v", sends)
+	}
+
+	// client errors are not retried
+	h.Session.Reset()
+	h.Session.FailNext("ChannelMessageSend", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden}})
+	if _, err := h.DiscordService.ChannelMessageSend(discordtest.ChannelID, "hello"); err == nil {
+		t.Fatal("want the forbidden error returned")
+	}
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 1 {
+		t.Fatalf("want a single attempt, got %d", len(sends))
+	}
+}
+
+func TestStopInterruptsRetries(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	h.DiscordService.SendBaseBackoff = time.Hour
+	h.Session.FailNext("ChannelMessageSend", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadGateway}})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.DiscordService.ChannelMessageSend(discordtest.ChannelID, "hello")
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	h.DiscordService.Stop(&wg)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("want the last error returned")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the send kept waiting for its retry after Stop")
+	}
+}
+
+func TestSendSplitsLongContent(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("line %03d ééé", i))
+	}
+	content := strings.Join(lines, "\n")
+
+	if _, err := h.DiscordService.ChannelMessageSendCodeBlock(discordtest.ChannelID, content); err != nil {
+		t.Fatal(err)
+	}
+	sends := h.Session.CallsOf("ChannelMessageSend")
+	if len(sends) < 2 {
+		t.Fatalf("want several messages, got %d", len(sends))
+	}
+	var got []string
+	for _, send := range sends {
+		if n := utf8.RuneCountInString(send.Content); n > discord.MessageLengthLimit {
+			t.Fatalf("message of %d characters", n)
+		}
+		if !strings.HasPrefix(send.Content, "```\n") || !strings.HasSuffix(send.Content, "\n```") {
+			t.Fatalf("want every chunk in a code block, got %q", send.Content)
+		}
+		got = append(got, strings.TrimSuffix(strings.TrimPrefix(send.Content, "```\n"), "\n```"))
+	}
+	if strings.Join(got, "\n") != content {
+		t.Fatal("want the chunks to add up to the content, in order")
+	}
+}
+
+func TestSendHugeContentAsFile(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	content := strings.Repeat("a very long line of text\n", 1000)
+
+	if _, err := h.DiscordService.ChannelMessageSend(discordtest.ChannelID, content); err != nil {
+		t.Fatal(err)
+	}
+	if sends := h.Session.CallsOf("ChannelMessageSend"); len(sends) != 0 {
+		t.Fatalf("want no text message, got %d", len(sends))
+	}
+	if files := h.Session.CallsOf("ChannelFileSend"); len(files) != 1 || files[0].Content != content {
+		t.Fatalf("want the content attached, got %+v", files)
+	}
+}
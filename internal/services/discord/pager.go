@@ -0,0 +1,497 @@
+package discord
+
+import (
+	"dalian-bot/internal/core"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// defaultPagerLimit items in a page when Pager.Limit is not set.
+	defaultPagerLimit = 10
+	// pageSelectOptionLimit options discord allows in a select menu.
+	pageSelectOptionLimit = 25
+)
+
+// pagerReactions actions of the navigation reactions of pagers using reactions.
+var pagerReactions = map[string]core.PagerAction{
+	EmojiFirstPage:  core.PagerFirstPage,
+	EmojiLeftArrow:  core.PagerPrevPage,
+	EmojiRightArrow: core.PagerNextPage,
+	EmojiLastPage:   core.PagerLastPage,
+}
+
+// ErrPagerItemOutOfRange the display id given to Pager.ItemAt matches no item.
+var ErrPagerItemOutOfRange = errors.New("pager item out of range")
+
+type IPagerLoader interface {
+	//LoadPager initialize the pager
+	LoadPager(pager *Pager) error
+	//RenderPage render the given page
+	//index order of the first item
+	//limit max number of items in a page
+	//embedFrame given embed frame of render
+	//renderedEmbed *discordgo.MessageEmbed rendered, unsent.
+	RenderPage(pager *Pager, toPage, limit int, embedFrame discordgo.MessageEmbed) (renderedEmbed *discordgo.MessageEmbed, err error)
+}
+
+// IPagerItemLoader implemented by loaders that don't keep every item in Pager.CompleteItemSlice.
+type IPagerItemLoader interface {
+	//LoadItem return the item of the display id, starting from 1
+	LoadItem(pager *Pager, displayID int) (IPagerPart, error)
+}
+
+type Pager struct {
+	//core page loading functions, to be implemented
+	IPagerLoader
+	discordService *Service
+	//owner of this pager
+	OwnerUserID string
+	//autofilled later
+	AttachedMessage *discordgo.Message
+	//pagination cache. Limit is the page size, defaults to 10 and 25 at most
+	PageNow, PageMax, Limit int
+	//embed rendering skeleton
+	EmbedFrame *discordgo.MessageEmbed
+	//Customized pagination button
+	PrevPageButton, NextPageButton discordgo.Button
+	//Optional buttons, rendered when a CustomID is set
+	FirstPageButton, LastPageButton discordgo.Button
+	//PageSelectID custom id of a page select menu, rendered when set
+	PageSelectID string
+	//UseReactions navigate with reactions of the owner instead of components, e.g. for text commands
+	UseReactions bool
+	//Overtime time to expire the pager
+	Overtime time.Duration
+	//only used when not lazy loading
+	CompleteItemSlice []*IPagerPart
+	displayItemSlice  []*IPagerPart
+	//set once the pager expired
+	locked bool
+	//the interaction answered by the pager, if any. Its token edits the message, ephemeral or not
+	interaction *discordgo.Interaction
+}
+
+// Setup initialize a pager AND send an initial message with interaction components
+func (bp *Pager) Setup(trigger any, service *Service) error {
+
+	bp.discordService = service
+	if bp.PageNow <= 0 {
+		bp.PageNow = 1
+	}
+	if bp.Limit <= 0 {
+		bp.Limit = defaultPagerLimit
+	} else if bp.Limit > EmbedFieldCountLimit {
+		bp.Limit = EmbedFieldCountLimit
+	}
+	//initialize pager
+	if err := bp.IPagerLoader.LoadPager(bp); err != nil {
+		return err
+	}
+
+	//initialize first page
+	filledFrame, err := bp.IPagerLoader.RenderPage(bp, bp.PageNow, bp.Limit, *bp.EmbedFrame)
+	if err != nil {
+		return err
+	}
+	components := bp.components()
+
+	//work for Interaction(Slash commands), possibly through a Responder, and raw trigger
+	var i *discordgo.Interaction
+	if r, ok := trigger.(*Responder); ok {
+		//Interaction answered by a Responder, possibly deferred
+		if err := r.RespondEmbed(filledFrame, components...); err != nil {
+			return err
+		}
+		i = r.Interaction()
+	} else if i, ok = trigger.(*discordgo.Interaction); ok {
+		//Interaction (Slash)
+		if err := bp.discordService.InteractionRespondEmbed(i, filledFrame, components); err != nil {
+			return err
+		}
+	}
+	if i != nil {
+		if attachedMsg, err := bp.discordService.InteractionResponse(i); err != nil {
+			return fmt.Errorf("failed loading attached message from interaction%w", err)
+		} else {
+			bp.AttachedMessage = attachedMsg
+			bp.OwnerUserID = InteractionUser(i).ID
+			bp.interaction = i
+		}
+	} else if m, ok := trigger.(*discordgo.Message); ok {
+		//Raw command (Message)
+		if attachedMessage, err := bp.discordService.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Embeds:     []*discordgo.MessageEmbed{filledFrame},
+			Components: components,
+		}); err != nil {
+			return fmt.Errorf("failed loading attached message from message%w", err)
+		} else {
+			bp.AttachedMessage = attachedMessage
+			bp.OwnerUserID = m.Author.ID
+		}
+	} else {
+		return errors.New("unknown trigger type, pager initialization failed")
+	}
+
+	if bp.UseReactions && bp.PageMax > 1 {
+		for _, emoji := range []string{EmojiFirstPage, EmojiLeftArrow, EmojiRightArrow, EmojiLastPage} {
+			if err := bp.discordService.Session.MessageReactionAdd(bp.AttachedMessage.ChannelID, bp.AttachedMessage.ID, emoji); err != nil {
+				return fmt.Errorf("failed adding pager reactions: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SwitchPage switch the page for a given pager.
+// no verification process involved
+func (bp *Pager) SwitchPage(a core.PagerAction, i *discordgo.Interaction) error {
+	page, err := bp.actionPage(a)
+	if err != nil {
+		return err
+	}
+	return bp.SwitchToPage(page, i)
+}
+
+// SwitchToPage render the page and edit the attached message with it. Pages out of range wrap around.
+func (bp *Pager) SwitchToPage(page int, i *discordgo.Interaction) error {
+	if err := bp.renderAttached(page); err != nil {
+		return err
+	}
+	//edit response
+	return bp.discordService.InteractionRespondEditFromMessage(i, bp.AttachedMessage)
+}
+
+// HandleReaction switch the page as asked by a navigation reaction of the owner.
+// Reactions of other users are removed. Return false if the reaction is not one of the pager.
+func (bp *Pager) HandleReaction(r *discordgo.MessageReactionAdd) (bool, error) {
+	action, ok := pagerReactions[r.Emoji.Name]
+	if !ok || r.MessageID != bp.AttachedMessage.ID {
+		return false, nil
+	}
+	if r.UserID == bp.discordService.DiscordAccountConfig.BotID {
+		// the reactions put by Setup
+		return true, nil
+	}
+	// removing reactions of others needs the manage messages permission, the pager works without it
+	defer bp.discordService.Session.MessageReactionRemove(r.ChannelID, r.MessageID, r.Emoji.APIName(), r.UserID)
+	if r.UserID != bp.OwnerUserID {
+		return true, nil
+	}
+	page, err := bp.actionPage(action)
+	if err != nil {
+		return true, err
+	}
+	if err := bp.renderAttached(page); err != nil {
+		return true, err
+	}
+	editedMsg, err := bp.discordService.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Content:    &bp.AttachedMessage.Content,
+		Components: bp.AttachedMessage.Components,
+		Embeds:     bp.AttachedMessage.Embeds,
+		ID:         bp.AttachedMessage.ID,
+		Channel:    bp.AttachedMessage.ChannelID,
+	})
+	if err != nil {
+		return true, err
+	}
+	bp.AttachedMessage = editedMsg
+	return true, nil
+}
+
+func (bp *Pager) actionPage(a core.PagerAction) (int, error) {
+	switch a {
+	case core.PagerPrevPage:
+		return bp.PageNow - 1, nil
+	case core.PagerNextPage:
+		return bp.PageNow + 1, nil
+	case core.PagerFirstPage:
+		return 1, nil
+	case core.PagerLastPage:
+		return bp.PageMax, nil
+	}
+	return 0, fmt.Errorf("unknown pager action %d", a)
+}
+
+// renderAttached render the page into AttachedMessage, unsent.
+func (bp *Pager) renderAttached(page int) error {
+	newEmbed, err := bp.RenderPage(bp, page, bp.Limit, *bp.EmbedFrame)
+	if err != nil {
+		return err
+	}
+	bp.AttachedMessage.Embeds[0] = newEmbed
+	bp.AttachedMessage.Components = bp.components()
+	return nil
+}
+
+// HandleComponent switch the page as asked by the clicked component, if clicked by the owner.
+// Others are told so privately. Return false if the component is not one of the pager.
+func (bp *Pager) HandleComponent(i *discordgo.Interaction) (bool, error) {
+	data := i.MessageComponentData()
+	if !bp.ownsComponent(data.CustomID) {
+		return false, nil
+	}
+	if user := InteractionUser(i); bp.OwnerUserID != "" && (user == nil || user.ID != bp.OwnerUserID) {
+		return true, bp.discordService.InteractionRespondEphemeral(i, "Only the user who asked for this list can page through it.")
+	}
+	switch data.CustomID {
+	case bp.PrevPageButton.CustomID:
+		return true, bp.SwitchPage(core.PagerPrevPage, i)
+	case bp.NextPageButton.CustomID:
+		return true, bp.SwitchPage(core.PagerNextPage, i)
+	case bp.FirstPageButton.CustomID:
+		return true, bp.SwitchPage(core.PagerFirstPage, i)
+	case bp.LastPageButton.CustomID:
+		return true, bp.SwitchPage(core.PagerLastPage, i)
+	case bp.PageSelectID:
+		if len(data.Values) == 0 {
+			return true, nil
+		}
+		page, err := strconv.Atoi(data.Values[0])
+		if err != nil {
+			return true, fmt.Errorf("malformed page %q: %w", data.Values[0], err)
+		}
+		return true, bp.SwitchToPage(page, i)
+	}
+	return false, nil
+}
+
+// ownsComponent whether the custom id is the one of a component of the pager.
+func (bp *Pager) ownsComponent(customID string) bool {
+	if customID == "" {
+		return false
+	}
+	for _, id := range []string{bp.PrevPageButton.CustomID, bp.NextPageButton.CustomID, bp.FirstPageButton.CustomID,
+		bp.LastPageButton.CustomID, bp.PageSelectID} {
+		if customID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ItemAt Return the item of the display id, as numbered in the rendered pages.
+func (bp *Pager) ItemAt(displayID int) (IPagerPart, error) {
+	if loader, ok := bp.IPagerLoader.(IPagerItemLoader); ok {
+		return loader.LoadItem(bp, displayID)
+	}
+	if displayID <= 0 || displayID > len(bp.CompleteItemSlice) {
+		return nil, ErrPagerItemOutOfRange
+	}
+	return *bp.CompleteItemSlice[displayID-1], nil
+}
+
+// LockPagerButtons disable buttons of the pager, or remove its reactions
+func (bp *Pager) LockPagerButtons() error {
+	bp.locked = true
+	if bp.UseReactions {
+		if bp.PageMax <= 1 {
+			return nil
+		}
+		return bp.discordService.Session.MessageReactionsRemoveAll(bp.AttachedMessage.ChannelID, bp.AttachedMessage.ID)
+	}
+	components := bp.components()
+	var editedMsg *discordgo.Message
+	var err error
+	if bp.interaction != nil {
+		//ephemeral messages can only be edited through the interaction
+		editedMsg, err = bp.discordService.Session.InteractionResponseEdit(bp.interaction, &discordgo.WebhookEdit{
+			Components: &components,
+			Embeds:     &bp.AttachedMessage.Embeds,
+		})
+	} else {
+		//raw edit.
+		editedMsg, err = bp.discordService.Session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Content:    &bp.AttachedMessage.Content,
+			Components: components,
+			Embeds:     bp.AttachedMessage.Embeds,
+			ID:         bp.AttachedMessage.ID,
+			Channel:    bp.AttachedMessage.ChannelID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	bp.AttachedMessage = editedMsg
+	return nil
+}
+
+// components the navigation rows of the current page, nil for a single page.
+func (bp *Pager) components() []discordgo.MessageComponent {
+	if bp.PageMax <= 1 || bp.UseReactions {
+		//no buttons rendered for only one page
+		return nil
+	}
+	var buttons []discordgo.MessageComponent
+	for _, button := range []discordgo.Button{bp.FirstPageButton, bp.PrevPageButton, bp.NextPageButton, bp.LastPageButton} {
+		if button.CustomID == "" {
+			continue
+		}
+		button.Disabled = bp.locked
+		buttons = append(buttons, button)
+	}
+	components := []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+	if bp.PageSelectID != "" {
+		components = append(components, discordgo.ActionsRow{Components: []discordgo.MessageComponent{bp.pageSelectMenu()}})
+	}
+	return components
+}
+
+// pageSelectMenu list the pages around the current one, as many as a select menu holds.
+func (bp *Pager) pageSelectMenu() discordgo.SelectMenu {
+	first := bp.PageNow - pageSelectOptionLimit/2
+	if first > bp.PageMax-pageSelectOptionLimit+1 {
+		first = bp.PageMax - pageSelectOptionLimit + 1
+	}
+	if first < 1 {
+		first = 1
+	}
+	var options []discordgo.SelectMenuOption
+	for page := first; page <= bp.PageMax && len(options) < pageSelectOptionLimit; page++ {
+		options = append(options, discordgo.SelectMenuOption{
+			Label:   fmt.Sprintf("Page %d", page),
+			Value:   strconv.Itoa(page),
+			Default: page == bp.PageNow,
+		})
+	}
+	return discordgo.SelectMenu{
+		MenuType:    discordgo.StringSelectMenu,
+		CustomID:    bp.PageSelectID,
+		Placeholder: "Go to page",
+		Options:     options,
+		Disabled:    bp.locked,
+	}
+}
+
+type IPagerPart interface {
+	ToMessageEmbedField(displayID int) *discordgo.MessageEmbedField
+}
+
+type DefaultPageRenderer struct{}
+
+func (DefaultPageRenderer) RenderPage(pager *Pager, toPage, limit int, embedFrame discordgo.MessageEmbed) (renderedEmbed *discordgo.MessageEmbed, err error) {
+	return renderPage(pager, toPage, limit, embedFrame, len(pager.CompleteItemSlice), func(page, lowerLimit, upperLimit int) ([]*IPagerPart, error) {
+		return pager.CompleteItemSlice[lowerLimit:upperLimit], nil
+	})
+}
+
+// LazyPagerLoader An IPagerLoader counting the items at setup and fetching only the pages displayed,
+// e.g. with skip/limit queries. Fetched pages are kept, so display ids keep matching what the user has seen.
+type LazyPagerLoader struct {
+	//CountFunc return the number of items
+	CountFunc func() (int, error)
+	//LoadFunc return at most limit items, skipping the first skip ones
+	LoadFunc func(skip, limit int) ([]IPagerPart, error)
+	lock     sync.Mutex
+	total    int
+	pages    map[int][]*IPagerPart
+}
+
+func (l *LazyPagerLoader) LoadPager(_ *Pager) error {
+	total, err := l.CountFunc()
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.total = total
+	l.pages = make(map[int][]*IPagerPart)
+	return nil
+}
+
+func (l *LazyPagerLoader) RenderPage(pager *Pager, toPage, limit int, embedFrame discordgo.MessageEmbed) (renderedEmbed *discordgo.MessageEmbed, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return renderPage(pager, toPage, limit, embedFrame, l.total, func(page, lowerLimit, upperLimit int) ([]*IPagerPart, error) {
+		return l.loadPage(page, lowerLimit, upperLimit)
+	})
+}
+
+func (l *LazyPagerLoader) LoadItem(pager *Pager, displayID int) (IPagerPart, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if displayID <= 0 || displayID > l.total {
+		return nil, ErrPagerItemOutOfRange
+	}
+	page := (displayID-1)/pager.Limit + 1
+	upperLimit := page * pager.Limit
+	if upperLimit > l.total {
+		upperLimit = l.total
+	}
+	items, err := l.loadPage(page, (page-1)*pager.Limit, upperLimit)
+	if err != nil {
+		return nil, err
+	}
+	// the collection may have shrunk since the count
+	if index := (displayID - 1) % pager.Limit; index < len(items) {
+		return *items[index], nil
+	}
+	return nil, ErrPagerItemOutOfRange
+}
+
+// loadPage must be called with the lock held.
+func (l *LazyPagerLoader) loadPage(page, lowerLimit, upperLimit int) ([]*IPagerPart, error) {
+	if items, ok := l.pages[page]; ok {
+		return items, nil
+	}
+	loaded, err := l.LoadFunc(lowerLimit, upperLimit-lowerLimit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*IPagerPart, len(loaded))
+	for k := range loaded {
+		items[k] = &loaded[k]
+	}
+	l.pages[page] = items
+	return items, nil
+}
+
+// renderPage fill the frame with the items of the page, loaded by load.
+// lowerLimit and upperLimit given to load are the bounds of the page among all the items.
+func renderPage(pager *Pager, toPage, limit int, embedFrame discordgo.MessageEmbed, totalSize int,
+	load func(page, lowerLimit, upperLimit int) ([]*IPagerPart, error)) (*discordgo.MessageEmbed, error) {
+	maxPage := totalSize / limit
+	//page logic
+	if totalSize%limit != 0 {
+		maxPage += 1
+	}
+	pager.PageMax = maxPage
+	//boundary limit
+	if toPage > maxPage {
+		toPage = 1
+	} else if toPage < 1 {
+		toPage = maxPage
+	}
+	//boundary limit 2: nothing to show
+	if totalSize == 0 {
+		embedFrame.Description = "Your query rendered 0 result. Nothing to show."
+		return &embedFrame, nil
+	}
+	//split slice
+	lowerLimit := (toPage - 1) * limit
+	upperLimit := toPage * limit
+	if toPage == maxPage {
+		upperLimit = totalSize
+	}
+	items, err := load(toPage, lowerLimit, upperLimit)
+	if err != nil {
+		return nil, err
+	}
+	pager.displayItemSlice = items
+	//rendering
+	var alterFields []*discordgo.MessageEmbedField
+	for k, pagerPart := range pager.displayItemSlice {
+		var part = *pagerPart
+		alterFields = append(alterFields, part.ToMessageEmbedField(lowerLimit+k+1))
+	}
+	embedFrame.Fields = alterFields
+	embedFrame.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("page: %d/%d", toPage, maxPage)}
+	//setup pageNow
+	pager.PageNow = toPage
+	return &embedFrame, nil
+}
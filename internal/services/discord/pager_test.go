@@ -0,0 +1,96 @@
+package discord_test
+
+import (
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/discord/discordtest"
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type pagerItem int
+
+func (p pagerItem) ToMessageEmbedField(displayID int) *discordgo.MessageEmbedField {
+	return &discordgo.MessageEmbedField{Name: fmt.Sprint(displayID), Value: fmt.Sprint(int(p))}
+}
+
+func TestLazyPagerLoadsDisplayedPages(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	var loads []string
+	pager := discord.Pager{
+		IPagerLoader: &discord.LazyPagerLoader{
+			CountFunc: func() (int, error) { return 100, nil },
+			LoadFunc: func(skip, limit int) ([]discord.IPagerPart, error) {
+				loads = append(loads, fmt.Sprintf("%d+%d", skip, limit))
+				var items []discord.IPagerPart
+				for k := skip; k < skip+limit; k++ {
+					items = append(items, pagerItem(k))
+				}
+				return items, nil
+			},
+		},
+		Limit:          3,
+		PrevPageButton: discordgo.Button{Label: "prev", CustomID: "prev"},
+		NextPageButton: discordgo.Button{Label: "next", CustomID: "next"},
+		LastPageButton: discordgo.Button{Label: "last", CustomID: "last"},
+		PageSelectID:   "page",
+		EmbedFrame:     discord.InfoEmbed("items").Build(),
+	}
+	if err := pager.Setup(discordtest.SlashCommand("items").Interaction, h.DiscordService); err != nil {
+		t.Fatal(err)
+	}
+	if pager.PageMax != 34 {
+		t.Errorf("want 34 pages, got %d", pager.PageMax)
+	}
+	// only the owner can page
+	other := discordtest.ButtonClick(pager.AttachedMessage, "last").Interaction
+	other.Member = &discordgo.Member{User: &discordgo.User{ID: "someone else"}}
+	if handled, err := pager.HandleComponent(other); !handled || err != nil {
+		t.Fatalf("click of someone else not handled: %v", err)
+	}
+	if resp := h.LastResponse(); resp.Response.Data.Flags != discordgo.MessageFlagsEphemeral || pager.PageNow == pager.PageMax {
+		t.Fatalf("want a private rejection and the page kept, got %+v", resp.Response.Data)
+	}
+	if handled, err := pager.HandleComponent(discordtest.ButtonClick(pager.AttachedMessage, "last").Interaction); !handled || err != nil {
+		t.Fatalf("last page not handled: %v", err)
+	}
+	// the select menu holds 25 pages, ending with the last one
+	menu := h.LastResponse().Components[1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	if len(menu.Options) != 25 || menu.Options[24].Value != "34" || !menu.Options[24].Default {
+		t.Errorf("unexpected page options %+v", menu.Options)
+	}
+	// items of a page already fetched are not loaded again
+	if item, err := pager.ItemAt(100); err != nil || item.(pagerItem) != 99 {
+		t.Errorf("want the last item, got %v (%v)", item, err)
+	}
+	if _, err := pager.ItemAt(101); err != discord.ErrPagerItemOutOfRange {
+		t.Errorf("want out of range, got %v", err)
+	}
+	if fmt.Sprint(loads) != "[0+3 99+1]" {
+		t.Errorf("unexpected loads %v", loads)
+	}
+}
+
+func TestPagerInDirectMessages(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	pager := discord.Pager{
+		IPagerLoader: &discord.LazyPagerLoader{
+			CountFunc: func() (int, error) { return 1, nil },
+			LoadFunc: func(skip, limit int) ([]discord.IPagerPart, error) {
+				return []discord.IPagerPart{pagerItem(0)}, nil
+			},
+		},
+		Limit:      3,
+		EmbedFrame: discord.InfoEmbed("items").Build(),
+	}
+	// interactions in direct messages carry a user instead of a member
+	i := discordtest.SlashCommand("items").Interaction
+	i.User, i.Member = i.Member.User, nil
+	if err := pager.Setup(i, h.DiscordService); err != nil {
+		t.Fatal(err)
+	}
+	if pager.OwnerUserID != discordtest.UserID {
+		t.Errorf("owner = %q, want %q", pager.OwnerUserID, discordtest.UserID)
+	}
+}
@@ -0,0 +1,173 @@
+package discord
+
+import (
+	"bytes"
+	"dalian-bot/internal/core"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultDeferAfter discord drops interactions left unanswered for 3 seconds.
+const defaultDeferAfter = 2 * time.Second
+
+type responderState int
+
+const (
+	responderPending responderState = iota
+	responderDeferred
+	responderResponded
+)
+
+// Responder Answer an interaction however long the handler takes: the interaction is deferred once the handler
+// runs past ServiceConfig.DeferAfter, and Respond then edits the deferred response instead.
+// Get one with Service.NewResponder and call Done when the handler returns.
+type Responder struct {
+	service   *Service
+	i         *discordgo.Interaction
+	ephemeral bool
+	lock      sync.Mutex
+	state     responderState
+	timer     *time.Timer
+}
+
+// NewResponder start the deferral timer of the interaction.
+// Ephemeral responders answer with messages only the user of the interaction can see.
+func (s *Service) NewResponder(i *discordgo.Interaction, ephemeral bool) *Responder {
+	r := &Responder{service: s, i: i, ephemeral: ephemeral}
+	r.timer = time.AfterFunc(s.DeferAfter, func() {
+		if err := r.Defer(); err != nil {
+			core.Logger.Warnf("Error deferring interaction: %v", err)
+		}
+	})
+	return r
+}
+
+// Interaction the interaction answered.
+func (r *Responder) Interaction() *discordgo.Interaction {
+	return r.i
+}
+
+// Done stop the deferral timer.
+func (r *Responder) Done() {
+	r.timer.Stop()
+}
+
+// Defer acknowledge the interaction, discord shows the bot thinking until Respond. Nothing happens once answered.
+func (r *Responder) Defer() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.state != responderPending {
+		return nil
+	}
+	err := r.service.InteractionRespondComplex(r.i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: r.flags()},
+	})
+	if err != nil {
+		return err
+	}
+	r.state = responderDeferred
+	return nil
+}
+
+// Respond send the response, or edit it in if the interaction was deferred. Once answered, send a follow-up.
+func (r *Responder) Respond(data *discordgo.InteractionResponseData) error {
+	r.timer.Stop()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch r.state {
+	case responderPending:
+		data.Flags |= r.flags()
+		if err := r.service.InteractionRespondComplex(r.i, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		}); err != nil {
+			return err
+		}
+	case responderDeferred:
+		if _, err := r.service.Session.InteractionResponseEdit(r.i, &discordgo.WebhookEdit{
+			Content:    &data.Content,
+			Embeds:     &data.Embeds,
+			Components: &data.Components,
+		}); err != nil {
+			return err
+		}
+	default:
+		_, err := r.followup(func() *discordgo.WebhookParams {
+			return &discordgo.WebhookParams{Content: data.Content, Embeds: data.Embeds, Components: data.Components}
+		})
+		return err
+	}
+	r.state = responderResponded
+	return nil
+}
+
+// RespondText see Respond.
+func (r *Responder) RespondText(content string) error {
+	return r.Respond(&discordgo.InteractionResponseData{Content: content})
+}
+
+// RespondEmbed see Respond.
+func (r *Responder) RespondEmbed(embed *discordgo.MessageEmbed, components ...discordgo.MessageComponent) error {
+	return r.Respond(&discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}, Components: components})
+}
+
+// EditOriginal edit the response sent by Respond.
+func (r *Responder) EditOriginal(edit *discordgo.WebhookEdit) (*discordgo.Message, error) {
+	return r.service.Session.InteractionResponseEdit(r.i, edit)
+}
+
+// Followup send another message for the interaction, ephemeral if the responder is. Answer with Respond first.
+func (r *Responder) Followup(params *discordgo.WebhookParams) (*discordgo.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.followup(func() *discordgo.WebhookParams {
+		copied := *params
+		return &copied
+	})
+}
+
+// FollowupCodeBlock send content as code block follow-ups, split or attached as a file like ChannelMessageSendCodeBlock.
+func (r *Responder) FollowupCodeBlock(content string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	chunks := splitMessage(content, MessageLengthLimit-codeBlockOverhead)
+	if len(chunks) > maxSplitMessages {
+		_, err := r.followup(func() *discordgo.WebhookParams {
+			// a fresh reader for every attempt
+			return &discordgo.WebhookParams{Files: []*discordgo.File{{
+				Name:        longContentFileName,
+				ContentType: "text/plain",
+				Reader:      bytes.NewReader([]byte(content)),
+			}}}
+		})
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := r.followup(func() *discordgo.WebhookParams {
+			return &discordgo.WebhookParams{Content: fmt.Sprintf("```\n%s\n```", chunk)}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followup send the params built for every attempt. Must be called with the lock held.
+func (r *Responder) followup(build func() *discordgo.WebhookParams) (*discordgo.Message, error) {
+	return r.service.withRetry(func() (*discordgo.Message, error) {
+		params := build()
+		params.Flags |= r.flags()
+		return r.service.Session.FollowupMessageCreate(r.i, true, params)
+	})
+}
+
+func (r *Responder) flags() discordgo.MessageFlags {
+	if r.ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
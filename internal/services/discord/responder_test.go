@@ -0,0 +1,103 @@
+package discord_test
+
+import (
+	"dalian-bot/internal/services/discord/discordtest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResponderRespondsInTime(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	r := h.DiscordService.NewResponder(discordtest.SlashCommand("fast").Interaction, true)
+	defer r.Done()
+	if err := r.RespondText("done"); err != nil {
+		t.Fatal(err)
+	}
+	resp := h.LastResponse()
+	if resp.Response.Type != discordgo.InteractionResponseChannelMessageWithSource || resp.Content != "done" {
+		t.Fatalf("want a direct response, got %+v", resp.Response)
+	}
+	if resp.Message.Flags&discordgo.MessageFlagsEphemeral == 0 {
+		t.Error("want an ephemeral response")
+	}
+	// the timer is stopped once answered
+	time.Sleep(h.DiscordService.DeferAfter / 100)
+	if n := len(h.Session.CallsOf("InteractionRespond")); n != 1 {
+		t.Errorf("want a single response, got %d", n)
+	}
+}
+
+func TestResponderDefersSlowHandlers(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	h.DiscordService.DeferAfter = 10 * time.Millisecond
+	r := h.DiscordService.NewResponder(discordtest.SlashCommand("slow").Interaction, false)
+	defer r.Done()
+	time.Sleep(50 * time.Millisecond)
+
+	resp := h.LastResponse()
+	if resp.Response.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Fatalf("want a deferred response, got %+v", resp.Response)
+	}
+	if resp.Message.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		t.Error("want a public response")
+	}
+	if err := r.RespondText("finally"); err != nil {
+		t.Fatal(err)
+	}
+	edits := h.Session.CallsOf("InteractionResponseEdit")
+	if len(edits) != 1 || edits[0].Content != "finally" || edits[0].Message.Content != "finally" {
+		t.Fatalf("want the deferred response edited, got %+v", edits)
+	}
+	if _, err := r.EditOriginal(&discordgo.WebhookEdit{Content: new(string)}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Session.CallsOf("InteractionResponseEdit")); n != 2 {
+		t.Errorf("want the original edited again, got %d edits", n)
+	}
+	if n := len(h.Session.CallsOf("InteractionRespond")); n != 1 {
+		t.Errorf("want no response besides the deferral, got %d", n)
+	}
+}
+
+func TestResponderFollowups(t *testing.T) {
+	h := discordtest.NewHarness(t)
+	r := h.DiscordService.NewResponder(discordtest.SlashCommand("dump").Interaction, true)
+	defer r.Done()
+	if err := r.RespondText("here's the dump"); err != nil {
+		t.Fatal(err)
+	}
+	// answering again sends a follow-up
+	if err := r.RespondText("again"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.FollowupCodeBlock(strings.Repeat("x\n", 1500)); err != nil {
+		t.Fatal(err)
+	}
+	followups := h.Session.CallsOf("FollowupMessageCreate")
+	if len(followups) != 3 || followups[0].Content != "again" {
+		t.Fatalf("want a follow-up and a dump split in two, got %d follow-ups", len(followups))
+	}
+	for _, f := range followups {
+		if f.Message.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("want ephemeral follow-ups, got %q", f.Content)
+		}
+	}
+	for _, f := range followups[1:] {
+		if !strings.HasPrefix(f.Content, "```") || len(f.Content) > 2000 {
+			t.Errorf("want code blocks within limits, got %d characters", len(f.Content))
+		}
+	}
+
+	// too long to split, attached as a file
+	h.Session.Reset()
+	if err := r.FollowupCodeBlock(strings.Repeat("x\n", 6000)); err != nil {
+		t.Fatal(err)
+	}
+	followups = h.Session.CallsOf("FollowupMessageCreate")
+	if len(followups) != 1 || len(followups[0].Content) != 12000 {
+		t.Fatalf("want a single attachment, got %d follow-ups", len(followups))
+	}
+}
@@ -2,26 +2,52 @@ package discord
 
 import (
 	"dalian-bot/internal/core"
+	"errors"
 	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"io"
-	"log"
 	"reflect"
 	"sync"
+	"time"
 )
 
-// ChannelMessageSend A wrapper of discordgo ChannelMessageSend function.
+// ChannelMessageSend Queue a text message to the channel, see ChannelMessageSendComplex.
+// Content over MessageLengthLimit is split on line boundaries, or sent as a file when it's way too long.
+// Return the last message sent.
 func (s *Service) ChannelMessageSend(channelID, content string) (*discordgo.Message, error) {
-	return s.Session.ChannelMessageSend(channelID, content)
+	return s.sendText(channelID, content, 0, func(chunk string) string { return chunk })
 }
 
+// ChannelMessageSendCodeBlock Like ChannelMessageSend, every message being a code block.
 func (s *Service) ChannelMessageSendCodeBlock(channelID, content string) (*discordgo.Message, error) {
-	return s.Session.ChannelMessageSend(channelID, fmt.Sprintf("```\r%s\r```", content))
+	return s.sendText(channelID, content, codeBlockOverhead, func(chunk string) string {
+		return fmt.Sprintf("```\n%s\n```", chunk)
+	})
 }
 
-// ChannelMessageSendEmbed A wrapper of discordgo ChannelMessageSendEmbed function.
+// ChannelMessageSendEmbed Queue an embed to the channel, see ChannelMessageSendComplex.
 func (s *Service) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
-	return s.Session.ChannelMessageSendEmbed(channelID, embed)
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}})
+}
+
+// ChannelMessageSendComplex Queue the message to the channel and wait until it's sent.
+// Messages to a channel are sent in order, and retried with backoff when rate limited or on server errors.
+func (s *Service) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	return s.queueSend(channelID, func() (*discordgo.Message, error) {
+		return s.withRetry(func() (*discordgo.Message, error) {
+			return s.Session.ChannelMessageSendComplex(channelID, data)
+		})
+	})
+}
+
+// DirectMessageSend Send a text message to the user in private, see ChannelMessageSend.
+// It fails when the user doesn't accept direct messages from the bot.
+func (s *Service) DirectMessageSend(userID, content string) (*discordgo.Message, error) {
+	channel, err := s.Session.UserChannelCreate(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChannelMessageSend(channel.ID, content)
 }
 
 // ChannelMessageReportError Report the error as a plain message to given gild channel.
@@ -45,6 +71,40 @@ func (s *Service) InteractionRespondEmbed(i *discordgo.Interaction, embed *disco
 	})
 }
 
+// InteractionRespondEphemeral Shortcut method for a simple message reply only the user can see.
+func (s *Service) InteractionRespondEphemeral(i *discordgo.Interaction, content string) error {
+	return s.InteractionRespondComplex(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// Modal limits of discord, in characters.
+const (
+	ModalTitleLimit     = 45
+	TextInputValueLimit = 4000
+)
+
+// InteractionRespondModal Open a form of text inputs, one per row. Its submission arrives as an
+// InteractionModalSubmit interaction carrying the customID, see ModalValues.
+func (s *Service) InteractionRespondModal(i *discordgo.Interaction, customID, title string, inputs ...discordgo.TextInput) error {
+	var rows []discordgo.MessageComponent
+	for _, input := range inputs {
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{input}})
+	}
+	return s.InteractionRespondComplex(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   customID,
+			Title:      truncateText(title, ModalTitleLimit),
+			Components: rows,
+		},
+	})
+}
+
 // InteractionRespond Shortcut method for a simple message reply.
 func (s *Service) InteractionRespond(i *discordgo.Interaction, content string) error {
 	return s.InteractionRespondComplex(i, &discordgo.InteractionResponse{
@@ -83,13 +143,17 @@ func (s *Service) InteractionRespondEditFromMessage(i *discordgo.Interaction, ms
 	return s.InteractionResponseEdit(i, tempWebhookEdit)
 }
 
-// ChannelFileSend send a file to given guild channel.
+// ChannelFileSend Queue a file to given guild channel.
 // channelID the id of a channel
 // name the display filename to be sent to discord
 // r the io reader containing a valid file struct
 func (s *Service) ChannelFileSend(channelID, name string, r io.Reader) error {
-	if _, err := s.Session.ChannelFileSend(channelID, name, r); err != nil {
-		log.Println("Error sending discord message: ", err)
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.sendFile(channelID, name, content); err != nil {
+		core.Logger.Warnf("Error sending discord file: %v", err)
 		return err
 	}
 	return nil
@@ -104,27 +168,45 @@ func (s *Service) WebhookMessageSend(webhookID, webhookToken, content string) er
 	return s.WebhookExecuteComplex(webhookID, webhookToken, false, &discordgo.WebhookParams{Content: content})
 }
 
-type appCommands []*discordgo.ApplicationCommand
-
-func (cmds appCommands) delete(cmd *discordgo.ApplicationCommand) appCommands {
-	j := 0
-	for _, val := range cmds {
-		if val.Name != cmd.Name {
-			cmds[j] = val
-		}
-	}
-	return cmds[:j]
-}
-
 type Service struct {
 	ServiceConfig
 	core.TriggerableEmbedUtil
-	Session              *discordgo.Session
-	registeredCommands   appCommands
+	Session              Session
+	commands             commandRegistry
+	outbox               outbox
 	DiscordAccountConfig core.MessengerConfig
+	// GuildConfigs overrides DiscordAccountConfig per guild when set, see MessengerConfig.
+	GuildConfigs IGuildConfigs
+}
+
+// IGuildConfigs Guild-specific messenger configs, such as a guild settings store.
+type IGuildConfigs interface {
+	// GuildMessengerConfig return base with the overrides of the guild applied.
+	GuildMessengerConfig(guildID string, base core.MessengerConfig) core.MessengerConfig
+}
+
+// MessengerConfig Return the config of the guild, DiscordAccountConfig for DMs or without GuildConfigs.
+// Plugins should resolve it for every message instead of reading DiscordAccountConfig.
+func (s *Service) MessengerConfig(guildID string) core.MessengerConfig {
+	if s.GuildConfigs == nil || guildID == "" {
+		return s.DiscordAccountConfig
+	}
+	return s.GuildConfigs.GuildMessengerConfig(guildID, s.DiscordAccountConfig)
 }
 
 func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if s.SendMaxAttempts <= 0 {
+		s.SendMaxAttempts = defaultSendMaxAttempts
+	}
+	if s.SendBaseBackoff <= 0 {
+		s.SendBaseBackoff = defaultSendBaseBackoff
+	}
+	if s.SendMaxBackoff <= 0 {
+		s.SendMaxBackoff = defaultSendMaxBackoff
+	}
+	if s.DeferAfter <= 0 {
+		s.DeferAfter = defaultDeferAfter
+	}
 	reg.RegisterService(s)
 	return nil
 }
@@ -135,20 +217,15 @@ func (s *Service) Start(wg *sync.WaitGroup) {
 	if err != nil {
 		core.Logger.Panicf("error creating Discord session:%v", err)
 	}
-	discordSession.Identify.Intents = discordgo.IntentGuildMessages
+	discordSession.Identify.Intents = discordgo.IntentGuildMessages | discordgo.IntentGuildMessageReactions
 	err = discordSession.Open()
 	if err != nil {
 		core.Logger.Panicf("error opening Discord connection:%v", err)
 	}
 	discordSession.AddHandler(s.messageCreate)
 	discordSession.AddHandler(s.interactionCreate)
-	s.Session = discordSession
-	//Todo: move it to config file
-	s.DiscordAccountConfig = core.MessengerConfig{
-		Prefix:    "$",
-		Separator: "$",
-		BotID:     s.Session.State.User.ID,
-	}
+	discordSession.AddHandler(s.messageReactionAdd)
+	s.Attach(discordSession, discordSession.State.User.ID)
 	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
 	//Send an online message if the config have an admin-channel
 	if s.ServiceConfig.AdminChannel != "" {
@@ -157,16 +234,52 @@ func (s *Service) Start(wg *sync.WaitGroup) {
 	wg.Done()
 }
 
+// Attach wire a connected session to the service.
+// Start attaches the real gateway session; tests may attach a fake one instead of calling Start.
+func (s *Service) Attach(session Session, botID string) {
+	s.Session = session
+	//Todo: move it to config file
+	s.DiscordAccountConfig = core.MessengerConfig{
+		Prefix:    "$",
+		Separator: "$",
+		BotID:     botID,
+	}
+}
+
+// Stop commands are left in place, so they keep working across restarts.
+// Sends waiting for a retry fail with their last error.
 func (s *Service) Stop(wg *sync.WaitGroup) error {
-	s.DisposeAllSlashCommand()
+	s.outbox.stop()
 	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
 	wg.Done()
 	return nil
 }
 
 func (s *Service) Status() error {
-	//TODO implement me
-	panic("implement me")
+	if s.Session == nil {
+		return errors.New("discord session is not attached")
+	}
+	// fake sessions are always up
+	if gateway, ok := s.Session.(*discordgo.Session); ok && !gateway.DataReady {
+		return errors.New("discord gateway is not connected")
+	}
+	return nil
+}
+
+// IsAdminMessage Return true if the message is sent in the admin channel, or by one of the owners.
+func (s *Service) IsAdminMessage(m *discordgo.Message) bool {
+	if s.AdminChannel != "" && m.ChannelID == s.AdminChannel {
+		return true
+	}
+	if m.Author == nil {
+		return false
+	}
+	for _, ownerID := range s.OwnerIDs {
+		if m.Author.ID == ownerID {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Service) Name() string {
@@ -185,56 +298,26 @@ func (s *Service) messageCreate(_ *discordgo.Session, m *discordgo.MessageCreate
 }
 
 func (s *Service) interactionCreate(_ *discordgo.Session, i *discordgo.InteractionCreate) {
-	//debugging
-	fmt.Printf("Int: %s:%s:%v \r\n", i.Member.User.Username, i.Data, i.Message)
+	// autocomplete goes to the plugin owning the command only
+	if s.HandleAutocomplete(i) {
+		return
+	}
 	t := core.Trigger{
-		Type: TriggerTypeDiscord,
-		Event: Event{
-			EventType:         EventTypeInteractionCreate,
-			InteractionCreate: i,
-		},
+		Type:  TriggerTypeDiscord,
+		Event: InteractionEvent(i),
 	}
 	s.TriggerChan <- t
 }
 
-func (s *Service) DisposeAllSlashCommand() error {
-	for _, v := range s.registeredCommands {
-		err := s.Session.ApplicationCommandDelete(s.Session.State.User.ID, "", v.ID)
-		if err != nil {
-			log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
-		} else {
-			core.Logger.Debugf("disposed slash command: %s", v.Name)
-		}
-	}
-	return nil
-}
-
-func (s *Service) DisposeSlashCommand(command core.IPlugin) error {
-	if slash, ok := command.(ISlashCommand); ok {
-		for _, cmd := range slash.GetAppCommandsMap() {
-			s.registeredCommands = s.registeredCommands.delete(cmd)
-			s.Session.ApplicationCommandDelete(s.Session.State.User.ID, "", cmd.ID)
-		}
-	}
-	return nil
-}
-
-func (s *Service) RegisterSlashCommand(plugin core.IPlugin) error {
-	if slash, ok := plugin.(ISlashCommand); ok {
-		for _, cmd := range slash.GetAppCommandsMap() {
-			cmd, err := s.Session.ApplicationCommandCreate(s.Session.State.User.ID, "", cmd)
-			if err != nil {
-				log.Panicf("Cannot register Command %v", err)
-			} else {
-				core.Logger.Debugf("Installed slash command: %s", cmd.Name)
-				s.registeredCommands = append(s.registeredCommands, cmd)
-			}
-		}
-	} else {
-		core.Logger.Errorf("NOT A SLASH CMD")
+func (s *Service) messageReactionAdd(_ *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	t := core.Trigger{
+		Type: TriggerTypeDiscord,
+		Event: Event{
+			EventType:          EventTypeMessageReactionAdd,
+			MessageReactionAdd: r,
+		},
 	}
-	core.Logger.Debugf("Registered slash command for plugin:%s", plugin.GetName())
-	return nil
+	s.TriggerChan <- t
 }
 
 func (s *Service) IsGuildMessageFromBotOrSelf(m *discordgo.Message) bool {
@@ -253,4 +336,14 @@ func (s *Service) IsGuildMessageFromBotOrSelf(m *discordgo.Message) bool {
 type ServiceConfig struct {
 	Token        string
 	AdminChannel string
+	// OwnerIDs users allowed to run admin commands outside AdminChannel.
+	OwnerIDs []string
+	// CommandGuildIDs register plugin commands in these guilds instead of globally.
+	// Guild commands are updated instantly, global ones can take a while to show up.
+	CommandGuildIDs []string
+	SendMaxAttempts int           // attempts of a rate limited or failed send, defaults to 5
+	SendBaseBackoff time.Duration // defaults to 1s, doubled after every failed attempt
+	SendMaxBackoff  time.Duration // defaults to 30s
+	// DeferAfter interactions answered through a Responder are deferred after this long, defaults to 2s.
+	DeferAfter time.Duration
 }
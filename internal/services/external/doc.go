@@ -0,0 +1,40 @@
+// Package external
+// Host for out-of-process plugins, written in any language.
+//
+// Every configured plugin is an executable talking JSON-RPC 2.0 with Dalian, one JSON object per line,
+// on its stdin and stdout. Stderr is copied to Dalian's log. A plugin that exits is restarted with backoff.
+//
+// Right after launch Dalian sends an `initialize` request:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocol":1,"name":"echo","discord":{"bot_id":"…","prefix":"$","separator":"$"}}}
+//
+// and the plugin answers with the triggers it subscribes to, and optionally the slash commands it owns:
+//
+//	{"jsonrpc":"2.0","id":1,"result":{"subscriptions":["discord.message","console.message"],"commands":[{"name":"echo","description":"…"}]}}
+//
+// A restarted plugin is initialized again, and the commands it declares then replace its previous ones.
+//
+// Triggers then arrive as `trigger` notifications: {"method":"trigger","params":{"kind":"discord.message","data":{…}}}.
+// Kinds and their data are:
+//
+//	discord.message      a discordgo Message
+//	discord.interaction  a discordgo Interaction, only for the plugin's own commands,
+//	                     and message components whose custom_id starts with "<plugin name>:"
+//	console.message      {"id","channel_id","author","content"}
+//	telegram.message     a telegram Message
+//	ddtv.webhook         a DDTV WebHook
+//
+// The plugin may call the following methods; params are objects, results are objects or null:
+//
+//	discord.send_message        {channel_id, content, embeds?}                   -> {id}
+//	discord.respond_interaction {interaction_id, content, embeds?, ephemeral?}    -> null
+//	console.send_message        {channel_id, content}                            -> null
+//	telegram.send_message       {chat_id, text}                                  -> {message_id}
+//	data.get                    {key}                                            -> {value}, value is null when absent
+//	data.put                    {key, value}                                     -> null
+//	data.delete                 {key}                                            -> {deleted}
+//	log                         {level, message}                                 -> null
+//
+// Interactions can only be responded to by the plugin they were delivered to, and data keys are private to
+// each plugin. Before Dalian stops, a `shutdown` notification is sent and stdin is closed.
+package external
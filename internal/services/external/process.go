@@ -0,0 +1,404 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"dalian-bot/internal/core"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	shutdownTimeout  = 5 * time.Second
+	minRestartDelay  = time.Second
+	maxRestartDelay  = time.Minute
+	// stableAfter a plugin running this long is restarted without delay the next time it exits.
+	stableAfter = time.Minute
+	// interactionTTL discord stops accepting interaction responses after 15 minutes.
+	interactionTTL = 15 * time.Minute
+	maxLineSize    = 1 << 20
+	outboxSize     = 256
+)
+
+var errConnClosed = errors.New("plugin connection closed")
+
+// conn A JSON-RPC connection over the stdio of a single run of a plugin.
+type conn struct {
+	outbox  chan []byte
+	done    chan struct{}
+	lock    sync.Mutex
+	pending map[int64]chan *rpcMessage
+	nextID  int64
+	closed  bool
+}
+
+func newConn(w io.WriteCloser) *conn {
+	c := &conn{
+		outbox:  make(chan []byte, outboxSize),
+		done:    make(chan struct{}),
+		pending: make(map[int64]chan *rpcMessage),
+	}
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case line := <-c.outbox:
+				if _, err := w.Write(line); err != nil {
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// send queue the message without blocking. A plugin not reading its stdin loses messages, not the host.
+func (c *conn) send(msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errConnClosed
+	}
+	select {
+	case c.outbox <- append(raw, '\n'):
+		return nil
+	default:
+		return errors.New("plugin is not reading its input, message dropped")
+	}
+}
+
+func (c *conn) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.send(rpcMessage{Method: method, Params: raw})
+}
+
+func (c *conn) call(method string, params any, timeout time.Duration) (json.RawMessage, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.nextID++
+	id := c.nextID
+	respChan := make(chan *rpcMessage, 1)
+	c.pending[id] = respChan
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, id)
+		c.lock.Unlock()
+	}()
+	if err := c.send(rpcMessage{ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: raw}); err != nil {
+		return nil, err
+	}
+	select {
+	case resp, ok := <-respChan:
+		if !ok {
+			return nil, errConnClosed
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%s timed out after %s", method, timeout)
+	}
+}
+
+// resolve hand a response to the pending call.
+func (c *conn) resolve(msg *rpcMessage) {
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	// held while sending, close may close respChan otherwise.
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return
+	}
+	if respChan, ok := c.pending[id]; ok {
+		// buffered for one, a duplicate response is dropped.
+		select {
+		case respChan <- msg:
+		default:
+		}
+	}
+}
+
+// close fail every pending call and stop writing.
+func (c *conn) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	for id, respChan := range c.pending {
+		close(respChan)
+		delete(c.pending, id)
+	}
+}
+
+// process A configured plugin, restarted whenever it exits.
+type process struct {
+	PluginConfig
+	service      *Service
+	lock         sync.RWMutex
+	conn         *conn
+	info         initializeResult
+	interactions map[string]rememberedInteraction
+	ready        chan struct{}
+	readyOnce    sync.Once
+}
+
+type rememberedInteraction struct {
+	*discordgo.Interaction
+	received time.Time
+}
+
+func newProcess(config PluginConfig, service *Service) *process {
+	return &process{
+		PluginConfig: config,
+		service:      service,
+		interactions: make(map[string]rememberedInteraction),
+		ready:        make(chan struct{}),
+	}
+}
+
+func (p *process) markReady() {
+	p.readyOnce.Do(func() { close(p.ready) })
+}
+
+// supervise run the plugin until ctx is done.
+func (p *process) supervise(ctx context.Context) {
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := p.runOnce(ctx)
+		// the first run is over, whether it succeeded or not
+		p.markReady()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > stableAfter {
+			delay = minRestartDelay
+		}
+		core.Logger.Warnf("External plugin [%s] exited: %v. Restarting in %s.", p.Name, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		if delay *= 2; delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+func (p *process) runOnce(ctx context.Context) error {
+	cmd := exec.Command(p.Command, p.Args...)
+	cmd.Dir = p.Dir
+	cmd.Env = append(os.Environ(), p.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = &logWriter{name: p.Name}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c := newConn(stdin)
+	readDone := make(chan error, 1)
+	go func() { readDone <- p.readLoop(c, stdout) }()
+
+	stop := func() {
+		c.notify("shutdown", struct{}{})
+		c.close()
+		select {
+		case <-readDone:
+		case <-time.After(shutdownTimeout):
+			cmd.Process.Kill()
+			<-readDone
+		}
+	}
+	result, err := c.call("initialize", p.service.initializeParams(p.Name), handshakeTimeout)
+	if err == nil {
+		var info initializeResult
+		if err = json.Unmarshal(result, &info); err == nil {
+			p.lock.Lock()
+			p.conn, p.info = c, info
+			p.lock.Unlock()
+			// before ready, Launch returns with the commands synced.
+			if p.service.OnHandshake != nil {
+				p.service.OnHandshake()
+			}
+			p.markReady()
+			core.Logger.Infof("External plugin [%s] is online, subscribing to %v.", p.Name, info.Subscriptions)
+			select {
+			case err = <-readDone:
+				c.close()
+			case <-ctx.Done():
+				stop()
+			}
+		}
+	} else {
+		stop()
+	}
+	p.lock.Lock()
+	p.conn = nil
+	p.lock.Unlock()
+	if waitErr := cmd.Wait(); waitErr != nil {
+		return waitErr
+	}
+	if err == nil {
+		err = errors.New("exited")
+	}
+	return err
+}
+
+func (p *process) readLoop(c *conn, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			core.Logger.Warnf("External plugin [%s] wrote a malformed message: %v", p.Name, err)
+			c.send(rpcMessage{ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.Method == "" {
+			c.resolve(&msg)
+			continue
+		}
+		go func() {
+			result, rpcErr := p.service.handleCall(p, msg.Method, msg.Params)
+			if len(msg.ID) == 0 {
+				// notification, no reply
+				return
+			}
+			reply := rpcMessage{ID: msg.ID, Error: rpcErr}
+			if rpcErr == nil {
+				reply.Result, _ = json.Marshal(result)
+			}
+			c.send(reply)
+		}()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// notify send a notification to the running plugin. Return false if it's down.
+func (p *process) notify(method string, params any) bool {
+	p.lock.RLock()
+	c := p.conn
+	p.lock.RUnlock()
+	if c == nil {
+		return false
+	}
+	if err := c.notify(method, params); err != nil {
+		core.Logger.Warnf("External plugin [%s]: %v", p.Name, err)
+		return false
+	}
+	return true
+}
+
+func (p *process) subscribes(kind string) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.conn != nil && slices.Contains(p.info.Subscriptions, kind)
+}
+
+// owns Return true if the interaction is for a command or component of this plugin.
+func (p *process) owns(i *discordgo.Interaction) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		name := i.ApplicationCommandData().Name
+		for _, cmd := range p.info.Commands {
+			if cmd.Name == name {
+				return true
+			}
+		}
+	case discordgo.InteractionMessageComponent:
+		customID := i.MessageComponentData().CustomID
+		return len(customID) > len(p.Name) && customID[:len(p.Name)+1] == p.Name+":"
+	}
+	return false
+}
+
+func (p *process) rememberInteraction(i *discordgo.Interaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	for id, remembered := range p.interactions {
+		if now.Sub(remembered.received) > interactionTTL {
+			delete(p.interactions, id)
+		}
+	}
+	p.interactions[i.ID] = rememberedInteraction{Interaction: i, received: now}
+}
+
+func (p *process) lookupInteraction(id string) (*discordgo.Interaction, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	remembered, ok := p.interactions[id]
+	if !ok || time.Since(remembered.received) > interactionTTL {
+		return nil, false
+	}
+	return remembered.Interaction, true
+}
+
+func (p *process) commands() []*discordgo.ApplicationCommand {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.Commands
+}
+
+// logWriter copy the stderr of a plugin to the log, line by line.
+type logWriter struct {
+	name    string
+	partial []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.partial = append(w.partial, b...)
+	for {
+		idx := slices.Index(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		core.Logger.Infof("[%s] %s", w.name, w.partial[:idx])
+		w.partial = w.partial[idx+1:]
+	}
+	return len(b), nil
+}
@@ -0,0 +1,113 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const protocolVersion = 1
+
+// Trigger kinds a plugin can subscribe to.
+const (
+	KindDiscordMessage     = "discord.message"
+	KindDiscordInteraction = "discord.interaction"
+	KindConsoleMessage     = "console.message"
+	KindTelegramMessage    = "telegram.message"
+	KindDDTVWebhook        = "ddtv.webhook"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeCallFailed     = -32000
+)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func callFailed(err error) *rpcError {
+	return &rpcError{Code: codeCallFailed, Message: err.Error()}
+}
+
+type initializeParams struct {
+	Protocol int            `json:"protocol"`
+	Name     string         `json:"name"`
+	Discord  *messengerInfo `json:"discord,omitempty"`
+	Console  *messengerInfo `json:"console,omitempty"`
+	Telegram *messengerInfo `json:"telegram,omitempty"`
+}
+
+type messengerInfo struct {
+	BotID     string `json:"bot_id"`
+	Prefix    string `json:"prefix"`
+	Separator string `json:"separator"`
+}
+
+type initializeResult struct {
+	Subscriptions []string                        `json:"subscriptions"`
+	Commands      []*discordgo.ApplicationCommand `json:"commands"`
+}
+
+type triggerParams struct {
+	Kind string `json:"kind"`
+	Data any    `json:"data"`
+}
+
+type consoleMessage struct {
+	ID        int64  `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Author    string `json:"author"`
+	Content   string `json:"content"`
+}
+
+type discordSendParams struct {
+	ChannelID string                    `json:"channel_id"`
+	Content   string                    `json:"content"`
+	Embeds    []*discordgo.MessageEmbed `json:"embeds"`
+}
+
+type discordRespondParams struct {
+	InteractionID string                    `json:"interaction_id"`
+	Content       string                    `json:"content"`
+	Embeds        []*discordgo.MessageEmbed `json:"embeds"`
+	Ephemeral     bool                      `json:"ephemeral"`
+}
+
+type consoleSendParams struct {
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+}
+
+type telegramSendParams struct {
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type dataParams struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type logParams struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,361 @@
+package external
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/ddtv"
+	"dalian-bot/internal/services/discord"
+	"dalian-bot/internal/services/telegram"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// kvCollection values of data.put, keyed by `<plugin>/<key>`.
+const kvCollection = "external_plugin_kv"
+
+// readyTimeout how long Launch waits for the first handshake of every plugin.
+const readyTimeout = handshakeTimeout + time.Second
+
+// Service Host of out-of-process plugins, see the package doc for the protocol.
+// Messenger and data services are optional: calls needing a missing service fail, and their triggers never come.
+type Service struct {
+	ServiceConfig
+	DiscordService  *discord.Service
+	ConsoleService  *console.Service
+	TelegramService *telegram.Service
+	DataService     *data.Service
+	processes       []*process
+	ctx             context.Context
+	cancel          context.CancelFunc
+	supervised      sync.WaitGroup
+	launchOnce      sync.Once
+
+	// OnHandshake called after every successful handshake of a plugin, its commands may have changed.
+	// Set it before Launch.
+	OnHandshake func()
+}
+
+type ServiceConfig struct {
+	Plugins []PluginConfig
+}
+
+// PluginConfig How to launch an external plugin.
+type PluginConfig struct {
+	// Name unique, also the prefix of the plugin's component custom ids.
+	Name    string
+	Command string
+	Args    []string
+	// Dir working directory, Dalian's when empty.
+	Dir string
+	// Env extra `KEY=value` variables.
+	Env []string
+}
+
+type kvPo struct {
+	Key   string `bson:"_id"`
+	Value string `bson:"value"` // JSON
+}
+
+func (s *Service) Name() string {
+	return "external"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	names := make(map[string]bool)
+	for _, config := range s.Plugins {
+		if config.Name == "" || config.Command == "" {
+			return fmt.Errorf("external plugin needs a name and a command: %+v", config)
+		}
+		if names[config.Name] {
+			return fmt.Errorf("duplicated external plugin: %s", config.Name)
+		}
+		names[config.Name] = true
+	}
+	for _, fetch := range []error{
+		reg.FetchService(&s.DiscordService),
+		reg.FetchService(&s.ConsoleService),
+		reg.FetchService(&s.TelegramService),
+		reg.FetchService(&s.DataService),
+	} {
+		if fetch != nil && !errors.Is(fetch, core.ErrServiceFetchUnknownService) {
+			return fetch
+		}
+	}
+	return reg.RegisterService(s)
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
+	wg.Done()
+}
+
+// Launch start every plugin, and wait until each one completed or failed its first handshake,
+// so their slash commands are known. Services start concurrently, so it must be called once all of them
+// are online, typically by the plugin bridging the bot and this service.
+func (s *Service) Launch() {
+	s.launchOnce.Do(func() {
+		for _, config := range s.Plugins {
+			p := newProcess(config, s)
+			s.processes = append(s.processes, p)
+			s.supervised.Add(1)
+			go func() {
+				defer s.supervised.Done()
+				p.supervise(s.ctx)
+			}()
+		}
+		deadline := time.After(readyTimeout)
+		for _, p := range s.processes {
+			select {
+			case <-p.ready:
+			case <-deadline:
+			}
+		}
+	})
+}
+
+// Stop ask every plugin to shut down, killing the ones that don't exit in time.
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	defer wg.Done()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.supervised.Wait()
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	return nil
+}
+
+func (s *Service) Status() error {
+	var down []string
+	for _, p := range s.processes {
+		p.lock.RLock()
+		if p.conn == nil {
+			down = append(down, p.Name)
+		}
+		p.lock.RUnlock()
+	}
+	if len(down) > 0 {
+		return fmt.Errorf("external plugin(s) down: %s", strings.Join(down, ", "))
+	}
+	return nil
+}
+
+// Commands Slash commands owned by the running plugins.
+func (s *Service) Commands() []*discordgo.ApplicationCommand {
+	var commands []*discordgo.ApplicationCommand
+	for _, p := range s.processes {
+		commands = append(commands, p.commands()...)
+	}
+	return commands
+}
+
+// Dispatch forward the trigger to the plugins subscribed to it.
+func (s *Service) Dispatch(trigger core.Trigger) {
+	var kind string
+	var payload any
+	var interaction *discordgo.Interaction
+	switch trigger.Type {
+	case discord.TriggerTypeDiscord:
+		event := discord.UnboxEvent(trigger)
+		switch event.EventType {
+		case discord.EventTypeMessageCreate:
+			if s.DiscordService.IsGuildMessageFromBotOrSelf(event.MessageCreate.Message) {
+				return
+			}
+			kind, payload = KindDiscordMessage, event.MessageCreate.Message
+		case discord.EventTypeInteractionCreate:
+			interaction = event.InteractionCreate.Interaction
+			kind, payload = KindDiscordInteraction, interaction
+		}
+	case console.TriggerTypeConsole:
+		m := console.UnboxEvent(trigger).Message
+		if m.Bot {
+			return
+		}
+		kind, payload = KindConsoleMessage, consoleMessage{ID: m.ID, ChannelID: m.ChannelID, Author: m.Author, Content: m.Content}
+	case telegram.TriggerTypeTelegram:
+		m := telegram.UnboxEvent(trigger).Message
+		if m == nil || s.TelegramService.IsMessageFromBotOrSelf(m) {
+			return
+		}
+		kind, payload = KindTelegramMessage, m
+	case ddtv.TriggerTypeDDTV:
+		kind, payload = KindDDTVWebhook, ddtv.UnboxEvent(trigger).WebHook
+	}
+	if kind == "" {
+		return
+	}
+	for _, p := range s.processes {
+		if !p.subscribes(kind) {
+			continue
+		}
+		if interaction != nil {
+			if !p.owns(interaction) {
+				continue
+			}
+			p.rememberInteraction(interaction)
+		}
+		p.notify("trigger", triggerParams{Kind: kind, Data: payload})
+	}
+}
+
+func (s *Service) initializeParams(name string) initializeParams {
+	params := initializeParams{Protocol: protocolVersion, Name: name}
+	if s.DiscordService != nil {
+		params.Discord = newMessengerInfo(s.DiscordService.DiscordAccountConfig)
+	}
+	if s.ConsoleService != nil {
+		params.Console = newMessengerInfo(s.ConsoleService.ConsoleAccountConfig)
+	}
+	if s.TelegramService != nil {
+		params.Telegram = newMessengerInfo(s.TelegramService.TelegramAccountConfig)
+	}
+	return params
+}
+
+func newMessengerInfo(config core.MessengerConfig) *messengerInfo {
+	return &messengerInfo{BotID: config.BotID, Prefix: config.Prefix, Separator: config.Separator}
+}
+
+// handleCall serve a request or notification of a plugin.
+func (s *Service) handleCall(p *process, method string, rawParams json.RawMessage) (any, *rpcError) {
+	ctx := context.Background()
+	switch method {
+	case "discord.send_message":
+		var params discordSendParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		if s.DiscordService == nil {
+			return nil, callFailed(errors.New("discord is not enabled"))
+		}
+		msg, err := s.DiscordService.ChannelMessageSendComplex(params.ChannelID, &discordgo.MessageSend{
+			Content: params.Content,
+			Embeds:  params.Embeds,
+		})
+		if err != nil {
+			return nil, callFailed(err)
+		}
+		return map[string]string{"id": msg.ID}, nil
+	case "discord.respond_interaction":
+		var params discordRespondParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		if s.DiscordService == nil {
+			return nil, callFailed(errors.New("discord is not enabled"))
+		}
+		interaction, ok := p.lookupInteraction(params.InteractionID)
+		if !ok {
+			return nil, callFailed(fmt.Errorf("unknown or expired interaction %s", params.InteractionID))
+		}
+		resp := &discordgo.InteractionResponseData{Content: params.Content, Embeds: params.Embeds}
+		if params.Ephemeral {
+			resp.Flags = discordgo.MessageFlagsEphemeral
+		}
+		if err := s.DiscordService.InteractionRespondComplex(interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: resp,
+		}); err != nil {
+			return nil, callFailed(err)
+		}
+		return nil, nil
+	case "console.send_message":
+		var params consoleSendParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		if s.ConsoleService == nil {
+			return nil, callFailed(errors.New("console is not enabled"))
+		}
+		if err := s.ConsoleService.ChannelMessageSend(params.ChannelID, params.Content); err != nil {
+			return nil, callFailed(err)
+		}
+		return nil, nil
+	case "telegram.send_message":
+		var params telegramSendParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		if s.TelegramService == nil {
+			return nil, callFailed(errors.New("telegram is not enabled"))
+		}
+		msg, err := s.TelegramService.SendMessage(params.ChatID, params.Text)
+		if err != nil {
+			return nil, callFailed(err)
+		}
+		return map[string]int64{"message_id": msg.MessageID}, nil
+	case "data.get", "data.put", "data.delete":
+		var params dataParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		if params.Key == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "key is required"}
+		}
+		if s.DataService == nil {
+			return nil, callFailed(errors.New("data service is not enabled"))
+		}
+		collection := s.DataService.Collection(kvCollection)
+		id := data.ByID(p.Name + "/" + params.Key)
+		switch method {
+		case "data.get":
+			var record kvPo
+			if err := collection.FindOne(ctx, &record, id); err != nil {
+				if errors.Is(err, data.ErrNotFound) {
+					return map[string]any{"value": nil}, nil
+				}
+				return nil, callFailed(err)
+			}
+			return map[string]json.RawMessage{"value": json.RawMessage(record.Value)}, nil
+		case "data.put":
+			if len(params.Value) == 0 || !json.Valid(params.Value) {
+				return nil, &rpcError{Code: codeInvalidParams, Message: "value must be valid JSON"}
+			}
+			if _, err := collection.UpdateOne(ctx, id, bson.M{"value": string(params.Value)}, true); err != nil {
+				return nil, callFailed(err)
+			}
+			return nil, nil
+		default:
+			deleted, err := collection.DeleteOne(ctx, id)
+			if err != nil {
+				return nil, callFailed(err)
+			}
+			return map[string]bool{"deleted": deleted > 0}, nil
+		}
+	case "log":
+		var params logParams
+		if err := decodeParams(rawParams, &params); err != nil {
+			return nil, err
+		}
+		switch params.Level {
+		case "debug":
+			core.Logger.Debugf("[%s] %s", p.Name, params.Message)
+		case "warn":
+			core.Logger.Warnf("[%s] %s", p.Name, params.Message)
+		case "error":
+			core.Logger.Errorf("[%s] %s", p.Name, params.Message)
+		default:
+			core.Logger.Infof("[%s] %s", p.Name, params.Message)
+		}
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "unknown method " + method}
+}
+
+func decodeParams(raw json.RawMessage, receiver any) *rpcError {
+	if err := json.Unmarshal(raw, receiver); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
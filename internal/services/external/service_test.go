@@ -0,0 +1,207 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/console"
+	"dalian-bot/internal/services/data"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// helperEnv makes the test binary act as an external plugin, see runHelperPlugin.
+const helperEnv = "DALIAN_EXTERNAL_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) != "" {
+		runHelperPlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runHelperPlugin Store every console message with data.put, read it back and reply with it.
+// Exit on "crash".
+func runHelperPlugin() {
+	var writeLock sync.Mutex
+	write := func(msg rpcMessage) {
+		msg.JSONRPC = "2.0"
+		raw, _ := json.Marshal(msg)
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		os.Stdout.Write(append(raw, '\n'))
+	}
+	var pendingLock sync.Mutex
+	pending := make(map[string]chan rpcMessage)
+	nextID := 0
+	call := func(method string, params any) json.RawMessage {
+		raw, _ := json.Marshal(params)
+		pendingLock.Lock()
+		nextID++
+		id := fmt.Sprint(nextID)
+		respChan := make(chan rpcMessage, 1)
+		pending[id] = respChan
+		pendingLock.Unlock()
+		write(rpcMessage{ID: json.RawMessage(id), Method: method, Params: raw})
+		return (<-respChan).Result
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg rpcMessage
+		json.Unmarshal(scanner.Bytes(), &msg)
+		switch msg.Method {
+		case "":
+			pendingLock.Lock()
+			respChan := pending[string(msg.ID)]
+			pendingLock.Unlock()
+			respChan <- msg
+		case "initialize":
+			result, _ := json.Marshal(map[string]any{
+				"subscriptions": []string{KindConsoleMessage},
+				"commands":      []map[string]string{{"name": "echo", "description": "echo"}},
+			})
+			write(rpcMessage{ID: msg.ID, Result: result})
+		case "trigger":
+			var params struct {
+				Data consoleMessage `json:"data"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			if params.Data.Content == "crash" {
+				os.Exit(3)
+			}
+			go func() {
+				call("data.put", map[string]any{"key": "last", "value": params.Data.Content})
+				var got struct {
+					Value string `json:"value"`
+				}
+				json.Unmarshal(call("data.get", map[string]string{"key": "last"}), &got)
+				call("console.send_message", map[string]string{"channel_id": params.Data.ChannelID, "content": "got " + got.Value})
+			}()
+		case "shutdown":
+			return
+		}
+	}
+}
+
+// syncBuffer console output written and read concurrently.
+type syncBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.String()
+}
+
+// newTestService a started service running the helper plugin, Launch it once set up.
+func newTestService(t *testing.T) (*Service, *syncBuffer) {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := core.NewServiceRegistry()
+	input, _ := io.Pipe()
+	output := &syncBuffer{}
+	consoleService := &console.Service{ServiceConfig: console.ServiceConfig{Input: input, Output: output}}
+	consoleService.Init(reg)
+	dataService := &data.Service{ServiceConfig: data.ServiceConfig{Path: filepath.Join(t.TempDir(), "dalian.db")}}
+	dataService.Init(reg)
+	s := &Service{ServiceConfig: ServiceConfig{Plugins: []PluginConfig{{
+		Name:    "helper",
+		Command: executable,
+		Env:     []string{helperEnv + "=1"},
+	}}}}
+	if err := s.Init(reg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	reg.StartAll()
+	t.Cleanup(reg.StopAll)
+	return s, output
+}
+
+func sendConsole(s *Service, content string) {
+	s.Dispatch(core.Trigger{Type: console.TriggerTypeConsole, Event: console.Event{
+		EventType: console.EventTypeMessage,
+		Message:   &console.Message{ChannelID: console.ChannelStdin, Author: "console", Content: content},
+	}})
+}
+
+func waitFor(t *testing.T, what string, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPluginRoundTrip(t *testing.T) {
+	s, output := newTestService(t)
+	s.Launch()
+	if err := s.Status(); err != nil {
+		t.Fatalf("plugin not ready after Launch: %v", err)
+	}
+	if commands := s.Commands(); len(commands) != 1 || commands[0].Name != "echo" {
+		t.Errorf("unexpected commands %+v", commands)
+	}
+	sendConsole(s, "hello")
+	waitFor(t, "reply", 2*time.Second, func() bool { return strings.Contains(output.String(), "got hello") })
+
+	// values are private to the plugin, stored under its name
+	var record kvPo
+	if err := s.DataService.Collection(kvCollection).FindOne(context.Background(), &record, data.ByID("helper/last")); err != nil || record.Value != `"hello"` {
+		t.Errorf("stored %+v (%v)", record, err)
+	}
+}
+
+func TestPluginRestartsAfterCrash(t *testing.T) {
+	s, output := newTestService(t)
+	var handshakes atomic.Int32
+	s.OnHandshake = func() { handshakes.Add(1) }
+	s.Launch()
+	if handshakes.Load() != 1 {
+		t.Fatalf("want 1 handshake after Launch, got %d", handshakes.Load())
+	}
+	sendConsole(s, "crash")
+	waitFor(t, "crash", 2*time.Second, func() bool { return s.Status() != nil })
+	waitFor(t, "restart", 5*time.Second, func() bool { return s.Status() == nil })
+	// commands are resynced after the new handshake
+	waitFor(t, "second handshake", time.Second, func() bool { return handshakes.Load() == 2 })
+	sendConsole(s, "again")
+	waitFor(t, "reply", 2*time.Second, func() bool { return strings.Contains(output.String(), "got again") })
+}
+
+func TestUnknownMethod(t *testing.T) {
+	s := &Service{}
+	if _, err := s.handleCall(newProcess(PluginConfig{Name: "p"}, s), "discord.ban", nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("want method not found, got %v", err)
+	}
+	if _, err := s.handleCall(newProcess(PluginConfig{Name: "p"}, s), "data.get", json.RawMessage(`{"key":""}`)); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("want invalid params, got %v", err)
+	}
+}
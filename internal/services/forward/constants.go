@@ -0,0 +1,25 @@
+package forward
+
+// Events forwarded by plugins. Destinations subscribe to a subset of them, or to all of them.
+const (
+	// EventDDTVWebhook a DDTV webhook accepted by a notify channel of the guild. Data is a ddtv.WebHook.
+	EventDDTVWebhook = "ddtv.webhook"
+	// EventArchiveSave a site saved with the archive plugin. Data is the archive document.
+	EventArchiveSave = "archive.save"
+	// EventPing sent by `/forward test`. Data is empty.
+	EventPing = "ping"
+)
+
+// Events every event a destination can subscribe to.
+var Events = []string{EventDDTVWebhook, EventArchiveSave, EventPing}
+
+const (
+	HeaderEvent     = "X-Dalian-Event"
+	HeaderDelivery  = "X-Dalian-Delivery"
+	HeaderSignature = "X-Dalian-Signature" // sha256=<hex HMAC-SHA256 of the body, keyed with the destination secret>
+)
+
+const (
+	destinationCollection = "forward_destinations"
+	deadLetterCollection  = "forward_dead_letters"
+)
@@ -0,0 +1,407 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/exp/slices"
+)
+
+// Service Forward bot events to external HTTP endpoints as signed JSON.
+// Destinations are configured per guild. Failed deliveries are retried with exponential backoff,
+// and kept as dead letters once MaxAttempts is reached or the endpoint rejects them.
+type Service struct {
+	ServiceConfig
+	DataService *data.Service
+	slots       chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+	inFlight    sync.WaitGroup
+	running     atomic.Bool
+}
+
+type ServiceConfig struct {
+	Client      *http.Client  // defaults to a client with a 10s timeout
+	MaxAttempts int           // defaults to 5
+	BaseBackoff time.Duration // defaults to 2s, doubled after every failed attempt
+	MaxBackoff  time.Duration // defaults to 5min
+	MaxInFlight int           // concurrent requests, defaults to 16
+	// AllowPrivateNetworks allow destinations in loopback, private and link-local networks.
+	// Guild admins choose the URLs, so they are refused by default to keep the bot's network out of reach.
+	AllowPrivateNetworks bool
+}
+
+// Schema storage of the service, see data.Schema.
+var Schema = data.Schema{
+	Namespace: "forward",
+	Indexes: []data.Index{
+		{Collection: destinationCollection, Name: "guild", Keys: []data.SortField{{Field: "guild_id"}}},
+		{Collection: deadLetterCollection, Name: "guild_created", Keys: []data.SortField{{Field: "guild_id"}, {Field: "created_time", Descending: true}}},
+	},
+}
+
+// Destination An endpoint receiving events of a guild.
+type Destination struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	GuildID     string             `bson:"guild_id"`
+	URL         string             `bson:"url"`
+	Secret      string             `bson:"secret"`
+	Events      []string           `bson:"events"` // empty for every event
+	CreatedBy   string             `bson:"created_by"`
+	CreatedTime time.Time          `bson:"created_time"`
+}
+
+func (d Destination) Accepts(event string) bool {
+	return len(d.Events) == 0 || slices.Contains(d.Events, event)
+}
+
+// Envelope The JSON body POSTed to destinations.
+type Envelope struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	GuildID string          `json:"guild_id"`
+	Time    time.Time       `json:"time"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// DeadLetter A delivery given up on. Body is the envelope exactly as it was sent.
+type DeadLetter struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	DestinationID primitive.ObjectID `bson:"destination_id"`
+	GuildID       string             `bson:"guild_id"`
+	URL           string             `bson:"url"`
+	Event         string             `bson:"event"`
+	DeliveryID    string             `bson:"delivery_id"`
+	Body          string             `bson:"body"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"last_error"`
+	CreatedTime   time.Time          `bson:"created_time"`
+}
+
+type delivery struct {
+	destination Destination
+	event       string
+	id          string
+	body        []byte
+}
+
+func (s *Service) Name() string {
+	return "forward"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&s.DataService); err != nil {
+		return err
+	}
+	if err := s.DataService.RegisterSchema(Schema); err != nil {
+		return err
+	}
+	if s.Client == nil {
+		s.Client = s.newClient()
+	}
+	if s.MaxAttempts <= 0 {
+		s.MaxAttempts = 5
+	}
+	if s.BaseBackoff <= 0 {
+		s.BaseBackoff = 2 * time.Second
+	}
+	if s.MaxBackoff <= 0 {
+		s.MaxBackoff = 5 * time.Minute
+	}
+	if s.MaxInFlight <= 0 {
+		s.MaxInFlight = 16
+	}
+	s.slots = make(chan struct{}, s.MaxInFlight)
+	return reg.RegisterService(s)
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.running.Store(true)
+	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
+	wg.Done()
+}
+
+// Stop Requests on the wire are canceled, they and deliveries waiting for a retry are dead-lettered.
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	defer wg.Done()
+	s.running.Store(false)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.inFlight.Wait()
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	return nil
+}
+
+func (s *Service) Status() error {
+	if !s.running.Load() {
+		return errors.New("forward service is not running")
+	}
+	return nil
+}
+
+// Publish Forward the event to every destination of the guild subscribed to it.
+// Deliveries happen in the background, Publish only fails if the payload can't be marshaled
+// or the destinations can't be loaded.
+func (s *Service) Publish(guildID, event string, payload any) error {
+	if !s.running.Load() {
+		return errors.New("forward service is not running")
+	}
+	destinations, err := s.Destinations(context.Background(), guildID)
+	if err != nil {
+		return err
+	}
+	var raw json.RawMessage
+	for _, destination := range destinations {
+		if !destination.Accepts(event) {
+			continue
+		}
+		if raw == nil {
+			if raw, err = json.Marshal(payload); err != nil {
+				return err
+			}
+		}
+		id := primitive.NewObjectID().Hex()
+		body, err := json.Marshal(Envelope{ID: id, Event: event, GuildID: guildID, Time: time.Now(), Data: raw})
+		if err != nil {
+			return err
+		}
+		s.enqueue(delivery{destination: destination, event: event, id: id, body: body})
+	}
+	return nil
+}
+
+func (s *Service) enqueue(d delivery) {
+	if !s.running.Load() {
+		s.deadLetter(d, 0, errors.New("forward service is not running"))
+		return
+	}
+	s.inFlight.Add(1)
+	go s.deliver(d)
+}
+
+// deliver post until success, a non-retryable failure, MaxAttempts or Stop.
+func (s *Service) deliver(d delivery) {
+	defer s.inFlight.Done()
+	backoff := s.BaseBackoff
+	attempts := 0
+	for {
+		attempts++
+		s.slots <- struct{}{}
+		retryable, err := s.post(d)
+		<-s.slots
+		if err == nil {
+			return
+		}
+		core.Logger.Debugf("forward %s to %s failed (attempt %d): %v", d.event, d.destination.URL, attempts, err)
+		if !retryable || attempts >= s.MaxAttempts {
+			s.deadLetter(d, attempts, err)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			s.deadLetter(d, attempts, fmt.Errorf("%w (service stopped before retrying)", err))
+			return
+		}
+		if backoff *= 2; backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
+
+// newClient A client refusing to connect to non-public addresses, checked at dial time so
+// neither DNS answers nor redirects can lead it there.
+func (s *Service) newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if !s.AllowPrivateNetworks {
+		// a proxy would make the dial check moot
+		transport.Proxy = nil
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// publicIP Whether the ip is neither loopback, private, link-local nor unspecified.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// post Return whether a failure is worth retrying: network errors, timeouts, 429 and 5xx are.
+func (s *Service) post(d delivery) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, d.destination.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dalian-bot")
+	req.Header.Set(HeaderEvent, d.event)
+	req.Header.Set(HeaderDelivery, d.id)
+	req.Header.Set(HeaderSignature, Sign(d.destination.Secret, d.body))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode >= 500
+	return retryable, fmt.Errorf("endpoint responded %s", resp.Status)
+}
+
+func (s *Service) deadLetter(d delivery, attempts int, cause error) {
+	core.Logger.Warnf("giving up forwarding %s to %s after %d attempt(s): %v", d.event, d.destination.URL, attempts, cause)
+	_, err := s.DataService.Collection(deadLetterCollection).InsertOne(context.Background(), DeadLetter{
+		DestinationID: d.destination.ID,
+		GuildID:       d.destination.GuildID,
+		URL:           d.destination.URL,
+		Event:         d.event,
+		DeliveryID:    d.id,
+		Body:          string(d.body),
+		Attempts:      attempts,
+		LastError:     cause.Error(),
+		CreatedTime:   time.Now(),
+	})
+	if err != nil {
+		core.Logger.Errorf("Error saving forward dead letter: %v", err)
+	}
+}
+
+// Sign The HeaderSignature value of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// AddDestination Validate and save the destination. A random secret is generated if it has none.
+func (s *Service) AddDestination(ctx context.Context, d Destination) (Destination, error) {
+	u, err := url.Parse(d.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return d, fmt.Errorf("not a valid http(s) url: %s", d.URL)
+	}
+	if !s.AllowPrivateNetworks {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); (ip != nil && !publicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return d, fmt.Errorf("not a public address: %s", host)
+		}
+	}
+	for _, event := range d.Events {
+		if !slices.Contains(Events, event) {
+			return d, fmt.Errorf("unknown event: %s", event)
+		}
+	}
+	if d.Secret == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return d, err
+		}
+		d.Secret = hex.EncodeToString(secret)
+	}
+	d.CreatedTime = time.Now()
+	id, err := s.DataService.Collection(destinationCollection).InsertOne(ctx, d)
+	if err != nil {
+		return d, err
+	}
+	d.ID = id.(primitive.ObjectID)
+	return d, nil
+}
+
+func (s *Service) Destinations(ctx context.Context, guildID string) ([]Destination, error) {
+	var destinations []Destination
+	err := s.DataService.Collection(destinationCollection).Find(ctx, &destinations, data.Where(data.Eq("guild_id", guildID)),
+		data.FindOptions{Sort: []data.SortField{{Field: "created_time"}}})
+	return destinations, err
+}
+
+// RemoveDestination Return false if the guild has no such destination.
+func (s *Service) RemoveDestination(ctx context.Context, guildID string, id primitive.ObjectID) (bool, error) {
+	deleted, err := s.DataService.Collection(destinationCollection).DeleteOne(ctx, data.ByID(id).And(data.Eq("guild_id", guildID)))
+	return deleted > 0, err
+}
+
+// SendPing Forward a EventPing to the destination, regardless of its subscribed events.
+func (s *Service) SendPing(guildID string, id primitive.ObjectID) error {
+	var destination Destination
+	if err := s.DataService.Collection(destinationCollection).FindOne(context.Background(), &destination,
+		data.ByID(id).And(data.Eq("guild_id", guildID))); err != nil {
+		return err
+	}
+	deliveryID := primitive.NewObjectID().Hex()
+	body, err := json.Marshal(Envelope{ID: deliveryID, Event: EventPing, GuildID: guildID, Time: time.Now(), Data: json.RawMessage("{}")})
+	if err != nil {
+		return err
+	}
+	s.enqueue(delivery{destination: destination, event: EventPing, id: deliveryID, body: body})
+	return nil
+}
+
+// DeadLetters Return the latest dead letters of the guild.
+func (s *Service) DeadLetters(ctx context.Context, guildID string, limit int64) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := s.DataService.Collection(deadLetterCollection).Find(ctx, &letters, data.Where(data.Eq("guild_id", guildID)),
+		data.FindOptions{Sort: []data.SortField{{Field: "created_time", Descending: true}}, Limit: limit})
+	return letters, err
+}
+
+// Redeliver Queue every dead letter of the guild again, with the original body.
+// Letters of removed destinations are kept. Return the number of queued deliveries.
+func (s *Service) Redeliver(ctx context.Context, guildID string) (int, error) {
+	letters, err := s.DeadLetters(ctx, guildID, 0)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, letter := range letters {
+		var destination Destination
+		err := s.DataService.Collection(destinationCollection).FindOne(ctx, &destination, data.ByID(letter.DestinationID))
+		if errors.Is(err, data.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return queued, err
+		}
+		if _, err := s.DataService.Collection(deadLetterCollection).DeleteOne(ctx, data.ByID(letter.ID)); err != nil {
+			return queued, err
+		}
+		s.enqueue(delivery{destination: destination, event: letter.Event, id: letter.DeliveryID, body: []byte(letter.Body)})
+		queued++
+	}
+	return queued, nil
+}
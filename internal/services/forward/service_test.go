@@ -0,0 +1,201 @@
+package forward
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const testGuild = "guild"
+
+// endpoint answers with the queued status codes, then 200.
+type endpoint struct {
+	sync.Mutex
+	statuses []int
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	e.Lock()
+	defer e.Unlock()
+	e.received = append(e.received, r)
+	e.bodies = append(e.bodies, body)
+	status := http.StatusOK
+	if len(e.statuses) > 0 {
+		status, e.statuses = e.statuses[0], e.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (e *endpoint) count() int {
+	e.Lock()
+	defer e.Unlock()
+	return len(e.received)
+}
+
+// newTestService a service allowing private networks, as test endpoints listen on loopback.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return newTestServiceWith(t, ServiceConfig{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond,
+		AllowPrivateNetworks: true})
+}
+
+func newTestServiceWith(t *testing.T, config ServiceConfig) *Service {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	reg := core.NewServiceRegistry()
+	dataService := &data.Service{ServiceConfig: data.ServiceConfig{Path: filepath.Join(t.TempDir(), "dalian.db")}}
+	dataService.Init(reg)
+	s := &Service{ServiceConfig: config}
+	if err := s.Init(reg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	reg.StartAll()
+	t.Cleanup(reg.StopAll)
+	return s
+}
+
+func addEndpoint(t *testing.T, s *Service, e *endpoint, events ...string) Destination {
+	t.Helper()
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	d, err := s.AddDestination(context.Background(), Destination{GuildID: testGuild, URL: server.URL, Events: events})
+	if err != nil {
+		t.Fatalf("AddDestination: %v", err)
+	}
+	return d
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPublishSignsEnvelope(t *testing.T) {
+	s := newTestService(t)
+	e := &endpoint{}
+	d := addEndpoint(t, s, e)
+	if len(d.Secret) == 0 {
+		t.Fatal("want a generated secret")
+	}
+	if err := s.Publish(testGuild, EventArchiveSave, map[string]string{"site": "https://example.com"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, "delivery", func() bool { return e.count() == 1 })
+	req, body := e.received[0], e.bodies[0]
+	if req.Header.Get(HeaderSignature) != Sign(d.Secret, body) || req.Header.Get(HeaderEvent) != EventArchiveSave {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("body is not an envelope: %v", err)
+	}
+	if envelope.GuildID != testGuild || envelope.ID != req.Header.Get(HeaderDelivery) || string(envelope.Data) != `{"site":"https://example.com"}` {
+		t.Errorf("unexpected envelope: %+v", envelope)
+	}
+}
+
+func TestPublishFiltersEvents(t *testing.T) {
+	s := newTestService(t)
+	e := &endpoint{}
+	addEndpoint(t, s, e, EventDDTVWebhook)
+	s.Publish(testGuild, EventArchiveSave, struct{}{})
+	s.Publish("other guild", EventDDTVWebhook, struct{}{})
+	s.Publish(testGuild, EventDDTVWebhook, struct{}{})
+	waitFor(t, "delivery", func() bool { return e.count() == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if e.count() != 1 || e.received[0].Header.Get(HeaderEvent) != EventDDTVWebhook {
+		t.Errorf("want only the subscribed event of the guild, got %d deliveries", e.count())
+	}
+}
+
+func TestRetryThenDeadLetterAndRedeliver(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	// retried on 5xx, then succeeds
+	retried := &endpoint{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	addEndpoint(t, s, retried)
+	// rejected for good on 4xx
+	rejecting := &endpoint{statuses: []int{http.StatusBadRequest}}
+	addEndpoint(t, s, rejecting)
+	// keeps failing until MaxAttempts
+	failing := &endpoint{statuses: []int{500, 500, 500}}
+	addEndpoint(t, s, failing)
+
+	s.Publish(testGuild, EventPing, struct{}{})
+	waitFor(t, "dead letters", func() bool {
+		letters, _ := s.DeadLetters(ctx, testGuild, 0)
+		return len(letters) == 2
+	})
+	s.inFlight.Wait()
+	if retried.count() != 3 || rejecting.count() != 1 || failing.count() != 3 {
+		t.Errorf("attempts: retried %d, rejecting %d, failing %d", retried.count(), rejecting.count(), failing.count())
+	}
+
+	// both endpoints answer 200 from now on
+	queued, err := s.Redeliver(ctx, testGuild)
+	if err != nil || queued != 2 {
+		t.Fatalf("Redeliver = %d (%v)", queued, err)
+	}
+	s.inFlight.Wait()
+	if letters, _ := s.DeadLetters(ctx, testGuild, 0); len(letters) != 0 {
+		t.Errorf("want no dead letter left, got %+v", letters)
+	}
+	if string(rejecting.bodies[1]) != string(rejecting.bodies[0]) {
+		t.Error("redelivery must send the original body")
+	}
+}
+
+func TestAddDestinationValidation(t *testing.T) {
+	s := newTestService(t)
+	for _, d := range []Destination{
+		{GuildID: testGuild, URL: "ftp://example.com"},
+		{GuildID: testGuild, URL: "not a url"},
+		{GuildID: testGuild, URL: "https://example.com", Events: []string{"nope"}},
+	} {
+		if _, err := s.AddDestination(context.Background(), d); err == nil {
+			t.Errorf("want an error for %+v", d)
+		}
+	}
+}
+
+func TestPrivateNetworksAreRefused(t *testing.T) {
+	s := newTestServiceWith(t, ServiceConfig{MaxAttempts: 1})
+	for _, target := range []string{"http://127.0.0.1:8080", "http://[::1]/", "http://10.0.0.1", "http://169.254.169.254/latest/meta-data",
+		"http://localhost:9000"} {
+		if _, err := s.AddDestination(context.Background(), Destination{GuildID: testGuild, URL: target}); err == nil {
+			t.Errorf("want %s to be refused", target)
+		}
+	}
+
+	// hostnames may resolve to private addresses, the dial itself is refused then
+	e := &endpoint{}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	d := delivery{destination: Destination{URL: server.URL}, event: EventPing, id: "id", body: []byte("{}")}
+	if _, err := s.post(d); err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Errorf("want the dial to be refused, got %v", err)
+	}
+	if e.count() != 0 {
+		t.Error("the endpoint must not be reached")
+	}
+}
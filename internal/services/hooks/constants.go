@@ -0,0 +1,20 @@
+package hooks
+
+import "dalian-bot/internal/core"
+
+const TriggerTypeHook core.TriggerType = "hook"
+
+// Payload decoders of endpoints, see Event.Body.
+const (
+	DecoderJSON = "json"
+	DecoderForm = "form"
+	DecoderRaw  = "raw"
+)
+
+const (
+	// HeaderToken carries the secret of the endpoint. The `token` query parameter is accepted as well.
+	HeaderToken = "X-Dalian-Token"
+	QueryToken  = "token"
+	// DefaultSignatureHeader carries sha256=<hex HMAC-SHA256 of the body, keyed with the secret of the endpoint>.
+	DefaultSignatureHeader = "X-Dalian-Signature"
+)
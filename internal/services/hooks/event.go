@@ -0,0 +1,31 @@
+package hooks
+
+import (
+	"dalian-bot/internal/core"
+	"net/http"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTypeHook EventType = "hook"
+)
+
+// Event A call accepted by an endpoint.
+type Event struct {
+	EventType EventType
+	Endpoint  string // name of the endpoint, as in /hooks/<name>
+	// Body the payload decoded by the decoder of the endpoint:
+	// any for json (map[string]any for objects), url.Values for form and []byte for raw.
+	Body         any
+	Raw          []byte
+	Headers      http.Header
+	RemoteIP     string
+	ReceivedTime time.Time
+}
+
+func UnboxEvent(t core.Trigger) Event {
+	var e = t.Event.(Event)
+	return e
+}
@@ -0,0 +1,197 @@
+package hooks
+
+import (
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/web"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Service Inbound webhooks of any source, received at /hooks/<name> of the web service.
+// Every accepted call becomes a TriggerTypeHook trigger, plugins filter them by Event.Endpoint.
+type Service struct {
+	ServiceConfig
+	WebService *web.Service
+	core.TriggerableEmbedUtil
+	endpoints map[string]*Endpoint
+}
+
+type ServiceConfig struct {
+	Endpoints []Endpoint
+}
+
+// Endpoint A named source of webhooks and how its calls are authenticated and decoded.
+type Endpoint struct {
+	Name    string // letters, digits, - and _
+	Decoder string // DecoderJSON by default, DecoderForm or DecoderRaw
+	// Secret sent as HeaderToken or QueryToken, or used to sign the body. Calls are not authenticated when empty.
+	Secret string
+	// RequireSignature accept calls with a valid signature of the body in SignatureHeader only, not the token.
+	RequireSignature bool
+	SignatureHeader  string // defaults to DefaultSignatureHeader, e.g. X-Hub-Signature-256 for GitHub
+	// AllowedIPs IPs or CIDRs the source calls from, any when empty.
+	AllowedIPs  []string
+	allowedNets []*net.IPNet
+}
+
+// maxHookSize bodies over 1MB are rejected.
+const maxHookSize = 1 << 20
+
+var endpointNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (s *Service) Name() string {
+	return "hooks"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&s.WebService); err != nil {
+		return err
+	}
+	s.endpoints = make(map[string]*Endpoint)
+	for i := range s.Endpoints {
+		endpoint := &s.Endpoints[i]
+		if err := endpoint.init(); err != nil {
+			return err
+		}
+		if _, ok := s.endpoints[endpoint.Name]; ok {
+			return fmt.Errorf("hook endpoint %s is declared twice", endpoint.Name)
+		}
+		s.endpoints[endpoint.Name] = endpoint
+	}
+	s.WebService.Group("/hooks").POST("/:name", s.handleHook)
+	return reg.RegisterService(s)
+}
+
+// init validate the endpoint and fill in the defaults.
+func (e *Endpoint) init() error {
+	if !endpointNamePattern.MatchString(e.Name) {
+		return fmt.Errorf("invalid hook endpoint name %q", e.Name)
+	}
+	switch e.Decoder {
+	case "":
+		e.Decoder = DecoderJSON
+	case DecoderJSON, DecoderForm, DecoderRaw:
+	default:
+		return fmt.Errorf("hook endpoint %s: unknown decoder %s", e.Name, e.Decoder)
+	}
+	if e.RequireSignature && e.Secret == "" {
+		return fmt.Errorf("hook endpoint %s: a signature needs a secret", e.Name)
+	}
+	if e.SignatureHeader == "" {
+		e.SignatureHeader = DefaultSignatureHeader
+	}
+	nets, err := web.ParseIPNets(e.AllowedIPs)
+	if err != nil {
+		return fmt.Errorf("hook endpoint %s: %w", e.Name, err)
+	}
+	e.allowedNets = nets
+	if e.Secret == "" && len(e.allowedNets) == 0 {
+		core.Logger.Warnf("Hook endpoint %s has neither secret nor allowed IPs, calls are accepted from anyone.", e.Name)
+	}
+	return nil
+}
+
+// authenticate Return why the call is rejected, or an empty string.
+func (e *Endpoint) authenticate(c *gin.Context, body []byte) string {
+	if !web.AllowsIP(e.allowedNets, net.ParseIP(c.ClientIP())) {
+		return "ip"
+	}
+	if e.Secret == "" {
+		return ""
+	}
+	if e.RequireSignature {
+		if !web.ValidSignature(e.Secret, c.GetHeader(e.SignatureHeader), body) {
+			return "signature"
+		}
+		return ""
+	}
+	token := c.GetHeader(HeaderToken)
+	if token == "" {
+		token = c.Query(QueryToken)
+	}
+	if !web.ValidToken(e.Secret, token) {
+		return "token"
+	}
+	return ""
+}
+
+// decode the body, see Event.Body.
+func (e *Endpoint) decode(body []byte) (any, error) {
+	switch e.Decoder {
+	case DecoderForm:
+		return url.ParseQuery(string(body))
+	case DecoderRaw:
+		return body, nil
+	default:
+		var payload any
+		err := json.Unmarshal(body, &payload)
+		return payload, err
+	}
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	core.Logger.Debugf("Service [%s] is now online with %d endpoint(s).", reflect.TypeOf(s), len(s.endpoints))
+	wg.Done()
+}
+
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	wg.Done()
+	return nil
+}
+
+// Status hooks are received through the web service.
+func (s *Service) Status() error {
+	if s.WebService == nil {
+		return errors.New("hooks service is not initialized")
+	}
+	return s.WebService.Status()
+}
+
+func (s *Service) handleHook(c *gin.Context) {
+	endpoint, ok := s.endpoints[c.Param("name")]
+	if !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxHookSize))
+	if err != nil {
+		core.Logger.Warnf("Error reading hook %s from %s: %v", endpoint.Name, c.ClientIP(), err)
+		c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	if reason := endpoint.authenticate(c, body); reason != "" {
+		core.Logger.Warnf("Rejected hook %s from %s: invalid %s.", endpoint.Name, c.ClientIP(), reason)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	payload, err := endpoint.decode(body)
+	if err != nil {
+		core.Logger.Warnf("Malformed hook %s from %s: %v", endpoint.Name, c.ClientIP(), err)
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	c.Status(http.StatusOK)
+	s.SendTrigger(core.Trigger{
+		Type: TriggerTypeHook,
+		Event: Event{
+			EventType:    EventTypeHook,
+			Endpoint:     endpoint.Name,
+			Body:         payload,
+			Raw:          body,
+			Headers:      c.Request.Header.Clone(),
+			RemoteIP:     c.ClientIP(),
+			ReceivedTime: time.Now(),
+		},
+	})
+}
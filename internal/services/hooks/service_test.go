@@ -0,0 +1,116 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/web"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newTestService(t *testing.T, endpoints ...Endpoint) (*web.Service, chan core.Trigger) {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	gin.SetMode(gin.TestMode)
+	reg := core.NewServiceRegistry()
+	webService := &web.Service{}
+	if err := webService.Init(reg); err != nil {
+		t.Fatalf("web init: %v", err)
+	}
+	s := &Service{ServiceConfig: ServiceConfig{Endpoints: endpoints}}
+	if err := s.Init(reg); err != nil {
+		t.Fatalf("hooks init: %v", err)
+	}
+	triggers := make(chan core.Trigger, 10)
+	s.InstallTriggerChan(triggers)
+	return webService, triggers
+}
+
+func post(s *web.Service, target, remoteAddr string, headers map[string]string, body string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.GinEngine.ServeHTTP(w, req)
+	return w.Code
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHookEndpoints(t *testing.T) {
+	s, triggers := newTestService(t,
+		Endpoint{Name: "ci", Secret: "ci-secret"},
+		Endpoint{Name: "github", Decoder: DecoderForm, Secret: "gh-secret", RequireSignature: true, SignatureHeader: "X-Hub-Signature-256"},
+		Endpoint{Name: "nas", Decoder: DecoderRaw, AllowedIPs: []string{"10.0.0.0/8"}},
+	)
+	form := "action=push&ref=main"
+	for _, tc := range []struct {
+		name       string
+		target     string
+		remoteAddr string
+		headers    map[string]string
+		body       string
+		status     int
+	}{
+		{"unknown endpoint", "/hooks/nope", "10.1.2.3:1234", nil, `{}`, http.StatusNotFound},
+		{"json with token", "/hooks/ci?token=ci-secret", "192.0.2.1:1234", nil, `{"build":42,"ok":true}`, http.StatusOK},
+		{"wrong token", "/hooks/ci", "192.0.2.1:1234", map[string]string{HeaderToken: "nope"}, `{}`, http.StatusUnauthorized},
+		{"malformed json", "/hooks/ci", "192.0.2.1:1234", map[string]string{HeaderToken: "ci-secret"}, `{`, http.StatusBadRequest},
+		{"token without signature", "/hooks/github?token=gh-secret", "192.0.2.1:1234", nil, form, http.StatusUnauthorized},
+		{"signed form", "/hooks/github", "192.0.2.1:1234", map[string]string{"X-Hub-Signature-256": sign("gh-secret", form)}, form, http.StatusOK},
+		{"raw from allowed ip", "/hooks/nas", "10.1.2.3:1234", map[string]string{"X-Disk": "full"}, "disk full", http.StatusOK},
+		{"raw from other ip", "/hooks/nas", "192.0.2.1:1234", nil, "disk full", http.StatusUnauthorized},
+	} {
+		if status := post(s, tc.target, tc.remoteAddr, tc.headers, tc.body); status != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, status, tc.status)
+		}
+	}
+
+	if len(triggers) != 3 {
+		t.Fatalf("want 3 triggers, got %d", len(triggers))
+	}
+	ci := UnboxEvent(<-triggers)
+	if body, ok := ci.Body.(map[string]any); ci.Endpoint != "ci" || !ok || body["build"] != float64(42) {
+		t.Errorf("unexpected ci event: %+v", ci)
+	}
+	github := UnboxEvent(<-triggers)
+	if values, ok := github.Body.(url.Values); !ok || values.Get("ref") != "main" {
+		t.Errorf("unexpected github event: %+v", github)
+	}
+	nas := UnboxEvent(<-triggers)
+	if body, ok := nas.Body.([]byte); !ok || string(body) != "disk full" || nas.Headers.Get("X-Disk") != "full" || nas.RemoteIP != "10.1.2.3" {
+		t.Errorf("unexpected nas event: %+v", nas)
+	}
+}
+
+func TestInitRejectsInvalidEndpoints(t *testing.T) {
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	for _, endpoint := range []Endpoint{
+		{Name: "a/b"},
+		{Name: "x", Decoder: "xml"},
+		{Name: "x", RequireSignature: true},
+		{Name: "x", AllowedIPs: []string{"not an ip"}},
+	} {
+		reg := core.NewServiceRegistry()
+		webService := &web.Service{}
+		webService.Init(reg)
+		s := &Service{ServiceConfig: ServiceConfig{Endpoints: []Endpoint{endpoint}}}
+		if err := s.Init(reg); err == nil {
+			t.Errorf("endpoint %+v should be rejected", endpoint)
+		}
+	}
+}
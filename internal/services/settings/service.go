@@ -0,0 +1,196 @@
+package settings
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/data"
+	"dalian-bot/internal/services/discord"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const guildCollection = "guild_settings"
+
+// Limits of the text settings, in characters.
+const (
+	PrefixLimit    = 5
+	SeparatorLimit = 3
+)
+
+// Schema storage of the service, see data.Schema.
+var Schema = data.Schema{
+	Namespace: "settings",
+	Indexes: []data.Index{
+		{Collection: guildCollection, Name: "guild", Keys: []data.SortField{{Field: "guild_id"}}, Unique: true},
+	},
+}
+
+// GuildSettings Per-guild overrides. Empty fields fall back to the defaults of the bot.
+type GuildSettings struct {
+	GuildID         string    `bson:"guild_id"`
+	Prefix          string    `bson:"prefix"`
+	Separator       string    `bson:"separator"`
+	Locale          string    `bson:"locale"`   // a discord locale, e.g. en-US
+	Timezone        string    `bson:"timezone"` // an IANA name, e.g. Asia/Shanghai
+	NotifyChannelID string    `bson:"notify_channel_id"`
+	UpdatedBy       string    `bson:"updated_by"`
+	UpdatedTime     time.Time `bson:"updated_time"`
+}
+
+// Validate Return an error describing the first invalid setting.
+func (gs GuildSettings) Validate() error {
+	if gs.Prefix != "" && (len([]rune(gs.Prefix)) > PrefixLimit || strings.ContainsAny(gs.Prefix, " \t\r\n")) {
+		return fmt.Errorf("prefix must be at most %d characters without spaces", PrefixLimit)
+	}
+	if gs.Separator != "" && (len([]rune(gs.Separator)) > SeparatorLimit || strings.TrimSpace(gs.Separator) == "") {
+		return fmt.Errorf("separator must be at most %d characters, not only spaces", SeparatorLimit)
+	}
+	if gs.Locale != "" {
+		if _, ok := discordgo.Locales[discordgo.Locale(gs.Locale)]; !ok {
+			return fmt.Errorf("unknown locale %s", gs.Locale)
+		}
+	}
+	if gs.Timezone != "" {
+		if _, err := time.LoadLocation(gs.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %s", gs.Timezone)
+		}
+	}
+	return nil
+}
+
+// Location Return the timezone of the guild, the one of the bot when not set.
+func (gs GuildSettings) Location() *time.Location {
+	if gs.Timezone == "" {
+		return time.Local
+	}
+	if loc, err := time.LoadLocation(gs.Timezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// Service Guild settings stored with the data service and cached in memory.
+// When a discord.Service is registered, it resolves the messenger config of guilds through this service.
+type Service struct {
+	DataService    *data.Service
+	DiscordService *discord.Service
+	lock           sync.RWMutex
+	cache          map[string]GuildSettings // guild id : settings, guilds without settings included
+}
+
+func (s *Service) Name() string {
+	return "settings"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if err := reg.FetchService(&s.DataService); err != nil {
+		return err
+	}
+	if err := s.DataService.RegisterSchema(Schema); err != nil {
+		return err
+	}
+	// discord is optional
+	if err := reg.FetchService(&s.DiscordService); err == nil {
+		s.DiscordService.GuildConfigs = s
+	}
+	s.cache = make(map[string]GuildSettings)
+	return reg.RegisterService(s)
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
+	wg.Done()
+}
+
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	wg.Done()
+	return nil
+}
+
+// Status settings are read from the data service.
+func (s *Service) Status() error {
+	if s.DataService == nil {
+		return errors.New("settings service is not initialized")
+	}
+	return s.DataService.Status()
+}
+
+// Get Return the settings of the guild, loading them on the first call.
+// A guild without settings gets empty ones.
+func (s *Service) Get(guildID string) (GuildSettings, error) {
+	s.lock.RLock()
+	gs, ok := s.cache[guildID]
+	s.lock.RUnlock()
+	if ok {
+		return gs, nil
+	}
+	gs = GuildSettings{GuildID: guildID}
+	err := s.DataService.Collection(guildCollection).FindOne(context.Background(), &gs, data.Where(data.Eq("guild_id", guildID)))
+	if err != nil && !errors.Is(err, data.ErrNotFound) {
+		return GuildSettings{GuildID: guildID}, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cache[guildID] = gs
+	return gs, nil
+}
+
+// Save Validate and store the settings of gs.GuildID, replacing the previous ones.
+func (s *Service) Save(gs GuildSettings) error {
+	if err := gs.Validate(); err != nil {
+		return err
+	}
+	gs.UpdatedTime = time.Now()
+	if _, err := s.DataService.Collection(guildCollection).UpdateOne(context.Background(),
+		data.Where(data.Eq("guild_id", gs.GuildID)), gs, true); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cache[gs.GuildID] = gs
+	return nil
+}
+
+// Reset Remove the settings of the guild, back to the defaults of the bot.
+func (s *Service) Reset(guildID string) error {
+	if _, err := s.DataService.Collection(guildCollection).DeleteOne(context.Background(),
+		data.Where(data.Eq("guild_id", guildID))); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cache[guildID] = GuildSettings{GuildID: guildID}
+	return nil
+}
+
+// Location Return the timezone of the guild, the one of the bot when not set or not loadable.
+func (s *Service) Location(guildID string) *time.Location {
+	gs, err := s.Get(guildID)
+	if err != nil {
+		core.Logger.Warnf("Error loading settings of guild %s: %v", guildID, err)
+	}
+	return gs.Location()
+}
+
+// GuildMessengerConfig base with the prefix and separator of the guild, see discord.IGuildConfigs.
+func (s *Service) GuildMessengerConfig(guildID string, base core.MessengerConfig) core.MessengerConfig {
+	gs, err := s.Get(guildID)
+	if err != nil {
+		core.Logger.Warnf("Error loading settings of guild %s: %v", guildID, err)
+		return base
+	}
+	if gs.Prefix != "" {
+		base.Prefix = gs.Prefix
+	}
+	if gs.Separator != "" {
+		base.Separator = gs.Separator
+	}
+	return base
+}
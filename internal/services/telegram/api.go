@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Update An incoming update from the Bot API. Only the fields used by Dalian are mapped.
+type Update struct {
+	UpdateID      int64    `json:"update_id"`
+	Message       *Message `json:"message,omitempty"`
+	EditedMessage *Message `json:"edited_message,omitempty"`
+	ChannelPost   *Message `json:"channel_post,omitempty"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+}
+
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// ChatMember Only the status of the member is mapped.
+type ChatMember struct {
+	Status string `json:"status"` // creator, administrator, member, restricted, left or kicked
+}
+
+// apiResponse The envelope of every Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	ErrorCode   int             `json:"error_code,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+// APIError An error reported by the Bot API.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s failed [%d]: %s", e.Method, e.Code, e.Description)
+}
+
+// call invoke a Bot API method with a json body and decode its result into receiver (can be nil).
+func (s *Service) call(method string, params any, receiver any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(s.APIEndpoint, "/"), s.Token, method)
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram %s request failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("telegram %s returned malformed response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !apiResp.OK {
+		return &APIError{Method: method, Code: apiResp.ErrorCode, Description: apiResp.Description}
+	}
+	if receiver != nil {
+		return json.Unmarshal(apiResp.Result, receiver)
+	}
+	return nil
+}
+
+func (s *Service) getMe() (*User, error) {
+	var me User
+	if err := s.call("getMe", struct{}{}, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+func (s *Service) getUpdates(offset int64, timeoutSeconds int) ([]Update, error) {
+	var updates []Update
+	err := s.call("getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         timeoutSeconds,
+		"allowed_updates": []string{"message", "channel_post"},
+	}, &updates)
+	return updates, err
+}
+
+func (s *Service) getChatMember(chatID, userID int64) (*ChatMember, error) {
+	var member ChatMember
+	if err := s.call("getChatMember", map[string]any{"chat_id": chatID, "user_id": userID}, &member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *Service) setWebhook(url, secret string) error {
+	params := map[string]any{"url": url}
+	if secret != "" {
+		params["secret_token"] = secret
+	}
+	return s.call("setWebhook", params, nil)
+}
+
+func (s *Service) deleteWebhook() error {
+	return s.call("deleteWebhook", struct{}{}, nil)
+}
+
+// newHTTPClient a client whose timeout outlives a long-polling request.
+func newHTTPClient(s *Service) *http.Client {
+	return &http.Client{Timeout: s.PollTimeout + defaultRequestTimeout}
+}
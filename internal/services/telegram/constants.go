@@ -0,0 +1,24 @@
+package telegram
+
+import "dalian-bot/internal/core"
+
+const TriggerTypeTelegram core.TriggerType = "telegram"
+
+const (
+	// DefaultAPIEndpoint the official Bot API server.
+	DefaultAPIEndpoint = "https://api.telegram.org"
+	// WebhookPath the route mounted on web.Service when running in webhook mode, under its base path.
+	WebhookPath  = webhookGroup + webhookRoute
+	webhookGroup = "/telegram"
+	webhookRoute = "/webhook"
+	// HeaderSecretToken header carrying the secret token set by setWebhook.
+	HeaderSecretToken = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// Chat types, see Chat.Type.
+const (
+	ChatTypePrivate    = "private"
+	ChatTypeGroup      = "group"
+	ChatTypeSupergroup = "supergroup"
+	ChatTypeChannel    = "channel"
+)
@@ -0,0 +1,22 @@
+package telegram
+
+import (
+	"dalian-bot/internal/core"
+)
+
+type EventType string
+
+const (
+	EventTypeMessage EventType = "message"
+)
+
+type Event struct {
+	EventType EventType
+	Update    *Update
+	Message   *Message
+}
+
+func UnboxEvent(t core.Trigger) Event {
+	var e = t.Event.(Event)
+	return e
+}
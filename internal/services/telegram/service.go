@@ -0,0 +1,227 @@
+package telegram
+
+import (
+	"context"
+	"dalian-bot/internal/core"
+	"dalian-bot/internal/services/web"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollTimeout    = 30 * time.Second
+	defaultRequestTimeout = 10 * time.Second
+	// pollRetryInterval wait time before polling again after a failed getUpdates.
+	pollRetryInterval = 5 * time.Second
+)
+
+// Service Telegram bot service.
+// Receives updates either by long-polling getUpdates, or through a webhook mounted on web.Service
+// when ServiceConfig.WebhookURL is set.
+type Service struct {
+	ServiceConfig
+	core.TriggerableEmbedUtil
+	WebService            *web.Service
+	TelegramAccountConfig core.MessengerConfig
+	httpClient            *http.Client
+	offset                int64
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	stoppedChan           chan struct{}
+}
+
+type ServiceConfig struct {
+	Token string
+	// APIEndpoint Bot API server, defaults to DefaultAPIEndpoint. Point it to a local fake for testing.
+	APIEndpoint string
+	// WebhookURL public url of WebhookPath. Long-polling is used when empty.
+	WebhookURL string
+	// WebhookSecret verified against HeaderSecretToken for incoming webhook updates.
+	WebhookSecret string
+	// PollTimeout long-polling timeout, defaults to 30 seconds.
+	PollTimeout time.Duration
+}
+
+func (s *Service) Name() string {
+	return "telegram"
+}
+
+func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if s.APIEndpoint == "" {
+		s.APIEndpoint = DefaultAPIEndpoint
+	}
+	if s.PollTimeout == 0 {
+		s.PollTimeout = defaultPollTimeout
+	}
+	s.httpClient = newHTTPClient(s)
+	// cancelled on Stop, aborting any pending long-polling request.
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	// webService is only required in webhook mode.
+	if s.isWebhookMode() {
+		if err := reg.FetchService(&s.WebService); err != nil {
+			return err
+		}
+		s.WebService.Group(webhookGroup).POST(webhookRoute, s.handleWebhook)
+	}
+	return reg.RegisterService(s)
+}
+
+func (s *Service) Start(wg *sync.WaitGroup) {
+	defer wg.Done()
+	me, err := s.getMe()
+	if err != nil {
+		core.Logger.Errorf("error connecting Telegram Bot API: %v", err)
+		return
+	}
+	// same as discord, command words are still split on spaces
+	s.TelegramAccountConfig = core.MessengerConfig{
+		Prefix:    "$",
+		Separator: "$",
+		BotID:     strconv.FormatInt(me.ID, 10),
+	}
+	if s.isWebhookMode() {
+		if err := s.setWebhook(s.WebhookURL, s.WebhookSecret); err != nil {
+			core.Logger.Errorf("error setting Telegram webhook: %v", err)
+			return
+		}
+	} else {
+		// getUpdates is refused while a webhook is active.
+		if err := s.deleteWebhook(); err != nil {
+			core.Logger.Warnf("error removing Telegram webhook: %v", err)
+		}
+		s.stoppedChan = make(chan struct{})
+		go s.poll()
+	}
+	core.Logger.Debugf("Service [%s] is now online as @%s.", reflect.TypeOf(s), me.Username)
+}
+
+func (s *Service) Stop(wg *sync.WaitGroup) error {
+	s.cancel()
+	if s.stoppedChan != nil {
+		<-s.stoppedChan
+	}
+	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
+	wg.Done()
+	return nil
+}
+
+func (s *Service) Status() error {
+	if s.TelegramAccountConfig.BotID == "" {
+		return errors.New("telegram bot is not connected")
+	}
+	return nil
+}
+
+func (s *Service) isWebhookMode() bool {
+	return s.WebhookURL != ""
+}
+
+// poll long-poll getUpdates until Stop is called.
+func (s *Service) poll() {
+	defer close(s.stoppedChan)
+	timeoutSeconds := int(s.PollTimeout / time.Second)
+	for {
+		updates, err := s.getUpdates(s.offset, timeoutSeconds)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				// stopped
+				return
+			}
+			core.Logger.Warnf("Telegram polling failed: %v", err)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(pollRetryInterval):
+			}
+			continue
+		}
+		for k := range updates {
+			s.offset = updates[k].UpdateID + 1
+			s.dispatchUpdate(&updates[k])
+		}
+	}
+}
+
+func (s *Service) handleWebhook(c *gin.Context) {
+	if s.WebhookSecret != "" && c.GetHeader(HeaderSecretToken) != s.WebhookSecret {
+		core.Logger.Warnf("Telegram webhook rejected from %s: bad secret token", c.ClientIP())
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var update Update
+	if err := c.BindJSON(&update); err != nil {
+		core.Logger.Warnf("Error: %v\r\n", err)
+		return
+	}
+	c.Status(http.StatusOK)
+	s.dispatchUpdate(&update)
+}
+
+// dispatchUpdate turn an update into a Trigger. Updates without a text message are dropped.
+func (s *Service) dispatchUpdate(update *Update) {
+	m := update.Message
+	if m == nil {
+		m = update.ChannelPost
+	}
+	if m == nil || m.Text == "" {
+		return
+	}
+	t := core.Trigger{
+		Type: TriggerTypeTelegram,
+		Event: Event{
+			EventType: EventTypeMessage,
+			Update:    update,
+			Message:   m,
+		},
+	}
+	s.SendTrigger(t)
+}
+
+// SendMessage send a plain text message to the given chat.
+func (s *Service) SendMessage(chatID int64, text string) (*Message, error) {
+	var sent Message
+	if err := s.call("sendMessage", map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	}, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// IsMessageFromBotOrSelf Return true if the message is sent by a bot, including Dalian itself.
+func (s *Service) IsMessageFromBotOrSelf(m *Message) bool {
+	if m.From == nil {
+		// channel posts have no sender
+		return false
+	}
+	return m.From.IsBot || strconv.FormatInt(m.From.ID, 10) == s.TelegramAccountConfig.BotID
+}
+
+// IsChatAdmin Return true if the sender of the message administers its chat.
+// Anyone administers their private chat with the bot, and only admins can post in channels.
+func (s *Service) IsChatAdmin(m *Message) (bool, error) {
+	switch m.Chat.Type {
+	case ChatTypePrivate, ChatTypeChannel:
+		return true, nil
+	}
+	if m.From == nil {
+		return false, nil
+	}
+	member, err := s.getChatMember(m.Chat.ID, m.From.ID)
+	if err != nil {
+		return false, err
+	}
+	return member.Status == "creator" || member.Status == "administrator", nil
+}
+
+// ParseChatID parse a chat id stored as string.
+func ParseChatID(chatID string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(chatID), 10, 64)
+}
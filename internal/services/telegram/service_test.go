@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"dalian-bot/internal/core"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const testToken = "123:fake"
+
+// fakeBotAPI a local fake of the subset of Bot API used by Service.
+type fakeBotAPI struct {
+	sync.Mutex
+	pending []Update
+	sent    []map[string]any
+	admins  map[int64]bool // user ids administering every chat
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/bot"+testToken+"/")
+	var params map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&params)
+	f.Lock()
+	defer f.Unlock()
+	var result any
+	switch method {
+	case "getMe":
+		result = User{ID: 42, IsBot: true, FirstName: "Dalian", Username: "dalian_bot"}
+	case "deleteWebhook", "setWebhook":
+		result = true
+	case "getUpdates":
+		offset := int64(params["offset"].(float64))
+		var updates []Update
+		for _, u := range f.pending {
+			if u.UpdateID >= offset {
+				updates = append(updates, u)
+			}
+		}
+		if len(updates) == 0 {
+			// emulate a short long-polling wait
+			time.Sleep(50 * time.Millisecond)
+		}
+		result = updates
+	case "getChatMember":
+		status := "member"
+		if f.admins[int64(params["user_id"].(float64))] {
+			status = "administrator"
+		}
+		result = ChatMember{Status: status}
+	case "sendMessage":
+		f.sent = append(f.sent, params)
+		result = Message{MessageID: int64(len(f.sent)), Chat: Chat{ID: int64(params["chat_id"].(float64))}, Text: params["text"].(string)}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"ok":false,"error_code":404,"description":"Not Found: %s"}`, method)
+		return
+	}
+	raw, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(apiResponse{OK: true, Result: raw})
+}
+
+func newTestService(t *testing.T, fake *fakeBotAPI) (*Service, chan core.Trigger) {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	reg := core.NewServiceRegistry()
+	s := &Service{ServiceConfig: ServiceConfig{Token: testToken, APIEndpoint: server.URL, PollTimeout: time.Second}}
+	if err := s.Init(reg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	triggers := make(chan core.Trigger, 10)
+	s.InstallTriggerChan(triggers)
+	return s, triggers
+}
+
+func TestPollingTurnsUpdatesIntoTriggers(t *testing.T) {
+	fake := &fakeBotAPI{pending: []Update{
+		{UpdateID: 7, Message: &Message{MessageID: 1, Chat: Chat{ID: -100}, From: &User{ID: 9}, Text: "$ddtv set"}},
+		{UpdateID: 8, Message: &Message{MessageID: 2, Chat: Chat{ID: -100}, From: &User{ID: 9}}}, // no text, dropped
+	}}
+	s, triggers := newTestService(t, fake)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	s.Start(wg)
+	defer func() {
+		wg.Add(1)
+		s.Stop(wg)
+	}()
+
+	if err := s.Status(); err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if s.TelegramAccountConfig.BotID != "42" {
+		t.Errorf("BotID = %s, want 42", s.TelegramAccountConfig.BotID)
+	}
+	select {
+	case trigger := <-triggers:
+		if trigger.Type != TriggerTypeTelegram {
+			t.Fatalf("trigger type = %s", trigger.Type)
+		}
+		if m := UnboxEvent(trigger).Message; m.Text != "$ddtv set" || m.Chat.ID != -100 {
+			t.Errorf("unexpected message: %+v", m)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no trigger received")
+	}
+	select {
+	case trigger := <-triggers:
+		t.Errorf("unexpected trigger: %+v", trigger)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	fake := &fakeBotAPI{}
+	s, _ := newTestService(t, fake)
+	msg, err := s.SendMessage(-100, "hello")
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if msg.Chat.ID != -100 || len(fake.sent) != 1 || fake.sent[0]["text"] != "hello" {
+		t.Errorf("unexpected sendMessage call: %+v", fake.sent)
+	}
+}
+
+func TestIsChatAdmin(t *testing.T) {
+	s, _ := newTestService(t, &fakeBotAPI{admins: map[int64]bool{1: true}})
+	for _, tc := range []struct {
+		name string
+		m    *Message
+		want bool
+	}{
+		{"private chat", &Message{Chat: Chat{ID: 5, Type: ChatTypePrivate}, From: &User{ID: 2}}, true},
+		{"channel post", &Message{Chat: Chat{ID: -100, Type: ChatTypeChannel}}, true},
+		{"group admin", &Message{Chat: Chat{ID: -100, Type: ChatTypeSupergroup}, From: &User{ID: 1}}, true},
+		{"group member", &Message{Chat: Chat{ID: -100, Type: ChatTypeGroup}, From: &User{ID: 2}}, false},
+	} {
+		if got, err := s.IsChatAdmin(tc.m); err != nil || got != tc.want {
+			t.Errorf("%s: IsChatAdmin = %v (%v), want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	s, _ := newTestService(t, &fakeBotAPI{})
+	err := s.call("unknownMethod", struct{}{}, nil)
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Code != 404 {
+		t.Errorf("want APIError 404, got %v", err)
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	s, triggers := newTestService(t, &fakeBotAPI{})
+	s.WebhookSecret = "s3cret"
+	body := `{"update_id":1,"message":{"message_id":1,"chat":{"id":5,"type":"private"},"text":"$ping"}}`
+
+	for _, tc := range []struct {
+		secret string
+		status int
+	}{{"wrong", http.StatusUnauthorized}, {"s3cret", http.StatusOK}} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(body))
+		req.Header.Set(HeaderSecretToken, tc.secret)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		s.handleWebhook(c)
+		if w.Code != tc.status {
+			t.Errorf("secret %s: status = %d, want %d", tc.secret, w.Code, tc.status)
+		}
+	}
+	if len(triggers) != 1 {
+		t.Errorf("want exactly one trigger, got %d", len(triggers))
+	}
+}
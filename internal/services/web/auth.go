@@ -0,0 +1,65 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseIPNets Parse IPs or CIDRs, e.g. 192.168.1.0/24. A single IP is a network of its own.
+func ParseIPNets(allowed []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range allowed {
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed ip %s", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// AllowsIP Whether the ip is in one of the networks. Any ip is allowed when there are none.
+func AllowsIP(nets []*net.IPNet, ip net.IP) bool {
+	if len(nets) == 0 {
+		return true
+	}
+	for _, ipNet := range nets {
+		if ip != nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidToken Compare the token with the secret in constant time. An empty token is never valid.
+func ValidToken(secret, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// ValidSignature Check a `sha256=<hex HMAC-SHA256 of the body>` signature, keyed with the secret.
+func ValidSignature(secret, signature string, body []byte) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
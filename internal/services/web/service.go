@@ -1,19 +1,50 @@
 package web
 
 import (
+	"context"
+	"crypto/tls"
 	"dalian-bot/internal/core"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"path"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	DefaultAddr            = ":8740"
+	defaultReadTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 10 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+// Service The HTTP server of the bot. Other services mount their routes with Group before it starts.
 type Service struct {
 	GinEngine *gin.Engine
 	ServiceConfig
+	root     *gin.RouterGroup
+	server   *http.Server
+	listener net.Listener
+	running  atomic.Bool
 }
 
 type ServiceConfig struct {
-	TrustedProxies []string
+	Addr     string // listen address, defaults to DefaultAddr
+	BasePath string // every route is mounted under it, e.g. /dalian behind a reverse proxy
+	// PublicURL where users reach the server, e.g. https://example.com, for links sent outside the web.
+	PublicURL string
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are set.
+	TLSCertFile    string
+	TLSKeyFile     string
+	TrustedProxies []string      // client IPs are read from forwarding headers of these proxies only
+	ReadTimeout    time.Duration // defaults to 10s
+	WriteTimeout   time.Duration // defaults to 10s
 }
 
 func (s *Service) Name() string {
@@ -21,27 +52,102 @@ func (s *Service) Name() string {
 }
 
 func (s *Service) Init(reg *core.ServiceRegistry) error {
+	if s.Addr == "" {
+		s.Addr = DefaultAddr
+	}
+	if s.ReadTimeout <= 0 {
+		s.ReadTimeout = defaultReadTimeout
+	}
+	if s.WriteTimeout <= 0 {
+		s.WriteTimeout = defaultWriteTimeout
+	}
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		return errors.New("web: TLS needs both a certificate and a key")
+	}
 	/* Setup Api Server */
 	engine := gin.Default()
-	//allow only redirection
-	engine.SetTrustedProxies(s.TrustedProxies)
+	if err := engine.SetTrustedProxies(s.TrustedProxies); err != nil {
+		return fmt.Errorf("web: invalid trusted proxies: %w", err)
+	}
 	s.GinEngine = engine
+	s.root = engine.Group(s.BasePath)
 	return reg.RegisterService(s)
 }
 
+// Group Return a route group mounted at relativePath under the base path, e.g. `/ddtv` for the ddtv service.
+func (s *Service) Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	return s.root.Group(relativePath, handlers...)
+}
+
+// Start bind the listen address before returning, panicking when it can't, then serve in the background.
 func (s *Service) Start(wg *sync.WaitGroup) {
-	go s.GinEngine.Run(":8740")
-	core.Logger.Debugf("Service [%s] is now online.", reflect.TypeOf(s))
-	wg.Done()
+	defer wg.Done()
+	s.server = &http.Server{
+		Handler:      s.GinEngine,
+		ReadTimeout:  s.ReadTimeout,
+		WriteTimeout: s.WriteTimeout,
+	}
+	if s.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+		if err != nil {
+			core.Logger.Panicf("failed loading web TLS certificate: %v", err)
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		core.Logger.Panicf("failed listening on [%s]: %v", s.Addr, err)
+	}
+	if s.server.TLSConfig != nil {
+		listener = tls.NewListener(listener, s.server.TLSConfig)
+	}
+	s.listener = listener
+	s.running.Store(true)
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			core.Logger.Errorf("web server stopped: %v", err)
+		}
+		s.running.Store(false)
+	}()
+	core.Logger.Debugf("Service [%s] is now online at [%s%s].", reflect.TypeOf(s), listener.Addr(), s.BasePath)
 }
 
+// Stop wait for requests in progress to complete, for up to 10s.
 func (s *Service) Stop(wg *sync.WaitGroup) error {
+	defer wg.Done()
+	s.running.Store(false)
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		core.Logger.Warnf("error shutting down web server: %v", err)
+		return err
+	}
 	core.Logger.Debugf("Service [%s] is successfully closed.", reflect.TypeOf(s))
-	wg.Done()
 	return nil
 }
 
 func (s *Service) Status() error {
-	//TODO implement me
-	panic("implement me")
+	if !s.running.Load() {
+		return errors.New("web server is not running")
+	}
+	return nil
+}
+
+// URL Return the absolute url of a route under the base path, e.g. URL("/dashboard/login").
+func (s *Service) URL(relativePath string) (string, error) {
+	if s.PublicURL == "" {
+		return "", errors.New("web: public url is not configured")
+	}
+	return strings.TrimSuffix(s.PublicURL, "/") + path.Join("/", s.BasePath, relativePath), nil
+}
+
+// ListenAddr Return the address the server is bound to, nil before Start.
+func (s *Service) ListenAddr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
 }
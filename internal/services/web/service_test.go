@@ -0,0 +1,65 @@
+package web
+
+import (
+	"dalian-bot/internal/core"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newTestService(t *testing.T, config ServiceConfig) *Service {
+	t.Helper()
+	core.Logger = core.DalianLogger{SugaredLogger: zap.NewNop().Sugar()}
+	gin.SetMode(gin.TestMode)
+	s := &Service{ServiceConfig: config}
+	if err := s.Init(core.NewServiceRegistry()); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	return s
+}
+
+func TestGroupsUnderBasePathAndShutdown(t *testing.T) {
+	s := newTestService(t, ServiceConfig{Addr: "127.0.0.1:0", BasePath: "/dalian"})
+	s.Group("/ddtv").GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	s.Start(wg)
+	if err := s.Status(); err != nil {
+		t.Fatalf("status after start: %v", err)
+	}
+
+	for path, want := range map[string]int{"/dalian/ddtv/ping": http.StatusOK, "/ddtv/ping": http.StatusNotFound} {
+		resp, err := http.Get(fmt.Sprintf("http://%s%s", s.ListenAddr(), path))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	wg.Add(1)
+	if err := s.Stop(wg); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := s.Status(); err == nil {
+		t.Errorf("status after stop should fail")
+	}
+	if _, err := http.Get(fmt.Sprintf("http://%s/dalian/ddtv/ping", s.ListenAddr())); err == nil {
+		t.Errorf("server still answering after stop")
+	}
+}
+
+func TestInitRejectsHalfTLS(t *testing.T) {
+	s := &Service{ServiceConfig: ServiceConfig{TLSCertFile: "cert.pem"}}
+	if err := s.Init(core.NewServiceRegistry()); err == nil {
+		t.Fatalf("a certificate without key should be rejected")
+	}
+}